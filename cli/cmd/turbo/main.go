@@ -73,6 +73,36 @@ func main() {
 			return &run.RunCommand{Config: cf, UI: ui, SignalWatcher: signalWatcher},
 				nil
 		},
+		"query affected": func() (cli.Command, error) {
+			return &run.AffectedCommand{Config: cf, UI: ui}, nil
+		},
+		"config validate": func() (cli.Command, error) {
+			return &run.ConfigValidateCommand{Config: cf, UI: ui}, nil
+		},
+		"config show": func() (cli.Command, error) {
+			return &run.ConfigShowCommand{Config: cf, UI: ui}, nil
+		},
+		"cache verify": func() (cli.Command, error) {
+			return &run.CacheVerifyCommand{Config: cf, UI: ui}, nil
+		},
+		"cache ls": func() (cli.Command, error) {
+			return &run.CacheListCommand{Config: cf, UI: ui}, nil
+		},
+		"cache show": func() (cli.Command, error) {
+			return &run.CacheShowCommand{Config: cf, UI: ui}, nil
+		},
+		"cache extract": func() (cli.Command, error) {
+			return &run.CacheExtractCommand{Config: cf, UI: ui}, nil
+		},
+		"cache rm": func() (cli.Command, error) {
+			return &run.CacheRemoveCommand{Config: cf, UI: ui}, nil
+		},
+		"cache export": func() (cli.Command, error) {
+			return &run.CacheExportCommand{Config: cf, UI: ui, SignalWatcher: signalWatcher}, nil
+		},
+		"cache import": func() (cli.Command, error) {
+			return &run.CacheImportCommand{Config: cf, UI: ui}, nil
+		},
 		"prune": func() (cli.Command, error) {
 			return &prune.PruneCommand{Config: cf, Ui: ui}, nil
 		},
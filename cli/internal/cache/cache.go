@@ -8,13 +8,13 @@ package cache
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/spf13/pflag"
 	"github.com/vercel/turborepo/cli/internal/analytics"
 	"github.com/vercel/turborepo/cli/internal/config"
 	"github.com/vercel/turborepo/cli/internal/fs"
-	"github.com/vercel/turborepo/cli/internal/ui"
 	"github.com/vercel/turborepo/cli/internal/util"
 	"golang.org/x/sync/errgroup"
 )
@@ -22,15 +22,27 @@ import (
 // Cache is abstracted way to cache/fetch previously run tasks
 type Cache interface {
 	// Fetch returns true if there is a cache it. It is expected to move files
-	// into their correct position as a side effect
-	Fetch(target string, hash string, files []string) (bool, []string, int, error)
+	// into their correct position as a side effect. Before moving them, files
+	// matching the given repo-relative output globs are removed, so that files
+	// left over from a previous build don't remain alongside the cached ones.
+	Fetch(target string, hash string, outputGlobs []string) (bool, []string, int, error)
 	// Put caches files for a given hash
 	Put(target string, hash string, duration int, files []string) error
+	// Exists reports whether there is an artifact for hash, without restoring it
+	Exists(hash string) (ItemStatus, error)
 	Clean(target string)
 	CleanAll()
 	Shutdown()
 }
 
+// ItemStatus describes which caches have an artifact
+type ItemStatus struct {
+	Local  bool
+	Remote bool
+	// Duration is how long the task that produced the artifact took, in milliseconds
+	Duration int
+}
+
 const cacheEventHit = "HIT"
 const cacheEventMiss = "MISS"
 
@@ -62,15 +74,172 @@ type Opts struct {
 	SkipFilesystem  bool
 	Workers         int
 	RemoteCacheOpts fs.RemoteCacheOptions
+	// Tiers are the caches to use, highest priority first. If there are none, the local cache
+	// in Dir is used, followed by the remote cache.
+	Tiers []fs.CacheTier
+	// Policies replace the policies of the tiers of each type, such as fs.CacheTierRemote.
+	// Tiers whose policy allows neither reads nor writes aren't used.
+	Policies map[string]TierPolicy
+	// RemoteReadOnly stops artifacts from being stored in the remote cache
+	RemoteReadOnly bool
+}
+
+// EffectiveTiers returns the tiers to use, with the policies that Policies and RemoteReadOnly
+// give them. Tiers that SkipFilesystem, SkipRemote or their policy turn off are left out.
+func (opts Opts) EffectiveTiers() []fs.CacheTier {
+	tiers := opts.Tiers
+	if len(tiers) == 0 {
+		tiers = []fs.CacheTier{{Type: fs.CacheTierLocal}, {Type: fs.CacheTierRemote}}
+	}
+	chain := make([]fs.CacheTier, 0, len(tiers))
+	for _, tier := range tiers {
+		if (tier.Type == fs.CacheTierLocal && opts.SkipFilesystem) || (tier.Type == fs.CacheTierRemote && opts.SkipRemote) {
+			continue
+		}
+		policy := parseTierPolicy(tier.Policy)
+		if override, ok := opts.Policies[tier.Type]; ok {
+			policy = override
+		}
+		if tier.Type == fs.CacheTierRemote && opts.RemoteReadOnly {
+			policy.Write = false
+		}
+		if !policy.Read && !policy.Write {
+			continue
+		}
+		tier.Policy = policy.String()
+		chain = append(chain, tier)
+	}
+	return chain
+}
+
+// UsesRemote returns true if the remote cache is one of the caches in use
+func (opts Opts) UsesRemote() bool {
+	for _, tier := range opts.EffectiveTiers() {
+		if tier.Type == fs.CacheTierRemote {
+			return true
+		}
+	}
+	return false
+}
+
+// TierPolicy is whether a cache can be read from and written to
+type TierPolicy struct {
+	Read  bool
+	Write bool
+}
+
+var _readWrite = TierPolicy{Read: true, Write: true}
+
+func parseTierPolicy(policy string) TierPolicy {
+	switch policy {
+	case fs.CacheTierReadOnly:
+		return TierPolicy{Read: true}
+	case fs.CacheTierWriteOnly:
+		return TierPolicy{Write: true}
+	default:
+		return _readWrite
+	}
+}
+
+// String returns the name of the policy in turbo.json, or "" for a policy that turns a tier off
+func (p TierPolicy) String() string {
+	switch {
+	case p.Read && p.Write:
+		return fs.CacheTierReadWrite
+	case p.Read:
+		return fs.CacheTierReadOnly
+	case p.Write:
+		return fs.CacheTierWriteOnly
+	default:
+		return ""
+	}
+}
+
+// tierPoliciesValue implements the --cache flag, which sets the policies of the tiers of
+// each type as a list such as local:rw,remote:r
+type tierPoliciesValue struct {
+	opts *Opts
+}
+
+var _ pflag.Value = &tierPoliciesValue{}
+
+func (v *tierPoliciesValue) String() string {
+	entries := make([]string, 0, len(fs.CacheTierTypes))
+	for _, tierType := range fs.CacheTierTypes {
+		if policy, ok := v.opts.Policies[tierType]; ok {
+			access := ""
+			if policy.Read {
+				access += "r"
+			}
+			if policy.Write {
+				access += "w"
+			}
+			entries = append(entries, tierType+":"+access)
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+func (v *tierPoliciesValue) Set(value string) error {
+	policies := make(map[string]TierPolicy)
+	for _, entry := range strings.Split(value, ",") {
+		tierType, access, ok := strings.Cut(entry, ":")
+		if !ok {
+			return fmt.Errorf("invalid cache setting %q. Expected <type>:<access>, such as remote:r", entry)
+		}
+		if !isTierType(tierType) {
+			return fmt.Errorf("invalid cache type %q. Expected one of: %v", tierType, strings.Join(fs.CacheTierTypes, ", "))
+		}
+		if _, ok := policies[tierType]; ok {
+			return fmt.Errorf("the access of %q is set more than once", tierType)
+		}
+		var policy TierPolicy
+		switch access {
+		case "":
+		case "r":
+			policy.Read = true
+		case "w":
+			policy.Write = true
+		case "rw":
+			policy = _readWrite
+		default:
+			return fmt.Errorf("invalid access %q for %q. Expected r, w, rw, or nothing to turn it off", access, tierType)
+		}
+		policies[tierType] = policy
+	}
+	v.opts.Policies = policies
+	return nil
+}
+
+func (v *tierPoliciesValue) Type() string {
+	return "string"
+}
+
+func isTierType(tierType string) bool {
+	for _, t := range fs.CacheTierTypes {
+		if t == tierType {
+			return true
+		}
+	}
+	return false
 }
 
 var _remoteOnlyHelp = `Ignore the local filesystem cache for all tasks. Only
 allow reading and caching artifacts using the remote cache.`
 
+var _cacheHelp = `Set which caches artifacts are read from (r) and written
+to (w), such as local:rw,remote:r. A cache with no access, such as
+remote:, isn't used. Applies to every tier of the type.`
+
+var _remoteCacheReadOnlyHelp = `Restore artifacts from the remote cache, but never
+upload any.`
+
 // AddFlags adds cache-related flags to the given FlagSet
 func AddFlags(opts *Opts, flags *pflag.FlagSet, repoRoot fs.AbsolutePath) {
 	// skipping remote caching not currently a flag
 	flags.BoolVar(&opts.SkipFilesystem, "remote-only", false, _remoteOnlyHelp)
+	flags.Var(&tierPoliciesValue{opts: opts}, "cache", _cacheHelp)
+	flags.BoolVar(&opts.RemoteReadOnly, "remote-cache-read-only", false, _remoteCacheReadOnlyHelp)
 	fs.AbsolutePathVar(flags, &opts.Dir, "cache-dir", repoRoot, "Specify local filesystem cache directory.", "./node_modules/.cache/turbo")
 }
 
@@ -88,35 +257,40 @@ func New(opts Opts, config *config.Config, client client, recorder analytics.Rec
 
 // newSyncCache can return an error with a usable noopCache.
 func newSyncCache(opts Opts, config *config.Config, client client, recorder analytics.Recorder, onCacheRemoved OnCacheRemoved) (Cache, error) {
-	// Check to see if the user has turned off particular cache implementations.
-	useFsCache := !opts.SkipFilesystem
-	useHTTPCache := !opts.SkipRemote
-
-	// Since the above two flags are not mutually exclusive it is possible to configure
-	// yourself out of having a cache. We should tell you about it but we shouldn't fail
-	// your build for that reason.
+	// The user can turn off particular tiers, or leave none at all, in which case it is
+	// possible to configure yourself out of having a cache. We should tell you about it but
+	// we shouldn't fail your build for that reason.
 	//
 	// Further, since the httpCache can be removed at runtime, we need to insert a noopCache
 	// as a backup if you are configured to have *just* an httpCache.
-	//
-	// This is reduced from (!useFsCache && !useHTTPCache) || (!useFsCache & useHTTPCache)
-	useNoopCache := !useFsCache
-
-	// Build up an array of cache implementations, we can only ever have 1 or 2.
-	cacheImplementations := make([]Cache, 0, 2)
-
-	if useFsCache {
-		implementation, err := newFsCache(opts, recorder, config.Cwd)
-		if err != nil {
-			return nil, err
+	chain := opts.EffectiveTiers()
+	useNoopCache := true
+
+	cacheImplementations := make([]Cache, 0, len(chain)+1)
+	policies := make(map[Cache]TierPolicy, len(chain))
+	for _, tier := range chain {
+		var implementation Cache
+		switch tier.Type {
+		case fs.CacheTierLocal:
+			dir := opts.Dir
+			if tier.Dir != "" {
+				dir = fs.ResolveUnknownPath(config.Cwd, tier.Dir)
+			}
+			fsCache, err := newFsCache(dir, recorder, config.Cwd)
+			if err != nil {
+				return nil, err
+			}
+			implementation = fsCache
+			useNoopCache = false
+		case fs.CacheTierRemote:
+			httpCache := newHTTPCache(opts, config.RemoteConfig.TeamID, client, recorder, config.Cwd)
+			httpCache.writable = parseTierPolicy(tier.Policy).Write
+			implementation = httpCache
+		default:
+			return nil, fmt.Errorf("unknown cache tier type %q", tier.Type)
 		}
 		cacheImplementations = append(cacheImplementations, implementation)
-	}
-
-	if useHTTPCache {
-		fmt.Println(ui.Dim("• Remote computation caching enabled"))
-		implementation := newHTTPCache(opts, config.RemoteConfig.TeamID, client, recorder, config.Cwd)
-		cacheImplementations = append(cacheImplementations, implementation)
+		policies[implementation] = parseTierPolicy(tier.Policy)
 	}
 
 	if useNoopCache {
@@ -124,41 +298,80 @@ func newSyncCache(opts Opts, config *config.Config, client client, recorder anal
 		cacheImplementations = append(cacheImplementations, implementation)
 	}
 
-	// Precisely two cache implementations:
-	// fsCache and httpCache OR httpCache and noopCache
-	useMultiplexer := len(cacheImplementations) > 1
-	if useMultiplexer {
-		// We have early-returned any possible errors for this scenario.
-		return &cacheMultiplexer{
-			onCacheRemoved: onCacheRemoved,
-			opts:           opts,
-			caches:         cacheImplementations,
-		}, nil
-	}
-
-	// Precisely one cache implementation: fsCache OR noopCache
-	implementation := cacheImplementations[0]
-	_, isNoopCache := implementation.(*noopCache)
+	// A single cache that can be read and written doesn't need a multiplexer:
+	// fsCache OR noopCache
+	if len(cacheImplementations) == 1 {
+		implementation := cacheImplementations[0]
+		_, isNoopCache := implementation.(*noopCache)
 
-	// We want to let the user know something is wonky, but we don't want
-	// to trigger their build to fail.
-	if isNoopCache {
-		return implementation, ErrNoCachesEnabled
+		// We want to let the user know something is wonky, but we don't want
+		// to trigger their build to fail.
+		if isNoopCache {
+			return implementation, ErrNoCachesEnabled
+		}
+		if policies[implementation] == _readWrite {
+			return implementation, nil
+		}
 	}
-	return implementation, nil
+
+	// We have early-returned any possible errors for this scenario.
+	return &cacheMultiplexer{
+		onCacheRemoved: onCacheRemoved,
+		opts:           opts,
+		caches:         cacheImplementations,
+		policies:       policies,
+	}, nil
 }
 
 // A cacheMultiplexer multiplexes several caches into one.
 // Used when we have several active (eg. http, dir).
 type cacheMultiplexer struct {
-	caches         []Cache
+	caches []Cache
+	// policies are the tier policies of the caches. Caches without one can be read and written.
+	policies       map[Cache]TierPolicy
 	opts           Opts
 	mu             sync.RWMutex
 	onCacheRemoved OnCacheRemoved
 }
 
+// policy returns the tier policy of cache
+func (mplex *cacheMultiplexer) policy(cache Cache) TierPolicy {
+	if policy, ok := mplex.policies[cache]; ok {
+		return policy
+	}
+	return _readWrite
+}
+
 func (mplex *cacheMultiplexer) Put(target string, key string, duration int, files []string) error {
-	return mplex.storeUntil(target, key, duration, files, len(mplex.caches))
+	return mplex.storeUntil(target, key, duration, files, len(mplex.caches), nil)
+}
+
+// signedArtifact is a remote cache artifact, as it was downloaded, and its signature
+type signedArtifact struct {
+	tag  string
+	body []byte
+}
+
+// signedFetcher is implemented by caches that verify the signatures of the artifacts they
+// restore. fetchSigned is like Fetch, and also returns the verified artifact, or nil if
+// signing isn't enabled.
+type signedFetcher interface {
+	fetchSigned(target string, hash string, outputGlobs []string) (bool, []string, int, *signedArtifact, error)
+}
+
+// fetchSigned fetches from cache, along with the signed artifact it restored, if it has one
+func fetchSigned(cache Cache, target string, hash string, outputGlobs []string) (bool, []string, int, *signedArtifact, error) {
+	if fetcher, ok := cache.(signedFetcher); ok {
+		return fetcher.fetchSigned(target, hash, outputGlobs)
+	}
+	ok, files, duration, err := cache.Fetch(target, hash, outputGlobs)
+	return ok, files, duration, nil, err
+}
+
+// signedStorer is implemented by caches that can keep the signed artifact an entry was
+// restored from
+type signedStorer interface {
+	putSigned(target string, hash string, duration int, files []string, signed *signedArtifact) error
 }
 
 type cacheRemoval struct {
@@ -168,8 +381,9 @@ type cacheRemoval struct {
 
 // storeUntil stores artifacts into higher priority caches than the given one.
 // Used after artifact retrieval to ensure we have them in eg. the directory cache after
-// downloading from the RPC cache.
-func (mplex *cacheMultiplexer) storeUntil(target string, key string, duration int, files []string, stopAt int) error {
+// downloading from the RPC cache. Caches whose tier policy doesn't allow writes are skipped.
+// If the artifacts came from a signed artifact, caches that can keep it do.
+func (mplex *cacheMultiplexer) storeUntil(target string, key string, duration int, files []string, stopAt int, signed *signedArtifact) error {
 	// Attempt to store on all caches simultaneously.
 	toRemove := make([]*cacheRemoval, stopAt)
 	g := &errgroup.Group{}
@@ -178,10 +392,18 @@ func (mplex *cacheMultiplexer) storeUntil(target string, key string, duration in
 		if i == stopAt {
 			break
 		}
+		if !mplex.policy(cache).Write {
+			continue
+		}
 		c := cache
 		i := i
 		g.Go(func() error {
-			err := c.Put(target, key, duration, files)
+			var err error
+			if storer, ok := c.(signedStorer); ok && signed != nil {
+				err = storer.putSigned(target, key, duration, files, signed)
+			} else {
+				err = c.Put(target, key, duration, files)
+			}
 			if err != nil {
 				cd := &util.CacheDisabledError{}
 				if errors.As(err, &cd) {
@@ -236,7 +458,10 @@ func (mplex *cacheMultiplexer) Fetch(target string, key string, files []string)
 	// Retrieve from caches sequentially; if we did them simultaneously we could
 	// easily write the same file from two goroutines at once.
 	for i, cache := range caches {
-		ok, actualFiles, duration, err := cache.Fetch(target, key, files)
+		if !mplex.policy(cache).Read {
+			continue
+		}
+		ok, actualFiles, duration, signed, err := fetchSigned(cache, target, key, files)
 		if err != nil {
 			cd := &util.CacheDisabledError{}
 			if errors.As(err, &cd) {
@@ -254,13 +479,46 @@ func (mplex *cacheMultiplexer) Fetch(target string, key string, files []string)
 			// Store this into other caches. We can ignore errors here because we know
 			// we have previously successfully stored in a higher-priority cache, and so the overall
 			// result is a success at fetching. Storing in lower-priority caches is an optimization.
-			_ = mplex.storeUntil(target, key, duration, actualFiles, i)
+			_ = mplex.storeUntil(target, key, duration, actualFiles, i, signed)
 			return ok, actualFiles, duration, err
 		}
 	}
 	return false, files, 0, nil
 }
 
+// Exists combines the statuses of all caches, taking the duration from the highest priority
+// cache that has the artifact. Like Fetch, it treats errors as misses.
+func (mplex *cacheMultiplexer) Exists(hash string) (ItemStatus, error) {
+	mplex.mu.RLock()
+	caches := make([]Cache, len(mplex.caches))
+	copy(caches, mplex.caches)
+	mplex.mu.RUnlock()
+
+	status := ItemStatus{}
+	for _, cache := range caches {
+		if !mplex.policy(cache).Read {
+			continue
+		}
+		cacheStatus, err := cache.Exists(hash)
+		if err != nil {
+			cd := &util.CacheDisabledError{}
+			if errors.As(err, &cd) {
+				mplex.removeCache(&cacheRemoval{
+					cache: cache,
+					err:   cd,
+				})
+			}
+			continue
+		}
+		if !status.Local && !status.Remote {
+			status.Duration = cacheStatus.Duration
+		}
+		status.Local = status.Local || cacheStatus.Local
+		status.Remote = status.Remote || cacheStatus.Remote
+	}
+	return status, nil
+}
+
 func (mplex *cacheMultiplexer) Clean(target string) {
 	for _, cache := range mplex.caches {
 		cache.Clean(target)
@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+)
+
+// A bundle is a tar of artifacts in the format of the remote cache. Each artifact,
+// <hash>.tar.gz, is preceded by its metadata, <hash>.json.
+const (
+	_bundleMetaSuffix     = ".json"
+	_bundleArtifactSuffix = ".tar.gz"
+	_maxBundleMetaSize    = 1 << 20
+)
+
+// bundleMeta describes an artifact in a bundle
+type bundleMeta struct {
+	Hash     string `json:"hash"`
+	Duration int    `json:"duration"`
+	// Tag is the artifact's signature, the same as the x-artifact-tag header of the remote
+	// cache. It's only set if remote cache signing is enabled.
+	Tag string `json:"tag,omitempty"`
+}
+
+// _validHash matches the hashes of cache entries. Hashes are used as file names in the cache,
+// so hashes from bundles and the command line must not be able to name files elsewhere.
+var _validHash = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+
+// ExportBundle writes the entries of the local cache for the given hashes into w as a bundle.
+// If remote cache signing is enabled, entries that were restored from the remote cache are
+// exported as the artifact they were restored from, with its original signature. Other entries
+// are packed and signed the same way as for the remote cache, which needs the signing key.
+// It returns the hashes that have no entry in the local cache.
+func ExportBundle(w io.Writer, opts Opts, teamID string, hashes []string) ([]string, error) {
+	f := &fsCache{cacheDirectory: opts.Dir.ToString()}
+	signer := newArtifactSignatureAuthentication(opts, teamID)
+	tw := tar.NewWriter(w)
+	seen := make(map[string]bool, len(hashes))
+	var missing []string
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		entryDir := filepath.Join(f.cacheDirectory, hash)
+		meta, err := f.readMeta(hash)
+		if err != nil || !fs.PathExists(entryDir) {
+			missing = append(missing, hash)
+			continue
+		}
+		entryMeta := &bundleMeta{
+			Hash:     hash,
+			Duration: meta.Duration,
+		}
+		artifact, err := f.exportArtifact(hash, meta, entryMeta, signer)
+		if err != nil {
+			return nil, err
+		}
+		metaJSON, err := json.Marshal(entryMeta)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBundleFile(tw, hash+_bundleMetaSuffix, metaJSON); err != nil {
+			return nil, err
+		}
+		if err := writeBundleFile(tw, hash+_bundleArtifactSuffix, artifact); err != nil {
+			return nil, err
+		}
+	}
+	return missing, tw.Close()
+}
+
+// exportArtifact returns the artifact to export for the entry for hash, setting the tag in
+// entryMeta if signing is enabled
+func (f *fsCache) exportArtifact(hash string, meta *CacheMetadata, entryMeta *bundleMeta, signer *ArtifactSignatureAuthentication) ([]byte, error) {
+	if signer.isEnabled() {
+		signed, err := f.readSignedArtifact(hash, meta)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", hash, err)
+		}
+		if signed != nil {
+			entryMeta.Tag = signed.tag
+			return signed.body, nil
+		}
+	}
+	entryDir := filepath.Join(f.cacheDirectory, hash)
+	if err := verifyEntry(entryDir, meta); err != nil {
+		return nil, fmt.Errorf("%v: %w", hash, err)
+	}
+	artifact, err := packEntry(entryDir)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", hash, err)
+	}
+	if signer.isEnabled() {
+		entryMeta.Tag, err = signer.generateTag(hash, artifact)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign %v, which wasn't restored from a signed artifact: %w", hash, err)
+		}
+	}
+	return artifact, nil
+}
+
+// packEntry writes the files of the cache entry in dir into an artifact
+func packEntry(dir string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	err := filepath.WalkDir(dir, func(name string, dirEntry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if dirEntry.IsDir() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		return storeFile(tw, dir, relativePath)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeBundleFile(tw *tar.Writer, name string, contents []byte) error {
+	hdr := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Typeflag: tar.TypeReg,
+		Size:     int64(len(contents)),
+		ModTime:  mtime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(contents)
+	return err
+}
+
+// ImportBundle loads the artifacts in a bundle into the local cache. If remote cache signing is
+// enabled, every artifact must have a valid signature. Artifacts are restored with the same
+// checks as artifacts from the remote cache. It returns the hashes of the imported entries,
+// which include the entries imported before an error.
+func ImportBundle(r io.Reader, opts Opts, teamID string) ([]string, error) {
+	f := &fsCache{cacheDirectory: opts.Dir.ToString()}
+	if err := os.MkdirAll(f.cacheDirectory, fs.DirPermissions); err != nil {
+		return nil, err
+	}
+	verifier := newArtifactSignatureAuthentication(opts, teamID)
+	tr := tar.NewReader(r)
+	var pending *bundleMeta
+	var imported []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return imported, fmt.Errorf("invalid bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return imported, fmt.Errorf("invalid bundle: unexpected entry %v", hdr.Name)
+		}
+		switch {
+		case strings.HasSuffix(hdr.Name, _bundleMetaSuffix):
+			if hdr.Size > _maxBundleMetaSize {
+				return imported, fmt.Errorf("invalid bundle: %v is too large", hdr.Name)
+			}
+			metaJSON, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return imported, fmt.Errorf("invalid bundle: %w", err)
+			}
+			meta := &bundleMeta{}
+			if err := json.Unmarshal(metaJSON, meta); err != nil {
+				return imported, fmt.Errorf("invalid bundle: %v: %w", hdr.Name, err)
+			}
+			if !_validHash.MatchString(meta.Hash) || hdr.Name != meta.Hash+_bundleMetaSuffix {
+				return imported, fmt.Errorf("invalid bundle: %v has an invalid hash %q", hdr.Name, meta.Hash)
+			}
+			pending = meta
+		case strings.HasSuffix(hdr.Name, _bundleArtifactSuffix):
+			if pending == nil || hdr.Name != pending.Hash+_bundleArtifactSuffix {
+				return imported, fmt.Errorf("invalid bundle: %v has no metadata", hdr.Name)
+			}
+			if err := f.importArtifact(pending, tr, verifier); err != nil {
+				return imported, fmt.Errorf("failed to import %v: %w", pending.Hash, err)
+			}
+			imported = append(imported, pending.Hash)
+			pending = nil
+		default:
+			return imported, fmt.Errorf("invalid bundle: unexpected entry %v", hdr.Name)
+		}
+	}
+	return imported, nil
+}
+
+// importArtifact verifies the signature of an artifact from a bundle, and stores its files as
+// the cache entry for its hash. Signed artifacts are kept, so that they can be exported again
+// with the same signature.
+func (f *fsCache) importArtifact(meta *bundleMeta, artifact io.Reader, verifier *ArtifactSignatureAuthentication) error {
+	var signed *signedArtifact
+	if verifier.isEnabled() {
+		if meta.Tag == "" {
+			return errors.New("artifact verification failed: the artifact isn't signed")
+		}
+		b, err := ioutil.ReadAll(artifact)
+		if err != nil {
+			return err
+		}
+		isValid, err := verifier.validate(meta.Hash, b, meta.Tag)
+		if err != nil {
+			return fmt.Errorf("artifact verification failed: %w", err)
+		}
+		if !isValid {
+			return fmt.Errorf("artifact verification failed: artifact tag does not match expected tag %s", meta.Tag)
+		}
+		artifact = bytes.NewReader(b)
+		signed = &signedArtifact{tag: meta.Tag, body: b}
+	}
+
+	tempDir, err := os.MkdirTemp(f.cacheDirectory, meta.Hash+_tempEntrySuffix)
+	if err != nil {
+		return fmt.Errorf("error creating cache entry: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+	if _, err := restoreTar(fs.AbsolutePathFromUpstream(tempDir), artifact); err != nil {
+		return err
+	}
+	manifest, err := entryManifest(tempDir)
+	if err != nil {
+		return err
+	}
+	return f.commit(meta.Hash, tempDir, &CacheMetadata{
+		Duration: meta.Duration,
+		Hash:     meta.Hash,
+		Manifest: manifest,
+	}, signed)
+}
+
+// entryManifest computes the digests of the regular files in the entry stored in dir
+func entryManifest(dir string) (map[string]string, error) {
+	manifest := make(map[string]string)
+	err := filepath.WalkDir(dir, func(name string, dirEntry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !dirEntry.Type().IsRegular() {
+			return nil
+		}
+		relativePath, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		digest, err := fs.GitLikeHashFile(name)
+		if err != nil {
+			return err
+		}
+		manifest[filepath.ToSlash(relativePath)] = digest
+		return nil
+	})
+	return manifest, err
+}
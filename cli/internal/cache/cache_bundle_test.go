@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+// setupBundleCache stores an entry for "the-hash" in a new local cache, and returns the cache's
+// options
+func setupBundleCache(t *testing.T) Opts {
+	t.Helper()
+	cacheDir := t.TempDir()
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	assert.NilError(t, repoRoot.Join("my-pkg", "dist", "index.js").EnsureDir(), "EnsureDir")
+	assert.NilError(t, repoRoot.Join("my-pkg", "dist", "index.js").WriteFile([]byte("index"), 0644), "WriteFile")
+	assert.NilError(t, repoRoot.Join("my-pkg", "dist", "main.js").Symlink("index.js"), "Symlink")
+	cache := &fsCache{
+		cacheDirectory: cacheDir,
+		recorder:       &dummyRecorder{},
+		repoRoot:       repoRoot,
+	}
+	files := []string{filepath.Join("my-pkg", "dist", "index.js"), filepath.Join("my-pkg", "dist", "main.js")}
+	assert.NilError(t, cache.Put("unused", "thehash", 1200, files), "Put")
+	return Opts{Dir: fs.AbsolutePathFromUpstream(cacheDir)}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	opts := setupBundleCache(t)
+	bundle := &bytes.Buffer{}
+	missing, err := ExportBundle(bundle, opts, "team_id", []string{"thehash", "missinghash", "thehash"})
+	assert.NilError(t, err, "ExportBundle")
+	assert.DeepEqual(t, missing, []string{"missinghash"})
+
+	importOpts := Opts{Dir: fs.AbsolutePathFromUpstream(t.TempDir()).Join("cache")}
+	imported, err := ImportBundle(bundle, importOpts, "team_id")
+	assert.NilError(t, err, "ImportBundle")
+	assert.DeepEqual(t, imported, []string{"thehash"})
+
+	entry, err := NewLocalInspector(importOpts.Dir).Show("thehash")
+	assert.NilError(t, err, "Show")
+	assert.Equal(t, entry.Duration, 1200)
+	original, err := NewLocalInspector(opts.Dir).Show("thehash")
+	assert.NilError(t, err, "Show")
+	for i := range original.Files {
+		assert.Equal(t, entry.Files[i].Path, original.Files[i].Path)
+		assert.Equal(t, entry.Files[i].Digest, original.Files[i].Digest)
+		assert.Equal(t, entry.Files[i].LinkTarget, original.Files[i].LinkTarget)
+	}
+	results, err := VerifyLocal(importOpts.Dir)
+	assert.NilError(t, err, "VerifyLocal")
+	assert.Equal(t, len(results), 1)
+	assert.NilError(t, results[0].Err)
+	assert.Equal(t, results[0].Unverified, false)
+	assertNoTempEntries(t, importOpts.Dir.ToString())
+}
+
+func TestBundleSignatures(t *testing.T) {
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_KEY", "my-secret-key-env")
+	opts := setupBundleCache(t)
+	opts.RemoteCacheOpts.Signature = true
+	signed := &bytes.Buffer{}
+	_, err := ExportBundle(signed, opts, "team_id", []string{"thehash"})
+	assert.NilError(t, err, "ExportBundle")
+
+	importOpts := Opts{Dir: fs.AbsolutePathFromUpstream(t.TempDir()), RemoteCacheOpts: opts.RemoteCacheOpts}
+	_, err = ImportBundle(bytes.NewReader(signed.Bytes()), importOpts, "other_team_id")
+	assert.ErrorContains(t, err, "artifact verification failed")
+
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_KEY", "wrong-secret-key")
+	_, err = ImportBundle(bytes.NewReader(signed.Bytes()), importOpts, "team_id")
+	assert.ErrorContains(t, err, "artifact verification failed")
+
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_KEY", "my-secret-key-env")
+	imported, err := ImportBundle(bytes.NewReader(signed.Bytes()), importOpts, "team_id")
+	assert.NilError(t, err, "ImportBundle")
+	assert.DeepEqual(t, imported, []string{"thehash"})
+
+	// Artifacts exported without signing are rejected when signing is enabled
+	unsigned := &bytes.Buffer{}
+	_, err = ExportBundle(unsigned, Opts{Dir: opts.Dir}, "team_id", []string{"thehash"})
+	assert.NilError(t, err, "ExportBundle")
+	_, err = ImportBundle(unsigned, Opts{Dir: fs.AbsolutePathFromUpstream(t.TempDir()), RemoteCacheOpts: opts.RemoteCacheOpts}, "team_id")
+	assert.ErrorContains(t, err, "the artifact isn't signed")
+}
+
+func TestBundleKeepsRemoteSignatures(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err, "GenerateKey")
+	opts := Opts{
+		Dir: fs.AbsolutePathFromUpstream(t.TempDir()),
+		RemoteCacheOpts: fs.RemoteCacheOptions{
+			Signature: true,
+			PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+		},
+	}
+	artifact := makeTar(t, file("my-pkg/dist/index.js", "index"))
+	t.Setenv(_privateKeyEnv, base64.StdEncoding.EncodeToString(privateKey))
+	tag, err := newArtifactSignatureAuthentication(opts, "team_id").generateTag("thehash", artifact)
+	assert.NilError(t, err, "generateTag")
+	// Developer machines only have the public key
+	t.Setenv(_privateKeyEnv, "")
+
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	local := &fsCache{cacheDirectory: opts.Dir.ToString(), recorder: &dummyRecorder{}, repoRoot: repoRoot}
+	remote := &httpCache{
+		client: &artifactResp{
+			artifacts: map[string][]byte{"thehash": artifact},
+			tags:      map[string]string{"thehash": tag},
+		},
+		requestLimiter: make(limiter, 20),
+		recorder:       &dummyRecorder{},
+		signerVerifier: newArtifactSignatureAuthentication(opts, "team_id"),
+		repoRoot:       repoRoot,
+	}
+	mplex := &cacheMultiplexer{caches: []Cache{local, remote}}
+	hit, _, _, err := mplex.Fetch("unused", "thehash", nil)
+	assert.NilError(t, err, "Fetch")
+	assert.Equal(t, hit, true)
+	meta, err := local.readMeta("thehash")
+	assert.NilError(t, err, "readMeta")
+	assert.Equal(t, meta.Tag, tag)
+
+	// The entry is exported as the artifact it was restored from, with its original tag
+	bundle := &bytes.Buffer{}
+	_, err = ExportBundle(bundle, opts, "team_id", []string{"thehash"})
+	assert.NilError(t, err, "ExportBundle")
+	tr := tar.NewReader(bytes.NewReader(bundle.Bytes()))
+	_, err = tr.Next()
+	assert.NilError(t, err, "Next")
+	exportedMeta := &bundleMeta{}
+	assert.NilError(t, json.NewDecoder(tr).Decode(exportedMeta), "Decode")
+	assert.Equal(t, exportedMeta.Tag, tag)
+
+	importOpts := opts
+	importOpts.Dir = fs.AbsolutePathFromUpstream(t.TempDir())
+	imported, err := ImportBundle(bytes.NewReader(bundle.Bytes()), importOpts, "team_id")
+	assert.NilError(t, err, "ImportBundle")
+	assert.DeepEqual(t, imported, []string{"thehash"})
+
+	// Imported entries keep the signature too
+	reexported := &bytes.Buffer{}
+	_, err = ExportBundle(reexported, importOpts, "team_id", []string{"thehash"})
+	assert.NilError(t, err, "ExportBundle")
+	assert.DeepEqual(t, reexported.Bytes(), bundle.Bytes())
+
+	// Entries that weren't restored from a signed artifact need the private key to be signed
+	local.repoRoot = fs.AbsolutePathFromUpstream(t.TempDir())
+	assert.NilError(t, local.repoRoot.Join("a").WriteFile([]byte("a"), 0644), "WriteFile")
+	assert.NilError(t, local.Put("unused", "localhash", 0, []string{"a"}), "Put")
+	_, err = ExportBundle(&bytes.Buffer{}, opts, "team_id", []string{"localhash"})
+	assert.ErrorContains(t, err, "failed to sign localhash")
+}
+
+func TestImportBundleRejectsInvalidBundles(t *testing.T) {
+	artifact := makeTar(t, file("my-pkg/a", "a"))
+	testCases := []struct {
+		name    string
+		entries []tarEntry
+		wantErr string
+	}{
+		{
+			name: "hash that names another path",
+			entries: []tarEntry{
+				file("../evil.json", `{"hash":"../evil"}`),
+				file("../evil.tar.gz", string(artifact)),
+			},
+			wantErr: "has an invalid hash",
+		},
+		{
+			name:    "artifact without metadata",
+			entries: []tarEntry{file("thehash.tar.gz", string(artifact))},
+			wantErr: "has no metadata",
+		},
+		{
+			name: "artifact for another hash",
+			entries: []tarEntry{
+				file("thehash.json", `{"hash":"thehash"}`),
+				file("otherhash.tar.gz", string(artifact)),
+			},
+			wantErr: "has no metadata",
+		},
+		{
+			name:    "unexpected entry",
+			entries: []tarEntry{link(tar.TypeSymlink, "thehash.json", "/etc/passwd")},
+			wantErr: "unexpected entry",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bundle := &bytes.Buffer{}
+			tw := tar.NewWriter(bundle)
+			for _, entry := range tc.entries {
+				assert.NilError(t, tw.WriteHeader(entry.hdr), "WriteHeader")
+				_, err := tw.Write([]byte(entry.contents))
+				assert.NilError(t, err, "Write")
+			}
+			assert.NilError(t, tw.Close(), "Close")
+
+			cacheDir := fs.AbsolutePathFromUpstream(t.TempDir())
+			imported, err := ImportBundle(bundle, Opts{Dir: cacheDir}, "team_id")
+			assert.ErrorContains(t, err, tc.wantErr)
+			assert.Equal(t, len(imported), 0)
+		})
+	}
+}
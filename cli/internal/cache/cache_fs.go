@@ -5,11 +5,17 @@ package cache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
+	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
+	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/vercel/turborepo/cli/internal/analytics"
 	"github.com/vercel/turborepo/cli/internal/fs"
 	"golang.org/x/sync/errgroup"
@@ -23,42 +29,80 @@ type fsCache struct {
 }
 
 // newFsCache creates a new filesystem cache
-func newFsCache(opts Opts, recorder analytics.Recorder, repoRoot fs.AbsolutePath) (*fsCache, error) {
-	if err := opts.Dir.MkdirAll(); err != nil {
+func newFsCache(dir fs.AbsolutePath, recorder analytics.Recorder, repoRoot fs.AbsolutePath) (*fsCache, error) {
+	if err := dir.MkdirAll(); err != nil {
 		return nil, err
 	}
 	return &fsCache{
-		cacheDirectory: opts.Dir.ToStringDuringMigration(),
+		cacheDirectory: dir.ToStringDuringMigration(),
 		recorder:       recorder,
 		repoRoot:       repoRoot,
 	}, nil
 }
 
 // Fetch returns true if items are cached. It moves them into position as a side effect.
-func (f *fsCache) Fetch(target, hash string, _unusedOutputGlobs []string) (bool, []string, int, error) {
+// Entries without valid metadata are incomplete, and are treated as misses.
+func (f *fsCache) Fetch(target, hash string, outputGlobs []string) (bool, []string, int, error) {
 	cachedFolder := filepath.Join(f.cacheDirectory, hash)
 
-	// If it's not in the cache bail now
-	if !fs.PathExists(cachedFolder) {
+	// If it's not in the cache bail now. Put never replaces a complete entry, so there's no
+	// need to hold the entry's lock while reading it.
+	meta, err := f.readMeta(hash)
+	if err != nil || !fs.PathExists(cachedFolder) {
+		f.logFetch(false, hash, 0)
+		return false, nil, 0, nil
+	}
+	if err := verifyEntry(cachedFolder, meta); err != nil {
+		log.Printf("[WARNING] Ignoring the local cache entry for %v: %v", hash, err)
+		if errors.Is(err, errCorruptArtifact) {
+			if err := f.quarantine(hash); err != nil {
+				log.Printf("[WARNING] Failed to quarantine the local cache entry for %v: %v", hash, err)
+			}
+		}
 		f.logFetch(false, hash, 0)
 		return false, nil, 0, nil
 	}
 
 	// Otherwise, copy it into position
-	err := fs.RecursiveCopy(cachedFolder, target)
+	targetPath := fs.AbsolutePathFromUpstream(target)
+	if err := CleanOutputs(targetPath, outputGlobs); err != nil {
+		return false, nil, 0, fmt.Errorf("error cleaning outputs in %v: %w", target, err)
+	}
+	err = restoreDirectory(targetPath, cachedFolder)
 	if err != nil {
 		// TODO: what event to log here?
 		return false, nil, 0, fmt.Errorf("error moving artifact from cache into %v: %w", target, err)
 	}
 
-	meta, err := ReadCacheMetaFile(filepath.Join(f.cacheDirectory, hash+"-meta.json"))
-	if err != nil {
-		return false, nil, 0, fmt.Errorf("error reading cache metadata: %w", err)
-	}
 	f.logFetch(true, hash, meta.Duration)
 	return true, nil, meta.Duration, nil
 }
 
+// Exists checks for a complete entry for hash. Unlike Fetch, it doesn't verify the entry's files.
+func (f *fsCache) Exists(hash string) (ItemStatus, error) {
+	meta, err := f.readMeta(hash)
+	if err != nil || !fs.PathExists(filepath.Join(f.cacheDirectory, hash)) {
+		return ItemStatus{}, nil
+	}
+	return ItemStatus{Local: true, Duration: meta.Duration}, nil
+}
+
+// readMeta returns the metadata of the entry for hash, which is only written once the entry is complete
+func (f *fsCache) readMeta(hash string) (*CacheMetadata, error) {
+	meta, err := ReadCacheMetaFile(f.metaPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	if meta.Hash != hash {
+		return nil, fmt.Errorf("cache metadata for %v has the wrong hash %v", hash, meta.Hash)
+	}
+	return meta, nil
+}
+
+func (f *fsCache) metaPath(hash string) string {
+	return filepath.Join(f.cacheDirectory, hash+_metaFileSuffix)
+}
+
 func (f *fsCache) logFetch(hit bool, hash string, duration int) {
 	var event string
 	if hit {
@@ -75,7 +119,29 @@ func (f *fsCache) logFetch(hit bool, hash string, duration int) {
 	f.recorder.LogEvent(payload)
 }
 
+// Put copies the files into a temporary directory in the cache, and moves it into place once
+// it's complete, so that an interrupted Put never leaves behind an entry that looks valid
 func (f *fsCache) Put(target, hash string, duration int, files []string) error {
+	return f.put(hash, duration, files, nil)
+}
+
+// putSigned stores the files like Put, along with the signed remote cache artifact they were
+// restored from, so that the entry can be exported with its original signature
+func (f *fsCache) putSigned(target, hash string, duration int, files []string, signed *signedArtifact) error {
+	return f.put(hash, duration, files, signed)
+}
+
+func (f *fsCache) put(hash string, duration int, files []string, signed *signedArtifact) error {
+	tempDir, err := os.MkdirTemp(f.cacheDirectory, hash+_tempEntrySuffix)
+	if err != nil {
+		return fmt.Errorf("error creating cache entry: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	// manifest maps the posix-style path of each regular file in the entry to its digest
+	manifest := make(map[string]string, len(files))
+	var manifestMu sync.Mutex
+
 	g := new(errgroup.Group)
 
 	numDigesters := runtime.NumCPU()
@@ -90,13 +156,22 @@ func (f *fsCache) Put(target, hash string, duration int, files []string) error {
 					return fmt.Errorf("error stat'ing cache source %v: %v", file, err)
 				}
 				if !fromType.IsDir() {
-					if err := fs.EnsureDir(filepath.Join(f.cacheDirectory, hash, file)); err != nil {
+					if err := fs.EnsureDir(filepath.Join(tempDir, file)); err != nil {
 						return fmt.Errorf("error ensuring directory file from cache: %w", err)
 					}
 
-					if err := fs.CopyFile(&statedFile, filepath.Join(f.cacheDirectory, hash, file)); err != nil {
+					if err := fs.CopyFile(&statedFile, filepath.Join(tempDir, file)); err != nil {
 						return fmt.Errorf("error copying file from cache: %w", err)
 					}
+					if fromType&os.ModeSymlink == 0 {
+						digest, err := fs.GitLikeHashFile(filepath.Join(tempDir, file))
+						if err != nil {
+							return fmt.Errorf("error hashing cached file: %w", err)
+						}
+						manifestMu.Lock()
+						manifest[filepath.ToSlash(file)] = digest
+						manifestMu.Unlock()
+					}
 				}
 			}
 			return nil
@@ -112,12 +187,132 @@ func (f *fsCache) Put(target, hash string, duration int, files []string) error {
 		return err
 	}
 
-	WriteCacheMetaFile(filepath.Join(f.cacheDirectory, hash+"-meta.json"), &CacheMetadata{
+	return f.commit(hash, tempDir, &CacheMetadata{
 		Duration: duration,
 		Hash:     hash,
-	})
+		Manifest: manifest,
+	}, signed)
+}
+
+// _tempEntrySuffix is added to the hash in the names of entries that are still being written.
+// Put removes its temporary directory when it returns, but a process that is killed while
+// writing an entry leaves it behind. turbo never cleans these up, so they can be deleted by
+// hand when no turbo process is writing to the cache.
+const _tempEntrySuffix = ".tmp-"
+
+// _signedArtifactSuffix is added to the hash in the name of the signed artifact that an entry
+// was restored from
+const _signedArtifactSuffix = "-artifact.tar.gz"
+
+// commit moves the complete entry in tempDir into place, followed by the signed artifact it
+// came from, if any, and its metadata, which marks the entry as valid
+func (f *fsCache) commit(hash string, tempDir string, meta *CacheMetadata, signed *signedArtifact) error {
+	unlock, err := f.lock(hash)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := f.readMeta(hash); err == nil {
+		// Another process stored this entry while we were writing ours
+		return nil
+	}
+	// Remove whatever an interrupted Put left behind
+	metaPath := f.metaPath(hash)
+	if err := os.Remove(metaPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	signedArtifactPath := f.signedArtifactPath(hash)
+	if err := os.Remove(signedArtifactPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	cachedFolder := filepath.Join(f.cacheDirectory, hash)
+	if err := os.RemoveAll(cachedFolder); err != nil {
+		return err
+	}
+	if err := os.Rename(tempDir, cachedFolder); err != nil {
+		return fmt.Errorf("error moving cache entry into place: %w", err)
+	}
+	if signed != nil {
+		if err := writeFileAtomically(signedArtifactPath, signed.body); err != nil {
+			return fmt.Errorf("error storing signed artifact: %w", err)
+		}
+		meta.Tag = signed.tag
+	}
+	return WriteCacheMetaFile(metaPath, meta)
+}
+
+func (f *fsCache) signedArtifactPath(hash string) string {
+	return filepath.Join(f.cacheDirectory, hash+_signedArtifactSuffix)
+}
+
+// readSignedArtifact returns the signed artifact that the entry for hash was restored from, or
+// nil if it wasn't restored from one
+func (f *fsCache) readSignedArtifact(hash string, meta *CacheMetadata) (*signedArtifact, error) {
+	if meta.Tag == "" {
+		return nil, nil
+	}
+	body, err := ioutil.ReadFile(f.signedArtifactPath(hash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &signedArtifact{tag: meta.Tag, body: body}, nil
+}
+
+const _lockTimeout = 30 * time.Second
+
+// errLockHeld is returned by tryLockFile when another process holds the lock
+var errLockHeld = errors.New("lock is held by another process")
+
+// _entryMutexes holds a *sync.Mutex for each lock file path that this process has locked.
+// File locks only exclude other processes, so goroutines are serialized with these.
+var _entryMutexes sync.Map
+
+// lock acquires the lock for writing the entry for hash, and returns a function that releases
+// it. Other turbo processes that share the cache directory, including processes on other
+// machines that mount it over NFS, are excluded with an advisory lock on a file next to the
+// entry. The lock file is left in place, since removing it could let two processes lock
+// different files with the same name.
+func (f *fsCache) lock(hash string) (func(), error) {
+	lockPath, err := filepath.Abs(filepath.Join(f.cacheDirectory, hash+".lock"))
+	if err != nil {
+		return nil, err
+	}
+	mu, _ := _entryMutexes.LoadOrStore(lockPath, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
 
-	return nil
+	lockFile, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		mu.(*sync.Mutex).Unlock()
+		return nil, fmt.Errorf("error locking cache entry %v: %w", hash, err)
+	}
+	err = backoff.Retry(func() error {
+		err := tryLockFile(lockFile)
+		if err != nil && !errors.Is(err, errLockHeld) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, &backoff.ExponentialBackOff{
+		InitialInterval:     5 * time.Millisecond,
+		RandomizationFactor: backoff.DefaultRandomizationFactor,
+		Multiplier:          2,
+		MaxInterval:         500 * time.Millisecond,
+		MaxElapsedTime:      _lockTimeout,
+		Clock:               backoff.SystemClock,
+		Stop:                backoff.Stop,
+	})
+	if err != nil {
+		_ = lockFile.Close()
+		mu.(*sync.Mutex).Unlock()
+		return nil, fmt.Errorf("error locking cache entry %v: %w", hash, err)
+	}
+	return func() {
+		_ = unlockFile(lockFile)
+		_ = lockFile.Close()
+		mu.(*sync.Mutex).Unlock()
+	}, nil
 }
 
 func (f *fsCache) Clean(target string) {
@@ -135,19 +330,46 @@ func (cache *fsCache) Shutdown() {}
 type CacheMetadata struct {
 	Hash     string `json:"hash"`
 	Duration int    `json:"duration"`
+	// Manifest maps the posix-style path of each regular file in the entry to its git-like
+	// SHA1. Entries written by older versions of turbo don't have one.
+	Manifest map[string]string `json:"manifest"`
+	// Tag is the signature of the remote cache artifact that the entry was restored from,
+	// which is kept next to the entry. It's only set if remote cache signing is enabled.
+	Tag string `json:"tag,omitempty"`
 }
 
-// WriteCacheMetaFile writes cache metadata file at a path
+// WriteCacheMetaFile writes cache metadata file at a path. The file is written to a temporary
+// file first and then renamed, so that it's never seen partially written.
 func WriteCacheMetaFile(path string, config *CacheMetadata) error {
 	jsonBytes, marshalErr := json.Marshal(config)
 	if marshalErr != nil {
 		return marshalErr
 	}
-	writeFilErr := ioutil.WriteFile(path, jsonBytes, 0644)
-	if writeFilErr != nil {
-		return writeFilErr
+	return writeFileAtomically(path, jsonBytes)
+}
+
+// writeFileAtomically writes contents to a temporary file next to path, and renames it to path
+func writeFileAtomically(path string, contents []byte) error {
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+_tempEntrySuffix)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tempFile.Name()) }()
+	if _, err := tempFile.Write(contents); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		_ = tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempFile.Name(), 0644); err != nil {
+		return err
 	}
-	return nil
+	return os.Rename(tempFile.Name(), path)
 }
 
 // ReadCacheMetaFile reads cache metadata file at a path
@@ -1,9 +1,13 @@
 package cache
 
 import (
+	"bufio"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/vercel/turborepo/cli/internal/analytics"
@@ -275,3 +279,238 @@ func TestFetch(t *testing.T) {
 	assert.NilError(t, err, "ReadDir")
 	assert.Equal(t, len(entries), 0)
 }
+
+func TestFetchIncompleteEntry(t *testing.T) {
+	testCases := []struct {
+		name string
+		meta string
+	}{
+		{name: "missing metadata"},
+		{name: "truncated metadata", meta: `{"hash":"the-ha`},
+		{name: "metadata for another hash", meta: `{"hash":"other-hash","duration":0}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+			entryFile := filepath.Join(cacheDir, "the-hash", "some-package", "a")
+			assert.NilError(t, fs.EnsureDir(entryFile), "EnsureDir")
+			assert.NilError(t, ioutil.WriteFile(entryFile, []byte("partial"), 0644), "WriteFile")
+			if tc.meta != "" {
+				assert.NilError(t, ioutil.WriteFile(filepath.Join(cacheDir, "the-hash-meta.json"), []byte(tc.meta), 0644), "WriteFile")
+			}
+
+			cache := &fsCache{
+				cacheDirectory: cacheDir,
+				recorder:       &dummyRecorder{},
+				repoRoot:       repoRoot,
+			}
+			status, err := cache.Exists("the-hash")
+			assert.NilError(t, err, "Exists")
+			assert.Equal(t, status, ItemStatus{})
+			hit, _, _, err := cache.Fetch(repoRoot.ToString(), "the-hash", nil)
+			assert.NilError(t, err, "Fetch")
+			assert.Equal(t, hit, false)
+			_, err = repoRoot.Join("some-package").Lstat()
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+func TestPutReplacesIncompleteEntry(t *testing.T) {
+	cacheDir := t.TempDir()
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	assert.NilError(t, repoRoot.Join("some-package", "a").EnsureDir(), "EnsureDir")
+	assert.NilError(t, repoRoot.Join("some-package", "a").WriteFile([]byte("a"), 0644), "WriteFile")
+	// An interrupted Put left a file behind, but no metadata
+	staleFile := filepath.Join(cacheDir, "the-hash", "some-package", "stale")
+	assert.NilError(t, fs.EnsureDir(staleFile), "EnsureDir")
+	assert.NilError(t, ioutil.WriteFile(staleFile, []byte("stale"), 0644), "WriteFile")
+
+	cache := &fsCache{
+		cacheDirectory: cacheDir,
+		recorder:       &dummyRecorder{},
+		repoRoot:       repoRoot,
+	}
+	err := cache.Put("unused", "the-hash", 10, []string{filepath.Join("some-package", "a")})
+	assert.NilError(t, err, "Put")
+
+	_, err = os.Lstat(staleFile)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	meta, err := ReadCacheMetaFile(filepath.Join(cacheDir, "the-hash-meta.json"))
+	assert.NilError(t, err, "ReadCacheMetaFile")
+	assert.Equal(t, meta.Duration, 10)
+	assertNoTempEntries(t, cacheDir)
+	status, err := cache.Exists("the-hash")
+	assert.NilError(t, err, "Exists")
+	assert.Equal(t, status, ItemStatus{Local: true, Duration: 10})
+}
+
+func TestPutConcurrently(t *testing.T) {
+	cacheDir := t.TempDir()
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	assert.NilError(t, repoRoot.Join("some-package", "a").EnsureDir(), "EnsureDir")
+	assert.NilError(t, repoRoot.Join("some-package", "a").WriteFile([]byte("a"), 0644), "WriteFile")
+
+	// Each cache stands in for a separate task run writing the same entry
+	errs := make(chan error)
+	for i := 0; i < 8; i++ {
+		go func() {
+			cache := &fsCache{
+				cacheDirectory: cacheDir,
+				recorder:       &dummyRecorder{},
+				repoRoot:       repoRoot,
+			}
+			errs <- cache.Put("unused", "the-hash", 0, []string{filepath.Join("some-package", "a")})
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		assert.NilError(t, <-errs, "Put")
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(cacheDir, "the-hash", "some-package", "a"))
+	assert.NilError(t, err, "ReadFile")
+	assert.Equal(t, string(contents), "a")
+	_, err = ReadCacheMetaFile(filepath.Join(cacheDir, "the-hash-meta.json"))
+	assert.NilError(t, err, "ReadCacheMetaFile")
+	assertNoTempEntries(t, cacheDir)
+}
+
+// TestLockHelperProcess isn't a real test. TestLockExcludesOtherProcesses runs it in a
+// separate process to hold the lock on a file until its stdin is closed.
+func TestLockHelperProcess(t *testing.T) {
+	lockPath := os.Getenv("TURBO_TEST_LOCK_FILE")
+	if lockPath == "" {
+		return
+	}
+	lockFile, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		os.Exit(1)
+	}
+	if err := tryLockFile(lockFile); err != nil {
+		os.Exit(1)
+	}
+	fmt.Println("locked")
+	_, _ = ioutil.ReadAll(os.Stdin)
+	os.Exit(0)
+}
+
+func TestLockExcludesOtherProcesses(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "the-hash.lock")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+	cmd.Env = append(os.Environ(), "TURBO_TEST_LOCK_FILE="+lockPath)
+	stdin, err := cmd.StdinPipe()
+	assert.NilError(t, err, "StdinPipe")
+	stdout, err := cmd.StdoutPipe()
+	assert.NilError(t, err, "StdoutPipe")
+	assert.NilError(t, cmd.Start(), "Start")
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	assert.NilError(t, err, "reading from the helper process")
+	assert.Equal(t, line, "locked\n")
+
+	lockFile, err := os.OpenFile(lockPath, os.O_RDWR, 0644)
+	assert.NilError(t, err, "OpenFile")
+	defer func() { _ = lockFile.Close() }()
+	assert.ErrorIs(t, tryLockFile(lockFile), errLockHeld)
+
+	// The lock is released when the process holding it exits
+	assert.NilError(t, stdin.Close(), "Close")
+	assert.NilError(t, cmd.Wait(), "Wait")
+	assert.NilError(t, tryLockFile(lockFile), "tryLockFile")
+	assert.NilError(t, unlockFile(lockFile), "unlockFile")
+}
+
+func TestFetchCorruptEntry(t *testing.T) {
+	cacheDir := t.TempDir()
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	assert.NilError(t, repoRoot.Join("some-package", "a").EnsureDir(), "EnsureDir")
+	assert.NilError(t, repoRoot.Join("some-package", "a").WriteFile([]byte("a"), 0644), "WriteFile")
+
+	cache := &fsCache{
+		cacheDirectory: cacheDir,
+		recorder:       &dummyRecorder{},
+		repoRoot:       repoRoot,
+	}
+	err := cache.Put("unused", "the-hash", 0, []string{filepath.Join("some-package", "a")})
+	assert.NilError(t, err, "Put")
+	meta, err := ReadCacheMetaFile(filepath.Join(cacheDir, "the-hash-meta.json"))
+	assert.NilError(t, err, "ReadCacheMetaFile")
+	assert.Equal(t, len(meta.Manifest), 1)
+
+	// Damage the stored file, then remove the outputs so that a hit would restore it
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(cacheDir, "the-hash", "some-package", "a"), []byte("b"), 0644), "WriteFile")
+	assert.NilError(t, repoRoot.Join("some-package").RemoveAll(), "RemoveAll")
+
+	hit, _, _, err := cache.Fetch(repoRoot.ToString(), "the-hash", nil)
+	assert.NilError(t, err, "Fetch")
+	assert.Equal(t, hit, false)
+	_, err = repoRoot.Join("some-package").Lstat()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Lstat(filepath.Join(cacheDir, "the-hash"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Lstat(filepath.Join(cacheDir, _quarantineDir, "the-hash", "some-package", "a"))
+	assert.NilError(t, err, "Lstat")
+}
+
+func TestVerifyLocal(t *testing.T) {
+	cacheDir := t.TempDir()
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	for _, name := range []string{"a", "b"} {
+		assert.NilError(t, repoRoot.Join("some-package", name).EnsureDir(), "EnsureDir")
+		assert.NilError(t, repoRoot.Join("some-package", name).WriteFile([]byte(name), 0644), "WriteFile")
+	}
+	cache := &fsCache{
+		cacheDirectory: cacheDir,
+		recorder:       &dummyRecorder{},
+		repoRoot:       repoRoot,
+	}
+	for _, hash := range []string{"intact", "damaged", "missing-file", "extra-file"} {
+		err := cache.Put("unused", hash, 0, []string{filepath.Join("some-package", "a"), filepath.Join("some-package", "b")})
+		assert.NilError(t, err, "Put")
+	}
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(cacheDir, "damaged", "some-package", "a"), []byte("damaged"), 0644), "WriteFile")
+	assert.NilError(t, os.Remove(filepath.Join(cacheDir, "missing-file", "some-package", "b")), "Remove")
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(cacheDir, "extra-file", "some-package", "c"), []byte("c"), 0644), "WriteFile")
+	// Entries stored before manifests existed can't be checked
+	assert.NilError(t, fs.EnsureDir(filepath.Join(cacheDir, "legacy", "some-package", "a")), "EnsureDir")
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(cacheDir, "legacy", "some-package", "a"), []byte("a"), 0644), "WriteFile")
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(cacheDir, "legacy-meta.json"), []byte(`{"hash":"legacy","duration":0}`), 0644), "WriteFile")
+
+	results, err := VerifyLocal(fs.AbsolutePathFromUpstream(cacheDir))
+	assert.NilError(t, err, "VerifyLocal")
+	assert.Equal(t, len(results), 5)
+	for _, result := range results {
+		switch result.Hash {
+		case "intact":
+			assert.NilError(t, result.Err)
+			assert.Equal(t, result.Unverified, false)
+		case "legacy":
+			assert.NilError(t, result.Err)
+			assert.Equal(t, result.Unverified, true)
+		default:
+			assert.ErrorIs(t, result.Err, errCorruptArtifact, result.Hash)
+			assert.Equal(t, result.Quarantined, true)
+			_, err := os.Lstat(filepath.Join(cacheDir, result.Hash+"-meta.json"))
+			assert.ErrorIs(t, err, os.ErrNotExist)
+			_, err = os.Lstat(filepath.Join(cacheDir, _quarantineDir, result.Hash+"-meta.json"))
+			assert.NilError(t, err, "Lstat")
+		}
+	}
+
+	// Quarantined entries aren't checked again
+	results, err = VerifyLocal(fs.AbsolutePathFromUpstream(cacheDir))
+	assert.NilError(t, err, "VerifyLocal")
+	assert.Equal(t, len(results), 2)
+}
+
+func assertNoTempEntries(t *testing.T, cacheDir string) {
+	t.Helper()
+	entries, err := os.ReadDir(cacheDir)
+	assert.NilError(t, err, "ReadDir")
+	// Lock files are left in place, so only temporary entries are checked
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), _tempEntrySuffix) {
+			t.Errorf("unexpected leftover %v in the cache directory", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+)
+
+// _quarantineDir is the directory in the local cache that corrupt entries are moved to
+const _quarantineDir = "quarantine"
+
+const _metaFileSuffix = "-meta.json"
+
+// VerifyResult is the outcome of checking an entry in the local cache
+type VerifyResult struct {
+	Hash string
+	// Err is why the entry is corrupt, or nil if it's intact
+	Err error
+	// Unverified is true for entries that were stored without a manifest to check them against
+	Unverified bool
+	// Quarantined is true if the entry was corrupt and has been moved to the quarantine directory
+	Quarantined bool
+}
+
+// VerifyLocal checks every entry of the local cache at dir against its manifest, and moves the
+// corrupt entries to the quarantine directory of the cache
+func VerifyLocal(dir fs.AbsolutePath) ([]VerifyResult, error) {
+	f := &fsCache{cacheDirectory: dir.ToString()}
+	dirEntries, err := os.ReadDir(dir.ToString())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var results []VerifyResult
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if !strings.HasSuffix(name, _metaFileSuffix) || strings.Contains(name, _tempEntrySuffix) {
+			continue
+		}
+		hash := strings.TrimSuffix(name, _metaFileSuffix)
+		result := VerifyResult{Hash: hash}
+		if meta, err := f.readMeta(hash); err != nil {
+			result.Err = fmt.Errorf("%w: invalid metadata: %v", errCorruptArtifact, err)
+		} else if !fs.PathExists(filepath.Join(f.cacheDirectory, hash)) {
+			result.Err = fmt.Errorf("%w: its files are missing", errCorruptArtifact)
+		} else {
+			result.Unverified = meta.Manifest == nil
+			result.Err = verifyEntry(filepath.Join(f.cacheDirectory, hash), meta)
+		}
+		if errors.Is(result.Err, errCorruptArtifact) {
+			if err := f.quarantine(hash); err != nil {
+				return nil, fmt.Errorf("failed to quarantine %v: %w", hash, err)
+			}
+			result.Quarantined = true
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Hash < results[j].Hash
+	})
+	return results, nil
+}
+
+// verifyEntry checks that the files of the entry stored in dir match its manifest. Entries
+// without a manifest can't be checked.
+func verifyEntry(dir string, meta *CacheMetadata) error {
+	if meta.Manifest == nil {
+		return nil
+	}
+	verified := 0
+	err := fs.WalkMode(dir, func(name string, isDir bool, mode os.FileMode) error {
+		if isDir || mode&os.ModeSymlink != 0 {
+			return nil
+		}
+		relativePath, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		file := filepath.ToSlash(relativePath)
+		expected, ok := meta.Manifest[file]
+		if !ok {
+			return fmt.Errorf("%w: %v isn't in its manifest", errCorruptArtifact, file)
+		}
+		digest, err := fs.GitLikeHashFile(name)
+		if err != nil {
+			return err
+		}
+		if digest != expected {
+			return fmt.Errorf("%w: %v doesn't match its digest", errCorruptArtifact, file)
+		}
+		verified++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if verified != len(meta.Manifest) {
+		files := make([]string, 0, len(meta.Manifest))
+		for file := range meta.Manifest {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			if !fs.FileExists(filepath.Join(dir, filepath.FromSlash(file))) {
+				return fmt.Errorf("%w: %v is missing", errCorruptArtifact, file)
+			}
+		}
+	}
+	return nil
+}
+
+// quarantine moves a corrupt entry into the quarantine directory of the cache, where it can be
+// inspected, and where it won't be restored
+func (f *fsCache) quarantine(hash string) error {
+	unlock, err := f.lock(hash)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	quarantineDir := filepath.Join(f.cacheDirectory, _quarantineDir)
+	if err := os.MkdirAll(quarantineDir, fs.DirPermissions); err != nil {
+		return err
+	}
+	// The metadata goes first, since an entry without it is already a miss
+	for _, name := range []string{hash + _metaFileSuffix, hash, hash + _signedArtifactSuffix} {
+		dest := filepath.Join(quarantineDir, name)
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(f.cacheDirectory, name), dest); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/vercel/turborepo/cli/internal/analytics"
@@ -25,15 +26,19 @@ import (
 type client interface {
 	PutArtifact(hash string, body []byte, duration int, tag string) error
 	FetchArtifact(hash string) (*http.Response, error)
+	ArtifactExists(hash string) (*http.Response, error)
 }
 
 type httpCache struct {
+	// writable is false if artifacts must never be uploaded, such as for a read-only tier
 	writable       bool
 	client         client
 	requestLimiter limiter
 	recorder       analytics.Recorder
 	signerVerifier *ArtifactSignatureAuthentication
 	repoRoot       fs.AbsolutePath
+	// warnNoSigningKey makes sure that skipped uploads are only reported once per run
+	warnNoSigningKey sync.Once
 }
 
 type limiter chan struct{}
@@ -53,7 +58,16 @@ var mtime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
 const nobody = 65534
 
 func (cache *httpCache) Put(target, hash string, duration int, files []string) error {
-	// if cache.writable {
+	if !cache.writable {
+		return nil
+	}
+	if cache.signerVerifier.isEnabled() && !cache.signerVerifier.canSign() {
+		// Without the private key, uploaded artifacts would be rejected by everyone
+		cache.warnNoSigningKey.Do(func() {
+			log.Printf("[WARNING] Not uploading artifacts to the remote cache: remoteCache.publicKey is set, but %v isn't", _privateKeyEnv)
+		})
+		return nil
+	}
 	cache.requestLimiter.acquire()
 	defer cache.requestLimiter.release()
 
@@ -86,21 +100,23 @@ func (cache *httpCache) write(w io.WriteCloser, hash string, files []string) {
 	defer tw.Close()
 	for _, file := range files {
 		// log.Printf("caching file %v", file)
-		if err := cache.storeFile(tw, file); err != nil {
+		if err := storeFile(tw, cache.repoRoot.ToString(), file); err != nil {
 			log.Printf("[ERROR] Error uploading artifact %s to HTTP cache due to: %s", file, err)
 			// TODO(jaredpalmer): How can we cancel the request at this point?
 		}
 	}
 }
 
-func (cache *httpCache) storeFile(tw *tar.Writer, repoRelativePath string) error {
-	info, err := os.Lstat(repoRelativePath)
+// storeFile writes the file at repoRelativePath under root into the artifact
+func storeFile(tw *tar.Writer, root string, repoRelativePath string) error {
+	path := filepath.Join(root, repoRelativePath)
+	info, err := os.Lstat(path)
 	if err != nil {
 		return err
 	}
 	target := ""
 	if info.Mode()&os.ModeSymlink != 0 {
-		target, err = os.Readlink(repoRelativePath)
+		target, err = os.Readlink(path)
 		if err != nil {
 			return err
 		}
@@ -120,12 +136,20 @@ func (cache *httpCache) storeFile(tw *tar.Writer, repoRelativePath string) error
 	hdr.Gid = nobody
 	hdr.Uname = "nobody"
 	hdr.Gname = "nobody"
+	if info.Mode().IsRegular() {
+		// Record the file's digest, so that restoring the artifact can check it wasn't damaged
+		digest, err := fs.GitLikeHashFile(path)
+		if err != nil {
+			return err
+		}
+		hdr.PAXRecords = map[string]string{_digestPAXRecord: digest}
+	}
 	if err := tw.WriteHeader(hdr); err != nil {
 		return err
 	} else if info.IsDir() || target != "" {
 		return nil // nothing to write
 	}
-	f, err := os.Open(repoRelativePath)
+	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
@@ -137,16 +161,44 @@ func (cache *httpCache) storeFile(tw *tar.Writer, repoRelativePath string) error
 	return err
 }
 
-func (cache *httpCache) Fetch(target, key string, _unusedOutputGlobs []string) (bool, []string, int, error) {
+func (cache *httpCache) Fetch(target, key string, outputGlobs []string) (bool, []string, int, error) {
+	hit, files, duration, _, err := cache.fetchSigned(target, key, outputGlobs)
+	return hit, files, duration, err
+}
+
+// fetchSigned is like Fetch, and also returns the artifact and its tag if signing is enabled
+func (cache *httpCache) fetchSigned(target, key string, outputGlobs []string) (bool, []string, int, *signedArtifact, error) {
 	cache.requestLimiter.acquire()
 	defer cache.requestLimiter.release()
-	hit, files, duration, err := cache.retrieve(key)
+	hit, files, duration, signed, err := cache.retrieve(key, outputGlobs)
 	if err != nil {
 		// TODO: analytics event?
-		return false, files, duration, fmt.Errorf("failed to retrieve files from HTTP cache: %w", err)
+		return false, files, duration, nil, fmt.Errorf("failed to retrieve files from HTTP cache: %w", err)
 	}
 	cache.logFetch(hit, key, duration)
-	return hit, files, duration, err
+	return hit, files, duration, signed, err
+}
+
+// Exists asks the remote cache for the artifact's headers. The artifact isn't downloaded, so
+// its signature isn't verified.
+func (cache *httpCache) Exists(hash string) (ItemStatus, error) {
+	cache.requestLimiter.acquire()
+	defer cache.requestLimiter.release()
+	resp, err := cache.client.ArtifactExists(hash)
+	if err != nil {
+		return ItemStatus{}, fmt.Errorf("failed to check HTTP cache: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return ItemStatus{}, nil
+	} else if resp.StatusCode != http.StatusOK {
+		return ItemStatus{}, fmt.Errorf("failed to check HTTP cache: %v", resp.Status)
+	}
+	duration, err := artifactDuration(resp.Header)
+	if err != nil {
+		return ItemStatus{}, err
+	}
+	return ItemStatus{Remote: true, Duration: duration}, nil
 }
 
 func (cache *httpCache) logFetch(hit bool, hash string, duration int) {
@@ -165,157 +217,83 @@ func (cache *httpCache) logFetch(hit bool, hash string, duration int) {
 	cache.recorder.LogEvent(payload)
 }
 
-func (cache *httpCache) retrieve(hash string) (bool, []string, int, error) {
+func (cache *httpCache) retrieve(hash string, outputGlobs []string) (bool, []string, int, *signedArtifact, error) {
+	artifact, duration, signed, err := cache.fetchArtifact(hash)
+	if err != nil || artifact == nil {
+		return false, nil, 0, nil, err
+	}
+	defer func() { _ = artifact.Close() }()
+	if err := CleanOutputs(cache.repoRoot, outputGlobs); err != nil {
+		return false, nil, 0, nil, fmt.Errorf("error cleaning outputs: %w", err)
+	}
+	files, err := restoreTar(cache.repoRoot, artifact)
+	if errors.Is(err, errCorruptArtifact) {
+		log.Printf("[WARNING] Ignoring the remote cache artifact for %v: %v", hash, err)
+		return false, nil, 0, nil, nil
+	} else if err != nil {
+		return false, nil, 0, nil, err
+	}
+	return true, files, duration, signed, nil
+}
+
+// fetchArtifact downloads the artifact for the given hash, and verifies its signature if
+// signing is enabled. It returns the artifact's tar and the duration of the task that made it,
+// or a nil reader if the artifact doesn't exist. If signing is enabled, it also returns the
+// verified artifact and its tag. The caller must close the reader.
+func (cache *httpCache) fetchArtifact(hash string) (io.ReadCloser, int, *signedArtifact, error) {
 	resp, err := cache.client.FetchArtifact(hash)
 	if err != nil {
-		return false, nil, 0, err
+		return nil, 0, nil, err
 	}
-	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusNotFound {
-		return false, nil, 0, nil // doesn't exist - not an error
+		_ = resp.Body.Close()
+		return nil, 0, nil, nil // doesn't exist - not an error
 	} else if resp.StatusCode != http.StatusOK {
 		b, _ := ioutil.ReadAll(resp.Body)
-		return false, nil, 0, fmt.Errorf("%s", string(b))
+		_ = resp.Body.Close()
+		return nil, 0, nil, fmt.Errorf("%s", string(b))
 	}
-	// If present, extract the duration from the response.
-	duration := 0
-	if resp.Header.Get("x-artifact-duration") != "" {
-		intVar, err := strconv.Atoi(resp.Header.Get("x-artifact-duration"))
-		if err != nil {
-			return false, nil, 0, fmt.Errorf("invalid x-artifact-duration header: %w", err)
-		}
-		duration = intVar
+	duration, err := artifactDuration(resp.Header)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, 0, nil, err
+	}
+	if !cache.signerVerifier.isEnabled() {
+		return resp.Body, duration, nil, nil
 	}
-	var tarReader io.Reader
 
 	defer func() { _ = resp.Body.Close() }()
-	if cache.signerVerifier.isEnabled() {
-		expectedTag := resp.Header.Get("x-artifact-tag")
-		if expectedTag == "" {
-			// If the verifier is enabled all incoming artifact downloads must have a signature
-			return false, nil, 0, errors.New("artifact verification failed: Downloaded artifact is missing required x-artifact-tag header")
-		}
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return false, nil, 0, fmt.Errorf("artifact verification failed: %w", err)
-		}
-		isValid, err := cache.signerVerifier.validate(hash, b, expectedTag)
-		if err != nil {
-			return false, nil, 0, fmt.Errorf("artifact verification failed: %w", err)
-		}
-		if !isValid {
-			err = fmt.Errorf("artifact verification failed: artifact tag does not match expected tag %s", expectedTag)
-			return false, nil, 0, err
-		}
-		// The artifact has been verified and the body can be read and untarred
-		tarReader = bytes.NewReader(b)
-	} else {
-		tarReader = resp.Body
+	expectedTag := resp.Header.Get("x-artifact-tag")
+	if expectedTag == "" {
+		// If the verifier is enabled all incoming artifact downloads must have a signature
+		return nil, 0, nil, errors.New("artifact verification failed: Downloaded artifact is missing required x-artifact-tag header")
 	}
-	files, err := restoreTar(cache.repoRoot, tarReader)
+	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return false, nil, 0, err
+		return nil, 0, nil, fmt.Errorf("artifact verification failed: %w", err)
 	}
-	return true, files, duration, nil
-}
-
-// restoreTar returns posix-style repo-relative paths of the files it
-// restored. In the future, these should likely be repo-relative system paths
-// so that they are suitable for being fed into cache.Put for other caches.
-// For now, I think this is working because windows also accepts /-delimited paths.
-func restoreTar(root fs.AbsolutePath, reader io.Reader) ([]string, error) {
-	files := []string{}
-	missingLinks := []*tar.Header{}
-	gzr, err := gzip.NewReader(reader)
+	isValid, err := cache.signerVerifier.validate(hash, b, expectedTag)
 	if err != nil {
-		return nil, err
+		return nil, 0, nil, fmt.Errorf("artifact verification failed: %w", err)
 	}
-	defer func() { _ = gzr.Close() }()
-	tr := tar.NewReader(gzr)
-	for {
-		hdr, err := tr.Next()
-		if err != nil {
-			if err == io.EOF {
-				for _, link := range missingLinks {
-					err := restoreSymlink(root, link, true)
-					if err != nil {
-						return nil, err
-					}
-				}
-
-				return files, nil
-			}
-			return nil, err
-		}
-		// hdr.Name is always a posix-style path
-		// TODO: files should eventually be repo-relative system paths
-		files = append(files, hdr.Name)
-		filename := root.Join(hdr.Name)
-		if isChild, err := root.ContainsPath(filename); err != nil {
-			return nil, err
-		} else if !isChild {
-			return nil, fmt.Errorf("cannot untar file to %v", filename)
-		}
-		switch hdr.Typeflag {
-		case tar.TypeDir:
-			if err := filename.MkdirAll(); err != nil {
-				return nil, err
-			}
-		case tar.TypeReg:
-			if dir := filename.Dir(); dir != "." {
-				if err := dir.MkdirAll(); err != nil {
-					return nil, err
-				}
-			}
-			if f, err := filename.OpenFile(os.O_WRONLY|os.O_TRUNC|os.O_CREATE, os.FileMode(hdr.Mode)); err != nil {
-				return nil, err
-			} else if _, err := io.Copy(f, tr); err != nil {
-				return nil, err
-			} else if err := f.Close(); err != nil {
-				return nil, err
-			}
-		case tar.TypeSymlink:
-			if err := restoreSymlink(root, hdr, false); errors.Is(err, errNonexistentLinkTarget) {
-				missingLinks = append(missingLinks, hdr)
-			} else if err != nil {
-				return nil, err
-			}
-		default:
-			log.Printf("Unhandled file type %d for %s", hdr.Typeflag, hdr.Name)
-		}
+	if !isValid {
+		err = fmt.Errorf("artifact verification failed: artifact tag does not match expected tag %s", expectedTag)
+		return nil, 0, nil, err
 	}
+	// The artifact has been verified and the body can be read and untarred
+	return ioutil.NopCloser(bytes.NewReader(b)), duration, &signedArtifact{tag: expectedTag, body: b}, nil
 }
 
-var errNonexistentLinkTarget = errors.New("the link target does not exist")
-
-func restoreSymlink(root fs.AbsolutePath, hdr *tar.Header, allowNonexistentTargets bool) error {
-	// Note that hdr.Linkname is really the link target
-	relativeLinkTarget := filepath.FromSlash(hdr.Linkname)
-	linkFilename := root.Join(hdr.Name)
-	if err := linkFilename.EnsureDir(); err != nil {
-		return err
-	}
-
-	// TODO: check if this is an absolute path, or if we even care
-	linkTarget := linkFilename.Dir().Join(relativeLinkTarget)
-	if _, err := linkTarget.Lstat(); err != nil {
-		if os.IsNotExist(err) {
-			if !allowNonexistentTargets {
-				return errNonexistentLinkTarget
-			}
-			// if we're allowing nonexistent link targets, proceed to creating the link
-		} else {
-			return err
-		}
+// artifactDuration extracts the duration from the response's headers, if present
+func artifactDuration(header http.Header) (int, error) {
+	if header.Get("x-artifact-duration") == "" {
+		return 0, nil
 	}
-	// Ensure that the link we're about to create doesn't already exist
-	if err := linkFilename.Remove(); err != nil && !errors.Is(err, os.ErrNotExist) {
-		return err
-	}
-	if err := linkFilename.Symlink(relativeLinkTarget); err != nil {
-		return err
+	duration, err := strconv.Atoi(header.Get("x-artifact-duration"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid x-artifact-duration header: %w", err)
 	}
-	return nil
+	return duration, nil
 }
 
 func (cache *httpCache) Clean(target string) {
@@ -334,12 +312,7 @@ func newHTTPCache(opts Opts, teamID string, client client, recorder analytics.Re
 		client:         client,
 		requestLimiter: make(limiter, 20),
 		recorder:       recorder,
-		signerVerifier: &ArtifactSignatureAuthentication{
-			// TODO(Gaspar): this should use RemoteCacheOptions.TeamId once we start
-			// enforcing team restrictions for repositories.
-			teamId:  teamID,
-			enabled: opts.RemoteCacheOpts.Signature,
-		},
-		repoRoot: repoRoot,
+		signerVerifier: newArtifactSignatureAuthentication(opts, teamID),
+		repoRoot:       repoRoot,
 	}
 }
@@ -4,8 +4,14 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"log"
 	"net/http"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/vercel/turborepo/cli/internal/fs"
@@ -25,6 +31,10 @@ func (sr *errorResp) FetchArtifact(hash string) (*http.Response, error) {
 	return nil, sr.err
 }
 
+func (sr *errorResp) ArtifactExists(hash string) (*http.Response, error) {
+	return nil, sr.err
+}
+
 func TestRemoteCachingDisabled(t *testing.T) {
 	clientErr := &util.CacheDisabledError{
 		Status:  util.CachingStatusDisabled,
@@ -45,12 +55,61 @@ func TestRemoteCachingDisabled(t *testing.T) {
 	}
 }
 
+func TestExists(t *testing.T) {
+	client := &artifactResp{artifacts: map[string][]byte{"the-hash": []byte("unused")}}
+	cache := newHTTPCache(Opts{}, "team_id", client, nil, "")
+
+	status, err := cache.Exists("the-hash")
+	assert.NilError(t, err, "Exists")
+	assert.Equal(t, status, ItemStatus{Remote: true, Duration: 1200})
+	status, err = cache.Exists("missing-hash")
+	assert.NilError(t, err, "Exists")
+	assert.Equal(t, status, ItemStatus{})
+}
+
+func TestPutReadOnly(t *testing.T) {
+	client := &errorResp{err: errors.New("the artifact should not be uploaded")}
+	cache := newHTTPCache(Opts{}, "team_id", client, nil, "")
+	cache.writable = false
+	err := cache.Put("unused-target", "some-hash", 0, nil)
+	assert.NilError(t, err, "Put")
+
+	cache.writable = true
+	err = cache.Put("unused-target", "some-hash", 0, nil)
+	assert.ErrorContains(t, err, "should not be uploaded")
+}
+
+func TestPutWithoutPrivateKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err, "GenerateKey")
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY", "")
+	client := &errorResp{err: errors.New("the artifact should not be uploaded")}
+	cache := &httpCache{
+		writable:       true,
+		client:         client,
+		requestLimiter: make(limiter, 20),
+		signerVerifier: &ArtifactSignatureAuthentication{
+			publicKey: base64.StdEncoding.EncodeToString(publicKey),
+		},
+	}
+	logs := &bytes.Buffer{}
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+	err = cache.Put("unused-target", "some-hash", 0, nil)
+	assert.NilError(t, err, "Put")
+	err = cache.Put("unused-target", "other-hash", 0, nil)
+	assert.NilError(t, err, "Put")
+	// Skipping uploads is reported, but only once
+	assert.Equal(t, strings.Count(logs.String(), "Not uploading artifacts to the remote cache"), 1)
+	assert.Assert(t, strings.Contains(logs.String(), "TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY"))
+}
+
 func makeValidTar(t *testing.T) *bytes.Buffer {
 	// <repoRoot>
 	//   my-pkg/
 	//     some-file
 	//     link-to-extra-file -> ../extra-file
-	//     broken-link -> ../../global-dep
+	//     broken-link -> ../global-dep
 	//   extra-file
 
 	t.Helper()
@@ -106,7 +165,7 @@ func makeValidTar(t *testing.T) *bytes.Buffer {
 		Name:     "my-pkg/broken-link",
 		Mode:     int64(0644),
 		Typeflag: tar.TypeSymlink,
-		Linkname: "../../global-dep",
+		Linkname: "../global-dep",
 	}
 	if err := tw.WriteHeader(h); err != nil {
 		t.Fatalf("failed to write header: %v", err)
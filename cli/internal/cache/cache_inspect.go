@@ -0,0 +1,338 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+)
+
+// ErrEntryNotFound is returned when a cache has no artifact for a hash
+var ErrEntryNotFound = errors.New("no cache entry for this hash")
+
+// ErrNotSupported is returned for operations that a cache can't perform, such as listing the
+// artifacts in the remote cache
+var ErrNotSupported = errors.New("not supported by this cache")
+
+// Entry describes an artifact stored in a cache
+type Entry struct {
+	Hash string
+	// Size is the total size of the artifact's files, in bytes
+	Size int64
+	// Duration is how long the task that produced the artifact took, in milliseconds
+	Duration int
+	// Created is when the artifact was stored, or zero if the cache doesn't record it
+	Created time.Time
+	// Files are the artifact's files, sorted by path. Only Show sets them.
+	Files []EntryFile
+	// Logs maps the paths of the task logs in the artifact to their contents. Only Show sets them.
+	Logs map[string]string
+}
+
+// EntryFile is a file, directory or symlink stored in an artifact
+type EntryFile struct {
+	// Path is relative to the repo root, with forward slashes
+	Path       string
+	Size       int64
+	Mode       os.FileMode
+	LinkTarget string
+	// Digest is the git-like SHA1 the file was stored with, if it was recorded
+	Digest string
+}
+
+// Inspector reads and manages the artifacts of a single cache, for the turbo cache commands
+type Inspector interface {
+	// List returns the entries in the cache, newest first
+	List() ([]Entry, error)
+	// Show returns the entry for hash, with its files and task logs
+	Show(hash string) (*Entry, error)
+	// Extract restores the files of the entry for hash into dir, instead of the repo
+	Extract(hash string, dir fs.AbsolutePath) error
+	// Remove deletes the entry for hash
+	Remove(hash string) error
+}
+
+// validateHash checks that hash can be used in the paths and URLs of cache entries, so that
+// a hash given on the command line can't name files outside of the cache
+func validateHash(hash string) error {
+	if !_validHash.MatchString(hash) {
+		return fmt.Errorf("invalid hash %q: expected only letters and digits", hash)
+	}
+	return nil
+}
+
+// NewLocalInspector returns an Inspector for the local cache in dir
+func NewLocalInspector(dir fs.AbsolutePath) Inspector {
+	return &localInspector{cache: &fsCache{cacheDirectory: dir.ToString()}}
+}
+
+// NewRemoteInspector returns an Inspector for the remote cache, which verifies the signatures
+// of artifacts the same way turbo run does
+func NewRemoteInspector(opts Opts, teamID string, client client) Inspector {
+	return &remoteInspector{cache: newHTTPCache(opts, teamID, client, nil, "")}
+}
+
+// isLogFile returns true for the logs that turbo stores along with a task's outputs
+func isLogFile(file string) bool {
+	name := path.Base(file)
+	return path.Base(path.Dir(file)) == ".turbo" && strings.HasPrefix(name, "turbo-") && strings.HasSuffix(name, ".log")
+}
+
+type localInspector struct {
+	cache *fsCache
+}
+
+func (i *localInspector) List() ([]Entry, error) {
+	dirEntries, err := os.ReadDir(i.cache.cacheDirectory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		hash := strings.TrimSuffix(name, _metaFileSuffix)
+		if !strings.HasSuffix(name, _metaFileSuffix) || !_validHash.MatchString(hash) {
+			continue
+		}
+		entry, err := i.entry(hash, false)
+		if errors.Is(err, ErrEntryNotFound) {
+			// Incomplete entries are never restored, so they aren't listed either
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		if !entries[a].Created.Equal(entries[b].Created) {
+			return entries[a].Created.After(entries[b].Created)
+		}
+		return entries[a].Hash < entries[b].Hash
+	})
+	return entries, nil
+}
+
+func (i *localInspector) Show(hash string) (*Entry, error) {
+	return i.entry(hash, true)
+}
+
+// entry reads the entry for hash, along with its files if withFiles is set
+func (i *localInspector) entry(hash string, withFiles bool) (*Entry, error) {
+	if err := validateHash(hash); err != nil {
+		return nil, err
+	}
+	meta, err := i.cache.readMeta(hash)
+	if err != nil {
+		return nil, ErrEntryNotFound
+	}
+	dir := filepath.Join(i.cache.cacheDirectory, hash)
+	if !fs.PathExists(dir) {
+		return nil, ErrEntryNotFound
+	}
+	info, err := os.Stat(i.cache.metaPath(hash))
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{
+		Hash:     hash,
+		Duration: meta.Duration,
+		Created:  info.ModTime(),
+	}
+	err = filepath.WalkDir(dir, func(name string, dirEntry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == dir {
+			return nil
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			entry.Size += info.Size()
+		}
+		if !withFiles {
+			return nil
+		}
+		relativePath, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		file := EntryFile{
+			Path: filepath.ToSlash(relativePath),
+			Mode: info.Mode(),
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(name)
+			if err != nil {
+				return err
+			}
+			file.LinkTarget = filepath.ToSlash(target)
+		case info.Mode().IsRegular():
+			file.Size = info.Size()
+			file.Digest = meta.Manifest[file.Path]
+			if isLogFile(file.Path) {
+				contents, err := ioutil.ReadFile(name)
+				if err != nil {
+					return err
+				}
+				if entry.Logs == nil {
+					entry.Logs = make(map[string]string)
+				}
+				entry.Logs[file.Path] = string(contents)
+			}
+		}
+		entry.Files = append(entry.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (i *localInspector) Extract(hash string, dir fs.AbsolutePath) error {
+	if err := validateHash(hash); err != nil {
+		return err
+	}
+	meta, err := i.cache.readMeta(hash)
+	if err != nil {
+		return ErrEntryNotFound
+	}
+	entryDir := filepath.Join(i.cache.cacheDirectory, hash)
+	if !fs.PathExists(entryDir) {
+		return ErrEntryNotFound
+	}
+	if err := verifyEntry(entryDir, meta); err != nil {
+		return err
+	}
+	if err := dir.MkdirAll(); err != nil {
+		return err
+	}
+	return restoreDirectory(dir, entryDir)
+}
+
+func (i *localInspector) Remove(hash string) error {
+	if err := validateHash(hash); err != nil {
+		return err
+	}
+	unlock, err := i.cache.lock(hash)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// The metadata goes first, so that a partially removed entry is a miss
+	if err := os.Remove(i.cache.metaPath(hash)); errors.Is(err, os.ErrNotExist) {
+		return ErrEntryNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := os.Remove(i.cache.signedArtifactPath(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(i.cache.cacheDirectory, hash))
+}
+
+type remoteInspector struct {
+	cache *httpCache
+}
+
+func (i *remoteInspector) List() ([]Entry, error) {
+	return nil, ErrNotSupported
+}
+
+func (i *remoteInspector) Show(hash string) (*Entry, error) {
+	if err := validateHash(hash); err != nil {
+		return nil, err
+	}
+	artifact, duration, _, err := i.cache.fetchArtifact(hash)
+	if err != nil {
+		return nil, err
+	} else if artifact == nil {
+		return nil, ErrEntryNotFound
+	}
+	defer func() { _ = artifact.Close() }()
+	gzr, err := gzip.NewReader(artifact)
+	if err != nil {
+		return nil, corruptionError(err)
+	}
+	defer func() { _ = gzr.Close() }()
+	entry := &Entry{
+		Hash:     hash,
+		Duration: duration,
+	}
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, corruptionError(err)
+		}
+		file := EntryFile{
+			Path:   strings.TrimSuffix(hdr.Name, "/"),
+			Mode:   hdr.FileInfo().Mode(),
+			Digest: hdr.PAXRecords[_digestPAXRecord],
+		}
+		switch hdr.Typeflag {
+		case tar.TypeSymlink, tar.TypeLink:
+			file.LinkTarget = hdr.Linkname
+		case tar.TypeReg:
+			file.Size = hdr.Size
+			entry.Size += hdr.Size
+			if isLogFile(file.Path) {
+				contents, err := ioutil.ReadAll(tr)
+				if err != nil {
+					return nil, corruptionError(err)
+				}
+				if entry.Logs == nil {
+					entry.Logs = make(map[string]string)
+				}
+				entry.Logs[file.Path] = string(contents)
+			}
+		}
+		entry.Files = append(entry.Files, file)
+	}
+	sort.Slice(entry.Files, func(a, b int) bool {
+		return entry.Files[a].Path < entry.Files[b].Path
+	})
+	return entry, nil
+}
+
+func (i *remoteInspector) Extract(hash string, dir fs.AbsolutePath) error {
+	if err := validateHash(hash); err != nil {
+		return err
+	}
+	artifact, _, _, err := i.cache.fetchArtifact(hash)
+	if err != nil {
+		return err
+	} else if artifact == nil {
+		return ErrEntryNotFound
+	}
+	defer func() { _ = artifact.Close() }()
+	if err := dir.MkdirAll(); err != nil {
+		return err
+	}
+	if _, err := restoreTar(dir, artifact); err != nil {
+		return fmt.Errorf("failed to extract %v: %w", hash, err)
+	}
+	return nil
+}
+
+func (i *remoteInspector) Remove(hash string) error {
+	return ErrNotSupported
+}
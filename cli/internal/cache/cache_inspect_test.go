@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+// artifactResp is a client that serves artifacts from memory
+type artifactResp struct {
+	artifacts map[string][]byte
+	// tags are the x-artifact-tag headers sent with artifacts, if any
+	tags map[string]string
+}
+
+func (ar *artifactResp) PutArtifact(hash string, body []byte, duration int, tag string) error {
+	ar.artifacts[hash] = body
+	return nil
+}
+
+func (ar *artifactResp) FetchArtifact(hash string) (*http.Response, error) {
+	body, ok := ar.artifacts[hash]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+	}
+	header := http.Header{"X-Artifact-Duration": []string{"1200"}}
+	if tag, ok := ar.tags[hash]; ok {
+		header.Set("X-Artifact-Tag", tag)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}, nil
+}
+
+func (ar *artifactResp) ArtifactExists(hash string) (*http.Response, error) {
+	resp, err := ar.FetchArtifact(hash)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(&bytes.Buffer{})
+	return resp, nil
+}
+
+func TestLocalInspector(t *testing.T) {
+	cacheDir := t.TempDir()
+	repoRoot := fs.AbsolutePathFromUpstream(t.TempDir())
+	files := map[string]string{
+		"my-pkg/dist/index.js":          "index",
+		"my-pkg/.turbo/turbo-build.log": "build output",
+	}
+	var paths []string
+	for name, contents := range files {
+		file := repoRoot.Join(filepath.FromSlash(name))
+		assert.NilError(t, file.EnsureDir(), "EnsureDir")
+		assert.NilError(t, file.WriteFile([]byte(contents), 0644), "WriteFile")
+		paths = append(paths, filepath.FromSlash(name))
+	}
+	cache := &fsCache{
+		cacheDirectory: cacheDir,
+		recorder:       &dummyRecorder{},
+		repoRoot:       repoRoot,
+	}
+	assert.NilError(t, cache.Put("unused", "thehash", 1200, paths), "Put")
+	assert.NilError(t, cache.Put("unused", "otherhash", 0, paths[:1]), "Put")
+
+	inspector := NewLocalInspector(fs.AbsolutePathFromUpstream(cacheDir))
+	entries, err := inspector.List()
+	assert.NilError(t, err, "List")
+	assert.Equal(t, len(entries), 2)
+
+	entry, err := inspector.Show("thehash")
+	assert.NilError(t, err, "Show")
+	assert.Equal(t, entry.Duration, 1200)
+	assert.Equal(t, entry.Size, int64(len("index")+len("build output")))
+	digests := make(map[string]string)
+	for _, file := range entry.Files {
+		if file.Mode.IsRegular() {
+			digests[file.Path] = file.Digest
+		}
+	}
+	indexDigest, err := fs.GitLikeHashFile(repoRoot.Join("my-pkg", "dist", "index.js").ToString())
+	assert.NilError(t, err, "GitLikeHashFile")
+	assert.Equal(t, digests["my-pkg/dist/index.js"], indexDigest)
+	assert.DeepEqual(t, entry.Logs, map[string]string{"my-pkg/.turbo/turbo-build.log": "build output"})
+
+	// Extracting doesn't touch the repo
+	assert.NilError(t, repoRoot.Join("my-pkg", "dist", "index.js").WriteFile([]byte("changed"), 0644), "WriteFile")
+	to := fs.AbsolutePathFromUpstream(t.TempDir()).Join("extracted")
+	assert.NilError(t, inspector.Extract("thehash", to), "Extract")
+	contents, err := to.Join("my-pkg", "dist", "index.js").ReadFile()
+	assert.NilError(t, err, "ReadFile")
+	assert.Equal(t, string(contents), "index")
+	contents, err = repoRoot.Join("my-pkg", "dist", "index.js").ReadFile()
+	assert.NilError(t, err, "ReadFile")
+	assert.Equal(t, string(contents), "changed")
+
+	assert.NilError(t, inspector.Remove("thehash"), "Remove")
+	_, err = inspector.Show("thehash")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	assert.ErrorIs(t, inspector.Remove("thehash"), ErrEntryNotFound)
+	entries, err = inspector.List()
+	assert.NilError(t, err, "List")
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Hash, "otherhash")
+	assertNoTempEntries(t, cacheDir)
+}
+
+func TestLocalInspectorRejectsInvalidHashes(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(root, "node_modules", ".cache", "turbo")
+	assert.NilError(t, os.MkdirAll(cacheDir, 0755), "MkdirAll")
+	// An entry-like directory and metadata outside of the cache
+	outside := filepath.Join(root, "x")
+	assert.NilError(t, os.MkdirAll(outside, 0755), "MkdirAll")
+	assert.NilError(t, WriteCacheMetaFile(outside+_metaFileSuffix, &CacheMetadata{Hash: "x"}), "WriteCacheMetaFile")
+
+	inspector := NewLocalInspector(fs.AbsolutePathFromUpstream(cacheDir))
+	for _, hash := range []string{"../../../x", "..", "", "a/b", "abc\\def"} {
+		assert.ErrorContains(t, inspector.Remove(hash), "invalid hash")
+		_, err := inspector.Show(hash)
+		assert.ErrorContains(t, err, "invalid hash")
+		assert.ErrorContains(t, inspector.Extract(hash, fs.AbsolutePathFromUpstream(t.TempDir())), "invalid hash")
+	}
+	assert.Assert(t, fs.PathExists(outside))
+	assert.Assert(t, fs.PathExists(outside+_metaFileSuffix))
+}
+
+func TestRemoteInspector(t *testing.T) {
+	client := &artifactResp{artifacts: map[string][]byte{
+		"thehash": makeTar(t,
+			withDigest(file("my-pkg/dist/index.js", "index"), "index"),
+			file("my-pkg/.turbo/turbo-build.log", "build output"),
+		),
+	}}
+	inspector := NewRemoteInspector(Opts{}, "team_id", client)
+
+	entry, err := inspector.Show("thehash")
+	assert.NilError(t, err, "Show")
+	assert.Equal(t, entry.Duration, 1200)
+	assert.Equal(t, len(entry.Files), 2)
+	assert.Equal(t, entry.Files[1].Path, "my-pkg/dist/index.js")
+	assert.Assert(t, entry.Files[1].Digest != "")
+	assert.DeepEqual(t, entry.Logs, map[string]string{"my-pkg/.turbo/turbo-build.log": "build output"})
+
+	to := fs.AbsolutePathFromUpstream(t.TempDir())
+	assert.NilError(t, inspector.Extract("thehash", to), "Extract")
+	contents, err := to.Join("my-pkg", "dist", "index.js").ReadFile()
+	assert.NilError(t, err, "ReadFile")
+	assert.Equal(t, string(contents), "index")
+
+	_, err = inspector.Show("missinghash")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	_, err = inspector.List()
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.ErrorIs(t, inspector.Remove("thehash"), ErrNotSupported)
+}
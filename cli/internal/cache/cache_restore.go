@@ -0,0 +1,452 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/globby"
+)
+
+// Limits on the artifacts that are restored from a cache, so that a corrupt or hostile
+// artifact can't exhaust the disk
+const (
+	// _maxRestoredEntries is the most files, directories and links an artifact can contain
+	_maxRestoredEntries = 1 << 20
+	// _maxRestoredFileSize is the size of the largest file an artifact can contain
+	_maxRestoredFileSize = 8 << 30
+)
+
+// errCorruptArtifact is returned when an artifact is truncated, or its files don't match
+// their digests
+var errCorruptArtifact = errors.New("artifact is corrupt")
+
+// _digestPAXRecord is the PAX record that holds the git-like SHA1 of a file in an artifact tar
+const _digestPAXRecord = "TURBO.sha1"
+
+// expectedDigest is the git-like SHA1 that a restored file's contents must have
+type expectedDigest struct {
+	size int64
+	sha1 string
+}
+
+// restoreTar returns posix-style repo-relative paths of the files it
+// restored. In the future, these should likely be repo-relative system paths
+// so that they are suitable for being fed into cache.Put for other caches.
+// For now, I think this is working because windows also accepts /-delimited paths.
+func restoreTar(root fs.AbsolutePath, reader io.Reader) ([]string, error) {
+	return newRestorer(root).restoreTar(reader)
+}
+
+// restoreDirectory restores an artifact that is stored as a directory tree at dir. Symlinks to
+// directories are restored as empty directories, and broken symlinks are skipped.
+func restoreDirectory(root fs.AbsolutePath, dir string) error {
+	r := newRestorer(root)
+	err := fs.WalkMode(dir, func(name string, isDir bool, mode os.FileMode) error {
+		relativePath, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		if relativePath == "." {
+			return nil
+		}
+		entryName := filepath.ToSlash(relativePath)
+		switch {
+		case isDir:
+			return r.restoreDir(entryName)
+		case mode&os.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(name)
+			if err != nil {
+				return err
+			}
+			return r.restoreSymlink(entryName, filepath.ToSlash(linkTarget))
+		case mode.IsRegular():
+			info, err := os.Lstat(name)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			return r.restoreFile(entryName, info.Mode(), f, nil)
+		default:
+			return fmt.Errorf("cannot restore %v: unsupported file type %v", entryName, mode.Type())
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return r.restoreMissingLinks()
+}
+
+// CleanOutputs removes the files matching the given repo-relative output globs, so that
+// restoring or rebuilding a task's outputs doesn't leave files from an earlier build behind.
+// Files inside symlinked directories are left alone, since they may be outside of the repo.
+func CleanOutputs(repoRoot fs.AbsolutePath, outputGlobs []string) error {
+	if len(outputGlobs) == 0 {
+		return nil
+	}
+	files, err := globby.GlobFiles(repoRoot.ToStringDuringMigration(), outputGlobs, _emptyIgnore)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		relativePath, err := repoRoot.RelativePathString(file)
+		if err != nil {
+			return err
+		}
+		if inLink, err := isInsideSymlink(repoRoot, relativePath); err != nil {
+			return err
+		} else if inLink {
+			continue
+		}
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+var _emptyIgnore []string
+
+// isInsideSymlink reports whether any of the parent directories of relativePath, below root,
+// is a symlink
+func isInsideSymlink(root fs.AbsolutePath, relativePath string) (bool, error) {
+	if isOutside(relativePath) {
+		return true, nil
+	}
+	for dir := filepath.Dir(relativePath); dir != "."; dir = filepath.Dir(dir) {
+		info, err := root.Join(dir).Lstat()
+		if err != nil {
+			return false, err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// restorer writes the entries of a cached artifact into the repo. Every entry has to be inside
+// the repo root, links can only point at paths inside the repo root, and entries are never
+// written through an existing symlink, so that an artifact can't modify files outside the repo.
+type restorer struct {
+	root        fs.AbsolutePath
+	maxEntries  int
+	maxFileSize int64
+	entries     int
+	// dirs are the directories, relative to root, that are known not to be symlinks
+	dirs map[string]bool
+	// missingLinks are symlinks whose targets didn't exist when they were read. They're
+	// restored last, since on Windows a link's target determines what kind of link it is.
+	missingLinks []missingLink
+}
+
+type missingLink struct {
+	name       string
+	linkTarget string
+}
+
+func newRestorer(root fs.AbsolutePath) *restorer {
+	return &restorer{
+		root:        root,
+		maxEntries:  _maxRestoredEntries,
+		maxFileSize: _maxRestoredFileSize,
+		dirs:        map[string]bool{".": true},
+	}
+}
+
+func (r *restorer) restoreTar(reader io.Reader) ([]string, error) {
+	files := []string{}
+	gzr, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gzr.Close() }()
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			if err := r.restoreMissingLinks(); err != nil {
+				return nil, err
+			}
+			return files, nil
+		} else if err != nil {
+			return nil, corruptionError(err)
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			// Only carries metadata, which we don't use
+			continue
+		}
+		// hdr.Name is always a posix-style path
+		// TODO: files should eventually be repo-relative system paths
+		files = append(files, hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = r.restoreDir(hdr.Name)
+		case tar.TypeReg:
+			if hdr.Size > r.maxFileSize {
+				err = fmt.Errorf("cannot restore %v: file is larger than %v bytes", hdr.Name, r.maxFileSize)
+			} else {
+				var digest *expectedDigest
+				if sha1, ok := hdr.PAXRecords[_digestPAXRecord]; ok {
+					digest = &expectedDigest{size: hdr.Size, sha1: sha1}
+				}
+				err = r.restoreFile(hdr.Name, os.FileMode(hdr.Mode), tr, digest)
+			}
+		case tar.TypeSymlink:
+			err = r.restoreSymlink(hdr.Name, hdr.Linkname)
+		case tar.TypeLink:
+			err = r.restoreHardlink(hdr.Name, hdr.Linkname)
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			err = fmt.Errorf("cannot restore %v: devices and named pipes are not supported", hdr.Name)
+		default:
+			err = fmt.Errorf("cannot restore %v: unsupported file type %q", hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return nil, corruptionError(err)
+		}
+	}
+}
+
+// corruptionError marks errors from reading a truncated or damaged artifact as errCorruptArtifact
+func corruptionError(err error) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, gzip.ErrChecksum) || errors.Is(err, gzip.ErrHeader) || errors.Is(err, tar.ErrHeader) {
+		return fmt.Errorf("%w: %v", errCorruptArtifact, err)
+	}
+	return err
+}
+
+// entryPath counts an entry of the artifact, and returns its path relative to root and its
+// absolute path
+func (r *restorer) entryPath(name string) (string, fs.AbsolutePath, error) {
+	r.entries++
+	if r.entries > r.maxEntries {
+		return "", "", fmt.Errorf("cannot restore artifact: it has more than %v entries", r.maxEntries)
+	}
+	return r.resolve(name)
+}
+
+// resolve returns the path relative to root, and the absolute path, of a posix-style path in
+// the artifact. The path has to be inside root.
+func (r *restorer) resolve(name string) (string, fs.AbsolutePath, error) {
+	systemName := filepath.FromSlash(name)
+	if name == "" || strings.ContainsRune(name, 0) {
+		return "", "", fmt.Errorf("cannot restore %q: invalid path", name)
+	}
+	if path.IsAbs(name) || isAbsolute(systemName) {
+		return "", "", fmt.Errorf("cannot restore %v: it is an absolute path", name)
+	}
+	relativePath := filepath.Clean(systemName)
+	if relativePath == "." || isOutside(relativePath) {
+		return "", "", fmt.Errorf("cannot restore %v: it is outside of the repo", name)
+	}
+	filename := r.root.Join(relativePath)
+	if isChild, err := r.root.ContainsPath(filename); err != nil {
+		return "", "", err
+	} else if !isChild {
+		return "", "", fmt.Errorf("cannot restore %v: it is outside of the repo", name)
+	}
+	return relativePath, filename, nil
+}
+
+func (r *restorer) restoreDir(name string) error {
+	relativePath, _, err := r.entryPath(name)
+	if err != nil {
+		return err
+	}
+	return r.mkdirs(relativePath)
+}
+
+// restoreFile writes a regular file. If digest is given, the contents have to match it.
+func (r *restorer) restoreFile(name string, mode os.FileMode, contents io.Reader, digest *expectedDigest) error {
+	relativePath, filename, err := r.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if err := r.prepare(relativePath, filename); err != nil {
+		return err
+	}
+	// O_EXCL makes sure that we create a new file, rather than writing to whatever a
+	// symlink that was created after prepare points at
+	f, err := filename.OpenFile(os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var hasher hash.Hash
+	if digest != nil {
+		hasher = fs.NewGitLikeHash(digest.size)
+		w = io.MultiWriter(f, hasher)
+	}
+	n, err := io.Copy(w, io.LimitReader(contents, r.maxFileSize+1))
+	if err == nil && n > r.maxFileSize {
+		err = fmt.Errorf("cannot restore %v: file is larger than %v bytes", name, r.maxFileSize)
+	}
+	if err == nil && digest != nil && (n != digest.size || hex.EncodeToString(hasher.Sum(nil)) != digest.sha1) {
+		err = fmt.Errorf("%w: %v doesn't match its digest", errCorruptArtifact, name)
+	}
+	if err == nil {
+		err = f.Chmod(mode.Perm())
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = filename.Remove()
+		return err
+	}
+	return nil
+}
+
+func (r *restorer) restoreSymlink(name string, linkTarget string) error {
+	relativePath, _, err := r.entryPath(name)
+	if err != nil {
+		return err
+	}
+	return r.symlink(relativePath, linkTarget, false)
+}
+
+// symlink creates the link at relativePath. Unless allowNonexistentTargets is set, links
+// whose targets don't exist yet are put aside to be created once everything else is restored.
+func (r *restorer) symlink(relativePath string, linkTarget string, allowNonexistentTargets bool) error {
+	// The link is written with the cleaned target, which only has .. at the start, so the link
+	// resolves through its parent directories, which aren't symlinks, and then down
+	// to targetPath
+	cleanTarget, targetPath, err := r.confineLinkTarget(relativePath, linkTarget)
+	if err != nil {
+		return err
+	}
+	if !allowNonexistentTargets {
+		if _, err := r.root.Join(targetPath).Lstat(); errors.Is(err, os.ErrNotExist) {
+			r.missingLinks = append(r.missingLinks, missingLink{name: relativePath, linkTarget: linkTarget})
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	filename := r.root.Join(relativePath)
+	if err := r.prepare(relativePath, filename); err != nil {
+		return err
+	}
+	return filename.Symlink(cleanTarget)
+}
+
+// confineLinkTarget checks that the target of the symlink at relativePath is inside
+// root. It returns the cleaned target, and the path it points at relative to root.
+func (r *restorer) confineLinkTarget(relativePath string, linkTarget string) (string, string, error) {
+	name := filepath.ToSlash(relativePath)
+	target := filepath.FromSlash(linkTarget)
+	if linkTarget == "" || strings.ContainsRune(linkTarget, 0) {
+		return "", "", fmt.Errorf("cannot restore %v: invalid link target %q", name, linkTarget)
+	}
+	if path.IsAbs(linkTarget) || isAbsolute(target) {
+		return "", "", fmt.Errorf("cannot restore %v: link target %v is an absolute path", name, linkTarget)
+	}
+	target = filepath.Clean(target)
+	targetPath := filepath.Join(filepath.Dir(relativePath), target)
+	if isOutside(targetPath) {
+		return "", "", fmt.Errorf("cannot restore %v: link target %v is outside of the repo", name, linkTarget)
+	}
+	return target, targetPath, nil
+}
+
+func (r *restorer) restoreMissingLinks() error {
+	for _, link := range r.missingLinks {
+		if err := r.symlink(link.name, link.linkTarget, true); err != nil {
+			return err
+		}
+	}
+	r.missingLinks = nil
+	return nil
+}
+
+// restoreHardlink links name to linkTarget, which is the path in the artifact of a regular file
+// that has already been restored
+func (r *restorer) restoreHardlink(name string, linkTarget string) error {
+	relativePath, filename, err := r.entryPath(name)
+	if err != nil {
+		return err
+	}
+	targetPath, target, err := r.resolve(linkTarget)
+	if err != nil {
+		return fmt.Errorf("cannot restore %v: invalid link target: %w", name, err)
+	}
+	if err := r.mkdirs(filepath.Dir(targetPath)); err != nil {
+		return err
+	}
+	if info, err := target.Lstat(); err != nil {
+		return fmt.Errorf("cannot restore %v: %w", name, err)
+	} else if !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot restore %v: link target %v is not a regular file", name, linkTarget)
+	}
+	if err := r.prepare(relativePath, filename); err != nil {
+		return err
+	}
+	return os.Link(target.ToString(), filename.ToString())
+}
+
+// prepare creates the parent directories of the entry at relativePath, and removes whatever is
+// currently at that path so that it can be replaced
+func (r *restorer) prepare(relativePath string, filename fs.AbsolutePath) error {
+	if err := r.mkdirs(filepath.Dir(relativePath)); err != nil {
+		return err
+	}
+	info, err := filename.Lstat()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if info.IsDir() {
+		return fmt.Errorf("cannot restore %v: a directory already exists there", filepath.ToSlash(relativePath))
+	}
+	return filename.Remove()
+}
+
+// mkdirs creates the directory at relativePath and its parents. It refuses to create anything
+// inside a symlink, since the link could point anywhere.
+func (r *restorer) mkdirs(relativePath string) error {
+	if r.dirs[relativePath] {
+		return nil
+	}
+	if err := r.mkdirs(filepath.Dir(relativePath)); err != nil {
+		return err
+	}
+	dir := r.root.Join(relativePath)
+	info, err := dir.Lstat()
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.Mkdir(dir.ToString(), fs.DirPermissions); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("cannot restore into %v: it is a symlink", filepath.ToSlash(relativePath))
+	} else if !info.IsDir() {
+		return fmt.Errorf("cannot restore into %v: it is not a directory", filepath.ToSlash(relativePath))
+	}
+	r.dirs[relativePath] = true
+	return nil
+}
+
+// isAbsolute reports whether a system path is absolute, or rooted at the current drive on Windows
+func isAbsolute(systemPath string) bool {
+	return filepath.IsAbs(systemPath) || filepath.VolumeName(systemPath) != "" || strings.HasPrefix(systemPath, string(filepath.Separator))
+}
+
+// isOutside reports whether a cleaned relative path points outside of the directory it is
+// relative to
+func isOutside(relativePath string) bool {
+	return relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,347 @@
+package cache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"gotest.tools/v3/assert"
+)
+
+type tarEntry struct {
+	hdr      *tar.Header
+	contents string
+}
+
+func file(name string, contents string) tarEntry {
+	return tarEntry{
+		hdr: &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Typeflag: tar.TypeReg,
+			Size:     int64(len(contents)),
+		},
+		contents: contents,
+	}
+}
+
+func link(typeflag byte, name string, linkTarget string) tarEntry {
+	return tarEntry{
+		hdr: &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Typeflag: typeflag,
+			Linkname: linkTarget,
+		},
+	}
+}
+
+func makeTar(t testing.TB, entries ...tarEntry) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	for _, entry := range entries {
+		assert.NilError(t, tw.WriteHeader(entry.hdr), "WriteHeader")
+		_, err := tw.Write([]byte(entry.contents))
+		assert.NilError(t, err, "Write")
+	}
+	assert.NilError(t, tw.Close(), "Close")
+	assert.NilError(t, gzw.Close(), "Close")
+	return buf.Bytes()
+}
+
+// setupRestoreDirs returns a repo root inside a directory that also contains a file that
+// artifacts must not be able to modify
+func setupRestoreDirs(t testing.TB) (fs.AbsolutePath, fs.AbsolutePath) {
+	t.Helper()
+	parent := fs.AbsolutePathFromUpstream(t.TempDir())
+	root := parent.Join("repo")
+	assert.NilError(t, root.MkdirAll(), "MkdirAll")
+	outside := parent.Join("outside")
+	assert.NilError(t, outside.MkdirAll(), "MkdirAll")
+	assert.NilError(t, outside.Join("secret").WriteFile([]byte("secret"), 0644), "WriteFile")
+	return root, outside
+}
+
+func assertOutsideUnchanged(t testing.TB, outside fs.AbsolutePath) {
+	t.Helper()
+	entries, err := os.ReadDir(outside.ToString())
+	assert.NilError(t, err, "ReadDir")
+	assert.Equal(t, len(entries), 1, "expected nothing to be written outside of the repo")
+	contents, err := outside.Join("secret").ReadFile()
+	assert.NilError(t, err, "ReadFile")
+	assert.Equal(t, string(contents), "secret")
+}
+
+func TestRestoreTarRejectsHostileEntries(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entries []tarEntry
+		// setup runs before the artifact is restored
+		setup   func(t *testing.T, root fs.AbsolutePath, outside fs.AbsolutePath)
+		wantErr string
+	}{
+		{
+			name:    "absolute file",
+			entries: []tarEntry{file("/etc/passwd", "x")},
+			wantErr: "is an absolute path",
+		},
+		{
+			name:    "file outside of the repo",
+			entries: []tarEntry{file("my-pkg/../../outside/secret", "x")},
+			wantErr: "is outside of the repo",
+		},
+		{
+			name:    "absolute link target",
+			entries: []tarEntry{link(tar.TypeSymlink, "my-pkg/dist", "/etc")},
+			wantErr: "link target /etc is an absolute path",
+		},
+		{
+			name:    "link target outside of the repo",
+			entries: []tarEntry{link(tar.TypeSymlink, "my-pkg/dist", "../../outside")},
+			wantErr: "link target ../../outside is outside of the repo",
+		},
+		{
+			name: "file through a restored link",
+			entries: []tarEntry{
+				file("other/keep", "x"),
+				link(tar.TypeSymlink, "my-pkg/dist", "../other"),
+				file("my-pkg/dist/keep", "overwritten"),
+			},
+			wantErr: "cannot restore into my-pkg/dist: it is a symlink",
+		},
+		{
+			name:    "file through an existing link",
+			entries: []tarEntry{file("my-pkg/dist/secret", "overwritten")},
+			setup: func(t *testing.T, root fs.AbsolutePath, outside fs.AbsolutePath) {
+				assert.NilError(t, root.Join("my-pkg").MkdirAll(), "MkdirAll")
+				assert.NilError(t, root.Join("my-pkg", "dist").Symlink(outside.ToString()), "Symlink")
+			},
+			wantErr: "cannot restore into my-pkg/dist: it is a symlink",
+		},
+		{
+			name:    "hardlink outside of the repo",
+			entries: []tarEntry{link(tar.TypeLink, "my-pkg/secret", "../outside/secret")},
+			wantErr: "is outside of the repo",
+		},
+		{
+			name: "hardlink through a link",
+			entries: []tarEntry{
+				link(tar.TypeSymlink, "my-pkg/dist", "../other"),
+				link(tar.TypeLink, "my-pkg/secret", "my-pkg/dist/secret"),
+			},
+			setup: func(t *testing.T, root fs.AbsolutePath, outside fs.AbsolutePath) {
+				assert.NilError(t, root.Join("other").Symlink(outside.ToString()), "Symlink")
+			},
+			wantErr: "cannot restore into my-pkg/dist: it is a symlink",
+		},
+		{
+			name:    "character device",
+			entries: []tarEntry{{hdr: &tar.Header{Name: "my-pkg/tty", Typeflag: tar.TypeChar, Mode: 0644}}},
+			wantErr: "devices and named pipes are not supported",
+		},
+		{
+			name:    "named pipe",
+			entries: []tarEntry{{hdr: &tar.Header{Name: "my-pkg/pipe", Typeflag: tar.TypeFifo, Mode: 0644}}},
+			wantErr: "devices and named pipes are not supported",
+		},
+		{
+			name:    "unknown type",
+			entries: []tarEntry{{hdr: &tar.Header{Name: "my-pkg/unknown", Typeflag: 'Z', Mode: 0644}}},
+			wantErr: "unsupported file type",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root, outside := setupRestoreDirs(t)
+			if tc.setup != nil {
+				tc.setup(t, root, outside)
+			}
+			_, err := restoreTar(root, bytes.NewReader(makeTar(t, tc.entries...)))
+			assert.ErrorContains(t, err, tc.wantErr)
+			assertOutsideUnchanged(t, outside)
+		})
+	}
+}
+
+func TestRestoreTarReplacesExistingLinks(t *testing.T) {
+	root, outside := setupRestoreDirs(t)
+	assert.NilError(t, root.Join("my-pkg").MkdirAll(), "MkdirAll")
+	assert.NilError(t, root.Join("my-pkg", "secret").Symlink(outside.Join("secret").ToString()), "Symlink")
+
+	artifact := makeTar(t, file("my-pkg/secret", "restored"))
+	_, err := restoreTar(root, bytes.NewReader(artifact))
+	assert.NilError(t, err, "restoreTar")
+
+	info, err := root.Join("my-pkg", "secret").Lstat()
+	assert.NilError(t, err, "Lstat")
+	assert.Assert(t, info.Mode().IsRegular(), "expected the link to be replaced by a file")
+	contents, err := root.Join("my-pkg", "secret").ReadFile()
+	assert.NilError(t, err, "ReadFile")
+	assert.Equal(t, string(contents), "restored")
+	assertOutsideUnchanged(t, outside)
+}
+
+func TestRestoreTarLinks(t *testing.T) {
+	root, _ := setupRestoreDirs(t)
+	artifact := makeTar(t,
+		file("my-pkg/dist/index.js", "index"),
+		link(tar.TypeLink, "my-pkg/dist/main.js", "my-pkg/dist/index.js"),
+		link(tar.TypeSymlink, "my-pkg/current", "./dist/../dist"),
+		link(tar.TypeSymlink, "my-pkg/up", ".."),
+		// Without cleaning, this would resolve through my-pkg/up to outside of the repo
+		link(tar.TypeSymlink, "my-pkg/escape", "up/../../outside"),
+	)
+	_, err := restoreTar(root, bytes.NewReader(artifact))
+	assert.NilError(t, err, "restoreTar")
+
+	contents, err := root.Join("my-pkg", "dist", "main.js").ReadFile()
+	assert.NilError(t, err, "ReadFile")
+	assert.Equal(t, string(contents), "index")
+
+	// Link targets are written cleaned
+	linkTarget, err := root.Join("my-pkg", "current").Readlink()
+	assert.NilError(t, err, "Readlink")
+	assert.Equal(t, linkTarget, "dist")
+	linkTarget, err = root.Join("my-pkg", "up").Readlink()
+	assert.NilError(t, err, "Readlink")
+	assert.Equal(t, linkTarget, "..")
+	linkTarget, err = root.Join("my-pkg", "escape").Readlink()
+	assert.NilError(t, err, "Readlink")
+	assert.Equal(t, linkTarget, filepath.FromSlash("../outside"))
+}
+
+func TestRestoreTarLimits(t *testing.T) {
+	root, _ := setupRestoreDirs(t)
+	r := newRestorer(root)
+	r.maxEntries = 2
+	_, err := r.restoreTar(bytes.NewReader(makeTar(t, file("a", "a"), file("b", "b"), file("c", "c"))))
+	assert.ErrorContains(t, err, "it has more than 2 entries")
+
+	r = newRestorer(root)
+	r.maxFileSize = 4
+	_, err = r.restoreTar(bytes.NewReader(makeTar(t, file("large", "12345"))))
+	assert.ErrorContains(t, err, "file is larger than 4 bytes")
+	_, err = root.Join("large").Lstat()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Files read from disk don't have a size up front
+	r = newRestorer(root)
+	r.maxFileSize = 4
+	err = r.restoreFile("large", 0644, strings.NewReader("12345"), nil)
+	assert.ErrorContains(t, err, "file is larger than 4 bytes")
+	_, err = root.Join("large").Lstat()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// withDigest records the digest of contents in the entry, the way artifacts are stored
+func withDigest(entry tarEntry, contents string) tarEntry {
+	hasher := fs.NewGitLikeHash(int64(len(contents)))
+	_, _ = hasher.Write([]byte(contents))
+	entry.hdr.PAXRecords = map[string]string{_digestPAXRecord: hex.EncodeToString(hasher.Sum(nil))}
+	return entry
+}
+
+func TestRestoreTarVerifiesDigests(t *testing.T) {
+	root, _ := setupRestoreDirs(t)
+	artifact := makeTar(t, withDigest(file("my-pkg/dist/index.js", "index"), "index"))
+	_, err := restoreTar(root, bytes.NewReader(artifact))
+	assert.NilError(t, err, "restoreTar")
+	contents, err := root.Join("my-pkg", "dist", "index.js").ReadFile()
+	assert.NilError(t, err, "ReadFile")
+	assert.Equal(t, string(contents), "index")
+
+	// A file that was damaged after it was stored
+	artifact = makeTar(t, withDigest(file("my-pkg/dist/damaged.js", "indeX"), "index"))
+	_, err = restoreTar(root, bytes.NewReader(artifact))
+	assert.ErrorIs(t, err, errCorruptArtifact)
+	_, err = root.Join("my-pkg", "dist", "damaged.js").Lstat()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// A download that was cut short
+	artifact = makeTar(t, file("my-pkg/dist/truncated.js", strings.Repeat("truncated", 1024)))
+	_, err = restoreTar(root, bytes.NewReader(artifact[:len(artifact)/2]))
+	assert.ErrorIs(t, err, errCorruptArtifact)
+}
+
+func TestRestoreDirectoryRejectsHostileLinks(t *testing.T) {
+	root, outside := setupRestoreDirs(t)
+	cacheDir := t.TempDir()
+	assert.NilError(t, os.MkdirAll(filepath.Join(cacheDir, "my-pkg"), 0755), "MkdirAll")
+	assert.NilError(t, os.Symlink(outside.Join("secret").ToString(), filepath.Join(cacheDir, "my-pkg", "secret")), "Symlink")
+
+	err := restoreDirectory(root, cacheDir)
+	assert.ErrorContains(t, err, "is an absolute path")
+	assertOutsideUnchanged(t, outside)
+}
+
+func TestCleanOutputs(t *testing.T) {
+	root, outside := setupRestoreDirs(t)
+	for _, name := range []string{"my-pkg/dist/stale.js", "my-pkg/dist/chunks/stale.js", "my-pkg/src/index.ts"} {
+		filename := root.Join(filepath.FromSlash(name))
+		assert.NilError(t, filename.EnsureDir(), "EnsureDir")
+		assert.NilError(t, filename.WriteFile([]byte(name), 0644), "WriteFile")
+	}
+	assert.NilError(t, root.Join("my-pkg", "dist", "linked").Symlink(outside.ToString()), "Symlink")
+
+	err := CleanOutputs(root, []string{filepath.Join("my-pkg", "dist", "**")})
+	assert.NilError(t, err, "CleanOutputs")
+
+	for _, name := range []string{"my-pkg/dist/stale.js", "my-pkg/dist/chunks/stale.js"} {
+		_, err := root.Join(filepath.FromSlash(name)).Lstat()
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+	_, err = root.Join("my-pkg", "src", "index.ts").Lstat()
+	assert.NilError(t, err, "expected files that aren't outputs to be kept")
+	// The files in the linked directory aren't part of the repo
+	assertOutsideUnchanged(t, outside)
+}
+
+// FuzzRestoreTar checks that no artifact can write outside of the repo root or leave a link
+// that points outside of it. The corpus in testdata/fuzz/FuzzRestoreTar holds hostile artifacts.
+func FuzzRestoreTar(f *testing.F) {
+	f.Add(makeTar(f,
+		file("my-pkg/dist/index.js", "index"),
+		link(tar.TypeSymlink, "my-pkg/current", "dist"),
+	))
+	f.Add(makeTar(f,
+		link(tar.TypeSymlink, "my-pkg/dist", ".."),
+		file("my-pkg/dist/my-pkg/file", "x"),
+	))
+	f.Fuzz(func(t *testing.T, artifact []byte) {
+		root, outside := setupRestoreDirs(t)
+		_, _ = restoreTar(root, bytes.NewReader(artifact))
+		assertOutsideUnchanged(t, outside)
+
+		entries, err := os.ReadDir(root.Dir().ToString())
+		assert.NilError(t, err, "ReadDir")
+		assert.Equal(t, len(entries), 2, "expected nothing to be written next to the repo")
+
+		resolvedRoot, err := filepath.EvalSymlinks(root.ToString())
+		assert.NilError(t, err, "EvalSymlinks")
+		err = fs.WalkMode(root.ToString(), func(name string, isDir bool, mode os.FileMode) error {
+			if mode&os.ModeSymlink == 0 {
+				return nil
+			}
+			resolved, err := filepath.EvalSymlinks(name)
+			if err != nil {
+				// Broken links and loops don't point anywhere
+				return nil
+			}
+			relativePath, err := filepath.Rel(resolvedRoot, resolved)
+			assert.NilError(t, err, "Rel")
+			if isOutside(relativePath) {
+				t.Errorf("%v links to %v, outside of the repo", name, resolved)
+			}
+			return nil
+		})
+		assert.NilError(t, err, "WalkMode")
+	})
+}
@@ -4,6 +4,8 @@
 package cache
 
 import (
+	"bytes"
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -12,15 +14,76 @@ import (
 	"fmt"
 	"hash"
 	"os"
+	"strings"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
 )
 
+// _ed25519TagPrefix marks tags that are Ed25519 signatures. HMAC tags are plain base64, which
+// never contains a colon, so the two can't be confused.
+const _ed25519TagPrefix = "ed25519:"
+
+const _privateKeyEnv = "TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY"
+
 type ArtifactSignatureAuthentication struct {
 	teamId  string
 	enabled bool
+	// publicKey is the base64 encoded Ed25519 key from remoteCache.publicKey. When it's set,
+	// artifacts are signed with the matching private key instead of with an HMAC.
+	publicKey string
+}
+
+// newArtifactSignatureAuthentication signs and verifies artifacts as configured in the
+// remoteCache options of turbo.json
+func newArtifactSignatureAuthentication(opts Opts, teamID string) *ArtifactSignatureAuthentication {
+	return &ArtifactSignatureAuthentication{
+		// TODO(Gaspar): this should use RemoteCacheOptions.TeamId once we start
+		// enforcing team restrictions for repositories.
+		teamId:    teamID,
+		enabled:   opts.RemoteCacheOpts.Signature,
+		publicKey: opts.RemoteCacheOpts.PublicKey,
+	}
 }
 
 func (asa *ArtifactSignatureAuthentication) isEnabled() bool {
-	return asa.enabled
+	return asa.enabled || asa.publicKey != ""
+}
+
+// canSign returns false if artifacts are verified with a public key, but the private key
+// isn't available. Only trusted machines, such as CI, hold the private key, and everyone
+// else can only read from the remote cache.
+func (asa *ArtifactSignatureAuthentication) canSign() bool {
+	return asa.publicKey == "" || os.Getenv(_privateKeyEnv) != ""
+}
+
+// privateKey reads the base64 encoded Ed25519 private key, or its 32 byte seed, from the
+// environment, and checks that it matches the configured public key
+func (asa *ArtifactSignatureAuthentication) privateKey() (ed25519.PrivateKey, error) {
+	publicKey, err := fs.ParseRemoteCachePublicKey(asa.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	encoded := os.Getenv(_privateKeyEnv)
+	if len(encoded) == 0 {
+		return nil, fmt.Errorf("signature private key not found. You must specify a private key in the %v environment variable", _privateKeyEnv)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v: %w", _privateKeyEnv, err)
+	}
+	var privateKey ed25519.PrivateKey
+	switch len(decoded) {
+	case ed25519.SeedSize:
+		privateKey = ed25519.NewKeyFromSeed(decoded)
+	case ed25519.PrivateKeySize:
+		privateKey = ed25519.PrivateKey(decoded)
+	default:
+		return nil, fmt.Errorf("invalid %v: expected a %v byte Ed25519 seed or a %v byte private key, got %v bytes", _privateKeyEnv, ed25519.SeedSize, ed25519.PrivateKeySize, len(decoded))
+	}
+	if !bytes.Equal(privateKey.Public().(ed25519.PublicKey), publicKey) {
+		return nil, fmt.Errorf("%v doesn't match the public key in remoteCache.publicKey", _privateKeyEnv)
+	}
+	return privateKey, nil
 }
 
 // If the secret key is not found or the secret key length is 0, an error is returned
@@ -34,6 +97,9 @@ func (asa *ArtifactSignatureAuthentication) secretKey() ([]byte, error) {
 }
 
 func (asa *ArtifactSignatureAuthentication) generateTag(hash string, artifactBody []byte) (string, error) {
+	if asa.publicKey != "" {
+		return asa.generateEd25519Tag(hash, artifactBody)
+	}
 	tag, err := asa.getTagGenerator(hash)
 	if err != nil {
 		return "", err
@@ -42,20 +108,46 @@ func (asa *ArtifactSignatureAuthentication) generateTag(hash string, artifactBod
 	return base64.StdEncoding.EncodeToString(tag.Sum(nil)), nil
 }
 
-func (asa *ArtifactSignatureAuthentication) getTagGenerator(hash string) (hash.Hash, error) {
-	teamId := asa.teamId
-	secret, err := asa.secretKey()
+// generateEd25519Tag signs the artifact's metadata followed by its body
+func (asa *ArtifactSignatureAuthentication) generateEd25519Tag(hash string, artifactBody []byte) (string, error) {
+	privateKey, err := asa.privateKey()
+	if err != nil {
+		return "", err
+	}
+	message, err := asa.signedMessage(hash, artifactBody)
+	if err != nil {
+		return "", err
+	}
+	return _ed25519TagPrefix + base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, message)), nil
+}
+
+func (asa *ArtifactSignatureAuthentication) signedMessage(hash string, artifactBody []byte) ([]byte, error) {
+	metadata, err := asa.metadata(hash)
 	if err != nil {
 		return nil, err
 	}
+	message := make([]byte, 0, len(metadata)+len(artifactBody))
+	return append(append(message, metadata...), artifactBody...), nil
+}
+
+// metadata is what ties a tag to the artifact's hash and team, in addition to its contents
+func (asa *ArtifactSignatureAuthentication) metadata(hash string) ([]byte, error) {
 	artifactMetadata := &struct {
 		Hash   string `json:"hash"`
 		TeamId string `json:"teamId"`
 	}{
 		Hash:   hash,
-		TeamId: teamId,
+		TeamId: asa.teamId,
+	}
+	return json.Marshal(artifactMetadata)
+}
+
+func (asa *ArtifactSignatureAuthentication) getTagGenerator(hash string) (hash.Hash, error) {
+	secret, err := asa.secretKey()
+	if err != nil {
+		return nil, err
 	}
-	metadata, err := json.Marshal(artifactMetadata)
+	metadata, err := asa.metadata(hash)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +159,9 @@ func (asa *ArtifactSignatureAuthentication) getTagGenerator(hash string) (hash.H
 }
 
 func (asa *ArtifactSignatureAuthentication) validate(hash string, artifactBody []byte, expectedTag string) (bool, error) {
+	if asa.publicKey != "" {
+		return asa.validateEd25519(hash, artifactBody, expectedTag)
+	}
 	computedTag, err := asa.generateTag(hash, artifactBody)
 	if err != nil {
 		return false, fmt.Errorf("failed to verify artifact tag: %w", err)
@@ -74,6 +169,27 @@ func (asa *ArtifactSignatureAuthentication) validate(hash string, artifactBody [
 	return hmac.Equal([]byte(computedTag), []byte(expectedTag)), nil
 }
 
+// validateEd25519 checks the tag against the public key. Tags that aren't Ed25519 signatures,
+// such as HMACs, are never valid, since anyone holding the shared secret could have made them.
+func (asa *ArtifactSignatureAuthentication) validateEd25519(hash string, artifactBody []byte, expectedTag string) (bool, error) {
+	publicKey, err := fs.ParseRemoteCachePublicKey(asa.publicKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify artifact tag: %w", err)
+	}
+	if !strings.HasPrefix(expectedTag, _ed25519TagPrefix) {
+		return false, nil
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(expectedTag, _ed25519TagPrefix))
+	if err != nil {
+		return false, nil
+	}
+	message, err := asa.signedMessage(hash, artifactBody)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify artifact tag: %w", err)
+	}
+	return ed25519.Verify(publicKey, message, signature), nil
+}
+
 type StreamValidator struct {
 	currentHash hash.Hash
 }
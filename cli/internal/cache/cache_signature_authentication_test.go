@@ -4,10 +4,13 @@
 package cache
 
 import (
+	"crypto/ed25519"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -166,6 +169,89 @@ func Test_GenerateTagAndValidate(t *testing.T) {
 	}
 }
 
+func Test_Ed25519GenerateTagAndValidate(t *testing.T) {
+	teamId := "team_someid"
+	hash := "the-artifact-hash"
+	artifactBody := []byte("the artifact body as bytes")
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY", base64.StdEncoding.EncodeToString(privateKey.Seed()))
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_KEY", "my-secret-key-env")
+
+	asa := &ArtifactSignatureAuthentication{
+		teamId:    teamId,
+		publicKey: base64.StdEncoding.EncodeToString(publicKey),
+	}
+	assert.True(t, asa.isEnabled())
+	assert.True(t, asa.canSign())
+	tag, err := asa.generateTag(hash, artifactBody)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(tag, "ed25519:"))
+
+	isValid, err := asa.validate(hash, artifactBody, tag)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	cases := []struct {
+		name         string
+		hash         string
+		teamId       string
+		artifactBody []byte
+		tag          string
+	}{
+		{name: "Uses hash to validate tag", hash: "wrong-hash", teamId: teamId, artifactBody: artifactBody, tag: tag},
+		{name: "Uses teamId to validate tag", hash: hash, teamId: "wrong-teamId", artifactBody: artifactBody, tag: tag},
+		{name: "Uses artifactBody to validate tag", hash: hash, teamId: teamId, artifactBody: []byte("wrong-artifact-body"), tag: tag},
+		{name: "Rejects HMAC tags", hash: hash, teamId: teamId, artifactBody: artifactBody, tag: testUtilGetHMACTag(hash, teamId, artifactBody, "my-secret-key-env")},
+		{name: "Rejects malformed tags", hash: hash, teamId: teamId, artifactBody: artifactBody, tag: "ed25519:not base64"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier := &ArtifactSignatureAuthentication{
+				teamId:    tc.teamId,
+				publicKey: asa.publicKey,
+			}
+			isValid, err := verifier.validate(tc.hash, tc.artifactBody, tc.tag)
+			assert.NoError(t, err)
+			assert.False(t, isValid)
+		})
+	}
+
+	// The full private key can be given instead of its seed
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY", base64.StdEncoding.EncodeToString(privateKey))
+	fullKeyTag, err := asa.generateTag(hash, artifactBody)
+	assert.NoError(t, err)
+	assert.Equal(t, tag, fullKeyTag)
+}
+
+func Test_Ed25519VerifyOnly(t *testing.T) {
+	hash := "the-artifact-hash"
+	artifactBody := []byte("the artifact body as bytes")
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY", base64.StdEncoding.EncodeToString(privateKey.Seed()))
+	signer := &ArtifactSignatureAuthentication{publicKey: base64.StdEncoding.EncodeToString(publicKey)}
+	tag, err := signer.generateTag(hash, artifactBody)
+	assert.NoError(t, err)
+
+	// Machines without the private key can verify, but not sign
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY", "")
+	verifier := &ArtifactSignatureAuthentication{publicKey: signer.publicKey}
+	assert.False(t, verifier.canSign())
+	isValid, err := verifier.validate(hash, artifactBody, tag)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+	_, err = verifier.generateTag(hash, artifactBody)
+	assert.Error(t, err)
+
+	// A private key that doesn't belong to the public key is an error, rather than bad signatures
+	_, otherPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	t.Setenv("TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY", base64.StdEncoding.EncodeToString(otherPrivateKey.Seed()))
+	_, err = verifier.generateTag(hash, artifactBody)
+	assert.ErrorContains(t, err, "doesn't match the public key")
+}
+
 // Test utils
 
 // Return the Base64 encoded HMAC given the artifact metadata and artifact body
@@ -1,11 +1,14 @@
 package cache
 
 import (
+	"io"
 	"os"
 	"reflect"
+	"strings"
 	"sync/atomic"
 	"testing"
 
+	"github.com/spf13/pflag"
 	"github.com/vercel/turborepo/cli/internal/analytics"
 	"github.com/vercel/turborepo/cli/internal/config"
 	"github.com/vercel/turborepo/cli/internal/fs"
@@ -37,6 +40,16 @@ func (tc *testCache) Put(target string, hash string, duration int, files []strin
 	return nil
 }
 
+func (tc *testCache) Exists(hash string) (ItemStatus, error) {
+	if tc.disabledErr != nil {
+		return ItemStatus{}, tc.disabledErr
+	}
+	if _, ok := tc.entries[hash]; ok {
+		return ItemStatus{Local: true, Duration: 5}, nil
+	}
+	return ItemStatus{}, nil
+}
+
 func (tc *testCache) Clean(target string) {}
 func (tc *testCache) CleanAll()           {}
 func (tc *testCache) Shutdown()           {}
@@ -151,10 +164,209 @@ func TestFetchCachingDisabled(t *testing.T) {
 	mplex.mu.RUnlock()
 }
 
+func TestTierPolicies(t *testing.T) {
+	readOnly := newEnabledCache()
+	writeOnly := newEnabledCache()
+	readWrite := newEnabledCache()
+	mplex := &cacheMultiplexer{
+		caches: []Cache{writeOnly, readOnly, readWrite},
+		policies: map[Cache]TierPolicy{
+			readOnly:  {Read: true},
+			writeOnly: {Write: true},
+		},
+		onCacheRemoved: func(cache Cache, err error) {},
+	}
+
+	if err := mplex.Put("unused-target", "put-hash", 5, []string{"a-file"}); err != nil {
+		t.Errorf("Put got error %v, want <nil>", err)
+	}
+	if _, ok := readOnly.entries["put-hash"]; ok {
+		t.Error("Put stored files in a read-only cache")
+	}
+	for _, cache := range []*testCache{writeOnly, readWrite} {
+		if _, ok := cache.entries["put-hash"]; !ok {
+			t.Error("Put didn't store files in a writable cache")
+		}
+	}
+
+	// Only the write-only cache has this artifact, so it can't be fetched
+	writeOnly.entries["write-only-hash"] = []string{"a-file"}
+	if hit, _, _, _ := mplex.Fetch("unused-target", "write-only-hash", nil); hit {
+		t.Error("Fetch restored files from a write-only cache")
+	}
+	if status, _ := mplex.Exists("write-only-hash"); status != (ItemStatus{}) {
+		t.Errorf("Exists got %v for an artifact in a write-only cache, want a miss", status)
+	}
+
+	// Fetching from the lowest priority cache backfills the write-only cache, but not the
+	// read-only one
+	readWrite.entries["backfill-hash"] = []string{"a-file"}
+	if hit, _, _, _ := mplex.Fetch("unused-target", "backfill-hash", nil); !hit {
+		t.Error("Fetch didn't find files in a readable cache")
+	}
+	if _, ok := writeOnly.entries["backfill-hash"]; !ok {
+		t.Error("Fetch didn't backfill a write-only cache")
+	}
+	if _, ok := readOnly.entries["backfill-hash"]; ok {
+		t.Error("Fetch backfilled a read-only cache")
+	}
+}
+
+func TestEffectiveTiers(t *testing.T) {
+	tiers := []fs.CacheTier{
+		{Type: fs.CacheTierLocal},
+		{Type: fs.CacheTierLocal, Dir: "/mnt/turbo", Policy: fs.CacheTierReadOnly},
+		{Type: fs.CacheTierRemote},
+	}
+	testCases := []struct {
+		name string
+		args []string
+		want []fs.CacheTier
+	}{
+		{
+			name: "no flags",
+			want: []fs.CacheTier{
+				{Type: fs.CacheTierLocal, Policy: fs.CacheTierReadWrite},
+				{Type: fs.CacheTierLocal, Dir: "/mnt/turbo", Policy: fs.CacheTierReadOnly},
+				{Type: fs.CacheTierRemote, Policy: fs.CacheTierReadWrite},
+			},
+		},
+		{
+			name: "remote read-only",
+			args: []string{"--remote-cache-read-only"},
+			want: []fs.CacheTier{
+				{Type: fs.CacheTierLocal, Policy: fs.CacheTierReadWrite},
+				{Type: fs.CacheTierLocal, Dir: "/mnt/turbo", Policy: fs.CacheTierReadOnly},
+				{Type: fs.CacheTierRemote, Policy: fs.CacheTierReadOnly},
+			},
+		},
+		{
+			name: "policies for each type",
+			args: []string{"--cache=local:w,remote:r"},
+			want: []fs.CacheTier{
+				{Type: fs.CacheTierLocal, Policy: fs.CacheTierWriteOnly},
+				{Type: fs.CacheTierLocal, Dir: "/mnt/turbo", Policy: fs.CacheTierWriteOnly},
+				{Type: fs.CacheTierRemote, Policy: fs.CacheTierReadOnly},
+			},
+		},
+		{
+			name: "a type turned off",
+			args: []string{"--cache=remote:"},
+			want: []fs.CacheTier{
+				{Type: fs.CacheTierLocal, Policy: fs.CacheTierReadWrite},
+				{Type: fs.CacheTierLocal, Dir: "/mnt/turbo", Policy: fs.CacheTierReadOnly},
+			},
+		},
+		{
+			name: "write-only remote with read-only flag",
+			args: []string{"--cache=remote:w", "--remote-cache-read-only"},
+			want: []fs.CacheTier{
+				{Type: fs.CacheTierLocal, Policy: fs.CacheTierReadWrite},
+				{Type: fs.CacheTierLocal, Dir: "/mnt/turbo", Policy: fs.CacheTierReadOnly},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &Opts{Tiers: tiers}
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			AddFlags(opts, flags, fs.AbsolutePath("/repo"))
+			if err := flags.Parse(tc.args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := opts.EffectiveTiers(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("EffectiveTiers() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewReadOnlyRemote(t *testing.T) {
+	opts := Opts{SkipFilesystem: true, RemoteReadOnly: true}
+	got, err := New(opts, &config.Config{}, nil, &nullRecorder{}, func(Cache, error) {})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	mplex, ok := got.(*cacheMultiplexer)
+	if !ok {
+		t.Fatalf("New() = %v, want a cacheMultiplexer", reflect.TypeOf(got))
+	}
+	httpCache, ok := mplex.caches[0].(*httpCache)
+	if !ok {
+		t.Fatalf("caches[0] = %v, want an httpCache", reflect.TypeOf(mplex.caches[0]))
+	}
+	if httpCache.writable {
+		t.Error("the httpCache of a read-only remote tier is writable")
+	}
+	if policy := mplex.policy(httpCache); policy != (TierPolicy{Read: true}) {
+		t.Errorf("policy of the remote tier = %v, want read-only", policy)
+	}
+}
+
+func TestCacheFlagErrors(t *testing.T) {
+	testCases := map[string]string{
+		"--cache=remote":            `invalid cache setting "remote"`,
+		"--cache=nfs:rw":            `invalid cache type "nfs"`,
+		"--cache=remote:x":          `invalid access "x" for "remote"`,
+		"--cache=remote:r,remote:w": `the access of "remote" is set more than once`,
+	}
+	for arg, want := range testCases {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		AddFlags(&Opts{}, flags, fs.AbsolutePath("/repo"))
+		err := flags.Parse([]string{arg})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%v: got error %v, want one containing %q", arg, err, want)
+		}
+	}
+}
+
 type nullRecorder struct{}
 
 func (nullRecorder) LogEvent(analytics.EventPayload) {}
 
+func TestExistsCachingDisabled(t *testing.T) {
+	disabledCache := newDisabledCache()
+	localCache := newEnabledCache()
+	localCache.entries["some-hash"] = []string{"a-file"}
+	var removeCalled uint64
+	mplex := &cacheMultiplexer{
+		caches: []Cache{
+			disabledCache,
+			newEnabledCache(),
+			localCache,
+		},
+		onCacheRemoved: func(cache Cache, err error) {
+			atomic.AddUint64(&removeCalled, 1)
+		},
+	}
+
+	status, err := mplex.Exists("some-hash")
+	if err != nil {
+		t.Errorf("Exists got error %v, want <nil>", err)
+	}
+	if want := (ItemStatus{Local: true, Duration: 5}); status != want {
+		t.Errorf("Exists got %v, want %v", status, want)
+	}
+	status, err = mplex.Exists("missing-hash")
+	if err != nil {
+		t.Errorf("Exists got error %v, want <nil>", err)
+	}
+	if status != (ItemStatus{}) {
+		t.Errorf("Exists got %v, want a miss", status)
+	}
+
+	removes := atomic.LoadUint64(&removeCalled)
+	if removes != 1 {
+		t.Errorf("removes count: %v, want 1", removes)
+	}
+	mplex.mu.RLock()
+	if len(mplex.caches) != 2 {
+		t.Errorf("found %v caches, expected to have 2 after one was removed", len(mplex.caches))
+	}
+	mplex.mu.RUnlock()
+}
+
 func TestNew(t *testing.T) {
 	// Test will bomb if this fails, no need to specially handle the error
 	cwd, _ := os.Getwd()
@@ -219,6 +431,43 @@ func TestNew(t *testing.T) {
 			want:    &fsCache{},
 			wantErr: false,
 		},
+		{
+			name: "With a read-only fsCache configured, new returns a multiplexer",
+			args: args{
+				opts: Opts{
+					Dir:        fs.AbsolutePath(cwd),
+					SkipRemote: true,
+					Tiers:      []fs.CacheTier{{Type: fs.CacheTierLocal, Policy: fs.CacheTierReadOnly}},
+				},
+				config:         &config.Config{},
+				recorder:       &nullRecorder{},
+				onCacheRemoved: func(Cache, error) {},
+			},
+			want: &cacheMultiplexer{
+				caches: []Cache{&fsCache{}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "With tiers configured, new returns their caches in order",
+			args: args{
+				opts: Opts{
+					Dir: fs.AbsolutePath(cwd),
+					Tiers: []fs.CacheTier{
+						{Type: fs.CacheTierLocal},
+						{Type: fs.CacheTierLocal, Dir: cwd},
+						{Type: fs.CacheTierRemote, Policy: fs.CacheTierReadOnly},
+					},
+				},
+				config:         &config.Config{},
+				recorder:       &nullRecorder{},
+				onCacheRemoved: func(Cache, error) {},
+			},
+			want: &cacheMultiplexer{
+				caches: []Cache{&fsCache{}, &fsCache{}, &httpCache{}},
+			},
+			wantErr: false,
+		},
 		{
 			name: "With both configured, new returns an fsCache and httpCache",
 			args: args{
@@ -249,7 +498,11 @@ func TestNew(t *testing.T) {
 			}
 			switch multiplexer := got.(type) {
 			case *cacheMultiplexer:
-				want := tt.want.(*cacheMultiplexer)
+				want, ok := tt.want.(*cacheMultiplexer)
+				if !ok || len(multiplexer.caches) != len(want.caches) {
+					t.Errorf("New() = %v, want %v", multiplexer.caches, tt.want)
+					return
+				}
 				for i := range multiplexer.caches {
 					if reflect.TypeOf(multiplexer.caches[i]) != reflect.TypeOf(want.caches[i]) {
 						t.Errorf("New() = %v, want %v", reflect.TypeOf(multiplexer.caches[i]), reflect.TypeOf(want.caches[i]))
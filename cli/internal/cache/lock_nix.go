@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// tryLockFile takes an exclusive lock on file without waiting for it, returning errLockHeld
+// if another process holds it. fcntl locks are used rather than flock because they are
+// supported by NFS, and the kernel releases them when the process that holds them exits.
+func tryLockFile(file *os.File) error {
+	err := unix.FcntlFlock(file.Fd(), unix.F_SETLK, &unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart})
+	if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EACCES) {
+		return errLockHeld
+	}
+	return err
+}
+
+// unlockFile releases a lock taken by tryLockFile
+func unlockFile(file *os.File) error {
+	return unix.FcntlFlock(file.Fd(), unix.F_SETLK, &unix.Flock_t{Type: unix.F_UNLCK, Whence: io.SeekStart})
+}
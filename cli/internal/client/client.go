@@ -286,6 +286,17 @@ func (c *ApiClient) PutArtifact(hash string, artifactBody []byte, duration int,
 // FetchArtifact attempts to retrieve the build artifact with the given hash from the
 // Remote Caching server
 func (c *ApiClient) FetchArtifact(hash string) (*http.Response, error) {
+	return c.getArtifact(hash, http.MethodGet)
+}
+
+// ArtifactExists asks the Remote Caching server whether it has the build artifact with the
+// given hash, without downloading it. The response has the same status and headers as
+// FetchArtifact's, but no body.
+func (c *ApiClient) ArtifactExists(hash string) (*http.Response, error) {
+	return c.getArtifact(hash, http.MethodHead)
+}
+
+func (c *ApiClient) getArtifact(hash string, httpMethod string) (*http.Response, error) {
 	if err := c.okToRequest(); err != nil {
 		return nil, err
 	}
@@ -300,7 +311,7 @@ func (c *ApiClient) FetchArtifact(hash string) (*http.Response, error) {
 	requestURL := c.makeUrl("/v8/artifacts/" + hash + encoded)
 	allowAuth := true
 	if c.usePreflight {
-		resp, latestRequestURL, err := c.doPreflight(requestURL, http.MethodGet, "Authorization, User-Agent")
+		resp, latestRequestURL, err := c.doPreflight(requestURL, httpMethod, "Authorization, User-Agent")
 		if err != nil {
 			return nil, fmt.Errorf("pre-flight request failed before trying to fetch files in HTTP cache: %w", err)
 		}
@@ -309,7 +320,7 @@ func (c *ApiClient) FetchArtifact(hash string) (*http.Response, error) {
 		allowAuth = strings.Contains(strings.ToLower(headers), strings.ToLower("Authorization"))
 	}
 
-	req, err := retryablehttp.NewRequest(http.MethodGet, requestURL, nil)
+	req, err := retryablehttp.NewRequest(httpMethod, requestURL, nil)
 	if allowAuth {
 		req.Header.Set("Authorization", "Bearer "+c.token)
 	}
@@ -322,6 +333,11 @@ func (c *ApiClient) FetchArtifact(hash string) (*http.Response, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch artifact: %v", err)
 	} else if resp.StatusCode == http.StatusForbidden {
+		if httpMethod == http.MethodHead {
+			// There's no body to explain why
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("failed to fetch artifact: %v", resp.Status)
+		}
 		err = c.handle403(resp.Body)
 		_ = resp.Body.Close()
 		return nil, err
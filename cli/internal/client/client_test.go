@@ -157,3 +157,44 @@ func Test_FetchWhenCachingDisabled(t *testing.T) {
 		t.Errorf("response got %v, want <nil>", resp)
 	}
 }
+
+func Test_ArtifactExists(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodHead {
+			t.Errorf("request method: expected %v, got %v", http.MethodHead, req.Method)
+		}
+		if req.URL.Path != "/v8/artifacts/hash" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("x-artifact-duration", "500")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	remoteConfig := RemoteConfig{
+		TeamSlug: "my-team-slug",
+		APIURL:   ts.URL,
+		Token:    "my-token",
+	}
+	apiClient := NewClient(remoteConfig, hclog.Default(), "v1", Opts{})
+	resp, err := apiClient.ArtifactExists("hash")
+	if err != nil {
+		t.Fatalf("ArtifactExists: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status code: expected %v, got %v", http.StatusOK, resp.StatusCode)
+	}
+	if duration := resp.Header.Get("x-artifact-duration"); duration != "500" {
+		t.Errorf("x-artifact-duration: expected 500, got %v", duration)
+	}
+	resp, err = apiClient.ArtifactExists("missing-hash")
+	if err != nil {
+		t.Fatalf("ArtifactExists: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status code: expected %v, got %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
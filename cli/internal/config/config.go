@@ -45,6 +45,18 @@ type Config struct {
 	UserConfig   *UserConfig
 	RepoConfig   *RepoConfig
 	RemoteConfig client.RemoteConfig
+	// Where the values of RemoteConfig and LoginURL came from
+	RemoteConfigSources RemoteConfigSources
+}
+
+// RemoteConfigSources records where each remote config value came from: a flag such as
+// "--team", an env var, a config file, or SourceDefault. Values that aren't set have no source.
+type RemoteConfigSources struct {
+	APIURL   string
+	LoginURL string
+	Token    string
+	TeamID   string
+	TeamSlug string
 }
 
 // CacheConfig
@@ -87,16 +99,25 @@ func ParseAndValidate(args []string, ui cli.Ui, turboVersion string, userConfigF
 		return nil, fmt.Errorf("reading repo config file: %v", err)
 	}
 	remoteConfig := repoConfig.GetRemoteConfig(token)
+	sources := RemoteConfigSources{
+		APIURL:   repoConfig.source("apiurl", "TURBO_API"),
+		LoginURL: repoConfig.source("loginurl", "TURBO_LOGIN"),
+		Token:    userConfig.source("token", "TURBO_TOKEN"),
+		TeamID:   repoConfig.source("teamid", "TURBO_TEAMID"),
+		TeamSlug: repoConfig.source("teamslug", "TURBO_TEAM"),
+	}
 
 	if token == "" && IsCI() {
 		vercelArtifactsToken := os.Getenv("VERCEL_ARTIFACTS_TOKEN")
 		vercelArtifactsOwner := os.Getenv("VERCEL_ARTIFACTS_OWNER")
 		if vercelArtifactsToken != "" {
 			remoteConfig.Token = vercelArtifactsToken
+			sources.Token = "VERCEL_ARTIFACTS_TOKEN"
 		}
 		if vercelArtifactsOwner != "" {
 			//repoConfig.TeamId = vercelArtifactsOwner
 			remoteConfig.TeamID = vercelArtifactsOwner
+			sources.TeamID = "VERCEL_ARTIFACTS_OWNER"
 		}
 	}
 
@@ -139,16 +160,20 @@ func ParseAndValidate(args []string, ui cli.Ui, turboVersion string, userConfigF
 				return nil, fmt.Errorf("%s is an invalid URL", apiURL)
 			}
 			remoteConfig.APIURL = apiURL
+			sources.APIURL = "--api"
 		case strings.HasPrefix(arg, "--url="):
 			loginURLArg := arg[len("--url="):]
 			if _, err := url.ParseRequestURI(loginURLArg); err != nil {
 				return nil, fmt.Errorf("%s is an invalid URL", loginURLArg)
 			}
 			loginURL = loginURLArg
+			sources.LoginURL = "--url"
 		case strings.HasPrefix(arg, "--token="):
 			remoteConfig.Token = arg[len("--token="):]
+			sources.Token = "--token"
 		case strings.HasPrefix(arg, "--team="):
 			remoteConfig.TeamSlug = arg[len("--team="):]
+			sources.TeamSlug = "--team"
 		case arg == "--preflight":
 			usePreflight = true
 		default:
@@ -180,6 +205,8 @@ func ParseAndValidate(args []string, ui cli.Ui, turboVersion string, userConfigF
 		RemoteConfig: remoteConfig,
 		LoginURL:     loginURL,
 		TurboVersion: turboVersion,
+
+		RemoteConfigSources: sources,
 		Cache: &CacheConfig{
 			Workers: runtime.NumCPU() + 2,
 		},
@@ -42,6 +42,11 @@ func (rc *RepoConfig) GetRemoteConfig(token string) client.RemoteConfig {
 	}
 }
 
+// source returns where the value of the given key came from
+func (rc *RepoConfig) source(key string, envVar string) string {
+	return valueSource(rc.repoViper, rc.path, key, envVar)
+}
+
 // Internal call to save this config data to the user config file.
 func (rc *RepoConfig) write() error {
 	if err := rc.path.EnsureDir(); err != nil {
@@ -78,6 +83,11 @@ func (uc *UserConfig) SetToken(token string) error {
 	return uc.write()
 }
 
+// source returns where the value of the given key came from
+func (uc *UserConfig) source(key string, envVar string) string {
+	return valueSource(uc.userViper, uc.path, key, envVar)
+}
+
 // Internal call to save this config data to the user config file.
 func (uc *UserConfig) write() error {
 	if err := uc.path.EnsureDir(); err != nil {
@@ -116,6 +126,24 @@ func DefaultUserConfigPath() fs.AbsolutePath {
 	return fs.GetUserConfigDir().Join("config.json")
 }
 
+// SourceDefault is the source of values that weren't configured anywhere
+const SourceDefault = "default"
+
+// valueSource returns where viper found the value of the given key: the env var it is
+// bound to, the config file at path, or the default. Keys without a value have no source.
+func valueSource(v *viper.Viper, path fs.AbsolutePath, key string, envVar string) string {
+	switch {
+	case v.GetString(key) == "":
+		return ""
+	case os.Getenv(envVar) != "":
+		return envVar
+	case v.InConfig(key):
+		return path.ToString()
+	default:
+		return SourceDefault
+	}
+}
+
 const (
 	_defaultAPIURL   = "https://vercel.com/api"
 	_defaultLoginURL = "https://vercel.com"
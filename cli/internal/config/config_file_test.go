@@ -97,3 +97,17 @@ func TestWriteUserConfig(t *testing.T) {
 	assert.Equal(t, final.Token(), "")
 	assert.Equal(t, configPath.FileExists(), false, "config file should be deleted")
 }
+
+func TestRepoConfigSources(t *testing.T) {
+	testConfigFile := fs.AbsolutePathFromUpstream(t.TempDir()).Join("turborepo", "config.json")
+	assert.NilError(t, testConfigFile.EnsureDir(), "EnsureDir")
+	assert.NilError(t, testConfigFile.WriteFile([]byte(`{"teamSlug":"my-team","teamId":"team_123"}`), 0644), "WriteFile")
+	t.Setenv("TURBO_TEAM", "env-team")
+
+	config, err := ReadRepoConfigFile(testConfigFile)
+	assert.NilError(t, err, "ReadRepoConfigFile")
+
+	assert.Equal(t, config.source("apiurl", "TURBO_API"), SourceDefault)
+	assert.Equal(t, config.source("teamslug", "TURBO_TEAM"), "TURBO_TEAM")
+	assert.Equal(t, config.source("teamid", "TURBO_TEAMID"), testConfigFile.ToString())
+}
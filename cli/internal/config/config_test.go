@@ -90,3 +90,18 @@ func TestSelectCwd(t *testing.T) {
 	}
 
 }
+
+func TestRemoteConfigSources(t *testing.T) {
+	userConfigPath := fs.AbsolutePathFromUpstream(t.TempDir()).Join("turborepo", "config.json")
+	t.Setenv("TURBO_TOKEN", "my-token")
+	t.Setenv("TURBO_TEAM", "")
+	t.Setenv("TURBO_API", "")
+
+	cfg, err := ParseAndValidate([]string{"run", "build", "--team=my-team"}, ui.Default(), "my-version", userConfigPath)
+	if err != nil {
+		t.Fatalf("failed to parse config %v", err)
+	}
+	assert.Equal(t, "TURBO_TOKEN", cfg.RemoteConfigSources.Token)
+	assert.Equal(t, "--team", cfg.RemoteConfigSources.TeamSlug)
+	assert.Equal(t, SourceDefault, cfg.RemoteConfigSources.APIURL)
+}
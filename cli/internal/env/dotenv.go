@@ -0,0 +1,124 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var dotEnvKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// ParseDotEnv parses the contents of a .env file. Each line holds a KEY=value pair, optionally
+// prefixed with "export". Blank lines and lines starting with # are ignored. Values may be
+// wrapped in single quotes, which are taken literally, or double quotes, which may span lines
+// and support \n, \t, \" and \\ escapes. Unquoted values end at a # preceded by whitespace.
+// References to other variables are not expanded.
+func ParseDotEnv(contents string) (map[string]string, error) {
+	envVars := make(map[string]string)
+	rest := strings.ReplaceAll(contents, "\r\n", "\n")
+	lineNumber := 0
+	for rest != "" {
+		var line string
+		line, rest = cutLine(rest)
+		lineNumber++
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		eq := strings.Index(line, "=")
+		if eq == -1 {
+			return nil, fmt.Errorf("line %v: expected KEY=value", lineNumber)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !dotEnvKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("line %v: invalid variable name %q", lineNumber, key)
+		}
+		value := strings.TrimSpace(line[eq+1:])
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end == -1 {
+				return nil, fmt.Errorf("line %v: unterminated single-quoted value for %v", lineNumber, key)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// Double-quoted values can continue onto the following lines
+			quoted := value[1:]
+			for {
+				if end := closingQuote(quoted); end != -1 {
+					value = unescapeDoubleQuoted(quoted[:end])
+					break
+				}
+				if rest == "" {
+					return nil, fmt.Errorf("line %v: unterminated double-quoted value for %v", lineNumber, key)
+				}
+				var next string
+				next, rest = cutLine(rest)
+				lineNumber++
+				quoted += "\n" + next
+			}
+		default:
+			if comment := strings.Index(value, " #"); comment != -1 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+		envVars[key] = value
+	}
+	return envVars, nil
+}
+
+// cutLine splits s after its first line
+func cutLine(s string) (string, string) {
+	if i := strings.Index(s, "\n"); i != -1 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// closingQuote returns the index of the first unescaped double quote in s, or -1
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+var doubleQuotedEscapes = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+
+func unescapeDoubleQuoted(s string) string {
+	return doubleQuotedEscapes.Replace(s)
+}
+
+// ReadDotEnvFiles parses the given .env files in order, so that variables in later files
+// override those in earlier ones. Files that don't exist are skipped.
+func ReadDotEnvFiles(paths []string) (map[string]string, error) {
+	envVars := make(map[string]string)
+	for _, path := range paths {
+		contents, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		fileVars, err := ParseDotEnv(string(contents))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %w", path, err)
+		}
+		for k, v := range fileVars {
+			envVars[k] = v
+		}
+	}
+	return envVars, nil
+}
+
+// DotEnvPairs returns the sorted key=value pairs of variables read from .env files
+func DotEnvPairs(envVars map[string]string) []string {
+	return toSortedPairs(envVars)
+}
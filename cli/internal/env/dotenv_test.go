@@ -0,0 +1,76 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	contents := `# comment
+API_URL=https://example.com
+export REGION = us-east-1
+EMPTY=
+UNQUOTED=some value # trailing comment
+HASH=abc#123
+SINGLE='literal \n $VALUE # kept'
+DOUBLE="line one\nline \"two\""
+MULTILINE="first
+second"
+WINDOWS=crlf` + "\r\n"
+	got, err := ParseDotEnv(contents)
+	if err != nil {
+		t.Fatalf("ParseDotEnv() error: %v", err)
+	}
+	want := map[string]string{
+		"API_URL":   "https://example.com",
+		"REGION":    "us-east-1",
+		"EMPTY":     "",
+		"UNQUOTED":  "some value",
+		"HASH":      "abc#123",
+		"SINGLE":    `literal \n $VALUE # kept`,
+		"DOUBLE":    "line one\nline \"two\"",
+		"MULTILINE": "first\nsecond",
+		"WINDOWS":   "crlf",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDotEnv() got %v, want %v", got, want)
+	}
+}
+
+func TestParseDotEnv_Invalid(t *testing.T) {
+	testCases := []string{
+		"NO_EQUALS",
+		"1INVALID=name",
+		"UNTERMINATED='value",
+		"UNTERMINATED=\"value\nstill going",
+	}
+	for _, tc := range testCases {
+		if _, err := ParseDotEnv(tc); err == nil {
+			t.Errorf("expected an error parsing %q", tc)
+		}
+	}
+}
+
+func TestReadDotEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("A=from-env\nB=from-env\n"), 0644); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env.local"), []byte("B=from-local\n"), 0644); err != nil {
+		t.Fatalf("failed to write .env.local: %v", err)
+	}
+	got, err := ReadDotEnvFiles([]string{
+		filepath.Join(dir, ".env"),
+		filepath.Join(dir, ".env.missing"),
+		filepath.Join(dir, ".env.local"),
+	})
+	if err != nil {
+		t.Fatalf("ReadDotEnvFiles() error: %v", err)
+	}
+	want := map[string]string{"A": "from-env", "B": "from-local"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDotEnvFiles() got %v, want %v", got, want)
+	}
+}
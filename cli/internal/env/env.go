@@ -3,10 +3,16 @@ package env
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
+)
 
-	"github.com/vercel/turborepo/cli/internal/util"
+const (
+	// Wildcard matches any sequence of characters in an env var pattern
+	Wildcard = "*"
+	// NegationPrefix marks an env var pattern as excluding the variables it matches
+	NegationPrefix = "!"
 )
 
 func getEnvMap() map[string]string {
@@ -20,57 +26,100 @@ func getEnvMap() map[string]string {
 	return envMap
 }
 
-// getEnvPairsFromKeys returns a slice of key=value pairs for all env var keys specified in envKeys
-func getEnvPairsFromKeys(envKeys []string, allEnvVars map[string]string) []string {
-	hashableConfigEnvPairs := []string{}
-	for _, envVar := range envKeys {
-		hashableConfigEnvPairs = append(hashableConfigEnvPairs, fmt.Sprintf("%v=%v", envVar, allEnvVars[envVar]))
+// wildcardRegex converts an env var pattern, which may contain * wildcards, to an anchored regexp
+func wildcardRegex(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
 	}
-
-	return hashableConfigEnvPairs
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
 }
 
-// getEnvPairsFromPrefix returns a slice of all key=value pairs that match the given prefix
-func getEnvPairsFromPrefix(includePrefix string, excludePrefix string, allEnvVars map[string]string) []string {
-	allEnvPairs := []string{}
-	for k, v := range allEnvVars {
-		if excludePrefix != "" && strings.HasPrefix(k, excludePrefix) {
-			continue
+// resolveEnv returns the env vars from allEnvVars that match envPatterns or start with one of
+// envPrefixes, excluding those that match a negated pattern. If includeUnset is true, patterns
+// that name a single variable are included even when that variable is unset.
+func resolveEnv(allEnvVars map[string]string, envPatterns []string, envPrefixes []string, includeUnset bool) map[string]string {
+	resolved := make(map[string]string)
+	var exclusions []*regexp.Regexp
+	for _, pattern := range envPatterns {
+		if strings.HasPrefix(pattern, NegationPrefix) {
+			exclusions = append(exclusions, wildcardRegex(strings.TrimPrefix(pattern, NegationPrefix)))
+		} else if !strings.Contains(pattern, Wildcard) {
+			if value, ok := allEnvVars[pattern]; ok || includeUnset {
+				resolved[pattern] = value
+			}
+		} else {
+			matcher := wildcardRegex(pattern)
+			for k, v := range allEnvVars {
+				if matcher.MatchString(k) {
+					resolved[k] = v
+				}
+			}
 		}
-		if strings.HasPrefix(k, includePrefix) {
-			allEnvPairs = append(allEnvPairs, fmt.Sprintf("%v=%v", k, v))
+	}
+
+	// Variables added automatically for frameworks can be excluded by the CI vendor, as they may
+	// hold values that are unique to each build. Anything explicitly configured is kept.
+	excludePrefix := allEnvVars["TURBO_CI_VENDOR_ENV_KEY"]
+	for _, includePrefix := range envPrefixes {
+		for k, v := range allEnvVars {
+			if excludePrefix != "" && strings.HasPrefix(k, excludePrefix) {
+				continue
+			}
+			if strings.HasPrefix(k, includePrefix) {
+				resolved[k] = v
+			}
 		}
 	}
-	return allEnvPairs
-}
 
-// getEnvPairsFromPrefixes returns a slice containing key=value pairs
-func getEnvPairsFromPrefixes(includePrefixes []string, excludePrefix string, allEnvVars map[string]string) []string {
-	allEnvPairs := []string{}
-	for _, includePrefix := range includePrefixes {
-		hashableFrameworkEnvPairs := getEnvPairsFromPrefix(includePrefix, excludePrefix, allEnvVars)
-		allEnvPairs = append(allEnvPairs, hashableFrameworkEnvPairs...)
+	for k := range resolved {
+		for _, exclusion := range exclusions {
+			if exclusion.MatchString(k) {
+				delete(resolved, k)
+				break
+			}
+		}
 	}
-	return allEnvPairs
+	return resolved
 }
 
-// GetHashableEnvPairs returns all sorted key=value env var pairs for both frameworks and from envKeys
-func GetHashableEnvPairs(envKeys []string, envPrefixes []string) []string {
-	allEnvVars := getEnvMap()
-	excludePrefix := allEnvVars["TURBO_CI_VENDOR_ENV_KEY"]
-	hashableEnvFromKeys := getEnvPairsFromKeys(envKeys, allEnvVars)
-	hashableEnvFromPrefixes := getEnvPairsFromPrefixes(envPrefixes, excludePrefix, allEnvVars)
-
-	// convert to set to eliminate duplicates, then cast back to slice to sort for stable hashing
-	uniqueHashableEnvPairs := make(util.Set, len(hashableEnvFromKeys)+len(hashableEnvFromPrefixes))
-	for _, pair := range hashableEnvFromKeys {
-		uniqueHashableEnvPairs.Add(pair)
+// toSortedPairs returns the key=value pairs of envVars, sorted for stable hashing
+func toSortedPairs(envVars map[string]string) []string {
+	pairs := make([]string, 0, len(envVars))
+	for k, v := range envVars {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", k, v))
 	}
-	for _, pair := range hashableEnvFromPrefixes {
-		uniqueHashableEnvPairs.Add(pair)
+	sort.Strings(pairs)
+	return pairs
+}
+
+// ValidatePatterns checks that the given env var patterns are well-formed. Patterns are names of
+// variables, which may contain * wildcards, and may be negated by a leading !.
+func ValidatePatterns(envPatterns []string) error {
+	for _, pattern := range envPatterns {
+		name := strings.TrimPrefix(pattern, NegationPrefix)
+		if name == "" {
+			return fmt.Errorf("invalid env var pattern %q: expected a variable name", pattern)
+		}
+		if strings.HasPrefix(name, "$") {
+			return fmt.Errorf("invalid env var pattern %q: variable names should not be prefixed with $", pattern)
+		}
+		if strings.ContainsAny(name, "=!") {
+			return fmt.Errorf("invalid env var pattern %q: variable names cannot contain = or !", pattern)
+		}
 	}
+	return nil
+}
+
+// GetHashableEnvPairs returns all sorted key=value env var pairs for both frameworks and from
+// envPatterns. Patterns may use * as a wildcard, and variables that match a pattern starting
+// with ! are excluded. Patterns naming a single variable are included even if it is unset.
+func GetHashableEnvPairs(envPatterns []string, envPrefixes []string) []string {
+	return toSortedPairs(resolveEnv(getEnvMap(), envPatterns, envPrefixes, true))
+}
 
-	allHashableEnvPairs := uniqueHashableEnvPairs.UnsafeListOfStrings()
-	sort.Strings(allHashableEnvPairs)
-	return allHashableEnvPairs
+// GetEnvPairs returns the sorted key=value pairs of the env vars that are set and match
+// envPatterns or envPrefixes, following the same rules as GetHashableEnvPairs.
+func GetEnvPairs(envPatterns []string, envPrefixes []string) []string {
+	return toSortedPairs(resolveEnv(getEnvMap(), envPatterns, envPrefixes, false))
 }
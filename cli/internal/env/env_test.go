@@ -275,6 +275,42 @@ func TestGetHashableEnvPairs(t *testing.T) {
 			},
 			want: []string{"MANUAL=true", "NEXT_PUBLIC_VERCEL_ENV=true"},
 		},
+		{
+			env:  []string{"NEXT_PUBLIC_URL=a", "NEXT_PUBLIC_SECRET=b", "API_URL=c", "API_KEY=d", "OTHER=e"},
+			name: "wildcards match set env vars",
+			args: args{
+				envKeys:     []string{"API_*", "MISSING_*"},
+				envPrefixes: []string{},
+			},
+			want: []string{"API_KEY=d", "API_URL=c"},
+		},
+		{
+			env:  []string{"NEXT_PUBLIC_URL=a", "NEXT_PUBLIC_SECRET=b", "API_URL=c", "API_KEY=d"},
+			name: "negations exclude configured and framework env vars",
+			args: args{
+				envKeys:     []string{"API_*", "!API_KEY", "!*_SECRET"},
+				envPrefixes: []string{"NEXT_PUBLIC_"},
+			},
+			want: []string{"API_URL=c", "NEXT_PUBLIC_URL=a"},
+		},
+		{
+			env:  []string{"FIRST_THASH_ENV_VAR=first", "TURBO_TOKEN=never", "SOME_OTHER_THASH_ENV_VAR=second"},
+			name: "wildcards can match anywhere in a name",
+			args: args{
+				envKeys:     []string{"*THASH*"},
+				envPrefixes: []string{},
+			},
+			want: []string{"FIRST_THASH_ENV_VAR=first", "SOME_OTHER_THASH_ENV_VAR=second"},
+		},
+		{
+			env:  []string{"NEXT_PUBLIC_VERCEL_URL=a", "TURBO_CI_VENDOR_ENV_KEY=NEXT_PUBLIC_VERCEL_"},
+			name: "$TURBO_CI_VENDOR_ENV_KEY does not exclude env vars matched by wildcards",
+			args: args{
+				envKeys:     []string{"NEXT_PUBLIC_*"},
+				envPrefixes: []string{"NEXT_PUBLIC_"},
+			},
+			want: []string{"NEXT_PUBLIC_VERCEL_URL=a"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -289,3 +325,25 @@ func TestGetHashableEnvPairs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetEnvPairs(t *testing.T) {
+	setEnvs([]string{"SET=1", "EMPTY=", "API_URL=2"})
+	defer os.Clearenv()
+	got := GetEnvPairs([]string{"SET", "EMPTY", "UNSET", "API_*"}, []string{})
+	want := []string{"API_URL=2", "EMPTY=", "SET=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetEnvPairs() = %v, want %v", got, want)
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	valid := []string{"API_KEY", "NEXT_PUBLIC_*", "!NEXT_PUBLIC_SECRET", "*THASH*"}
+	if err := ValidatePatterns(valid); err != nil {
+		t.Errorf("ValidatePatterns(%v) got unexpected error %v", valid, err)
+	}
+	for _, pattern := range []string{"", "!", "$API_KEY", "!!API_KEY", "API_KEY=value"} {
+		if err := ValidatePatterns([]string{pattern}); err == nil {
+			t.Errorf("ValidatePatterns(%q) expected an error", pattern)
+		}
+	}
+}
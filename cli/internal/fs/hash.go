@@ -4,8 +4,10 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/vercel/turborepo/cli/internal/xxhash"
@@ -47,15 +49,39 @@ func GitLikeHashFile(filePath string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	hash := NewGitLikeHash(stat.Size())
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// NewGitLikeHash returns a hash that computes the same SHA1 as GitLikeHashFile, once the
+// contents of a file with the given size have been written to it
+func NewGitLikeHash(size int64) hash.Hash {
 	hash := sha1.New()
 	hash.Write([]byte("blob"))
 	hash.Write([]byte(" "))
-	hash.Write([]byte(strconv.FormatInt(stat.Size(), 10)))
+	hash.Write([]byte(strconv.FormatInt(size, 10)))
 	hash.Write([]byte{0})
+	return hash
+}
 
-	if _, err := io.Copy(hash, file); err != nil {
+// GitLikeHashSymlink mimics how Git calculates the SHA1 for a symlink, which
+// it stores as a blob containing the link target rather than the contents of
+// the file being pointed to.
+func GitLikeHashSymlink(filePath string) (string, error) {
+	target, err := os.Readlink(filePath)
+	if err != nil {
 		return "", err
 	}
+	hash := sha1.New()
+	hash.Write([]byte("blob"))
+	hash.Write([]byte(" "))
+	hash.Write([]byte(strconv.Itoa(len(target))))
+	hash.Write([]byte{0})
+	hash.Write([]byte(filepath.ToSlash(target)))
 
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
@@ -0,0 +1,101 @@
+package fs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vercel/turborepo/cli/internal/util"
+	"muzzammil.xyz/jsonc"
+)
+
+// _rootConfigReference is how a package's turbo.json refers to the root turbo.json in "extends"
+const _rootConfigReference = "//"
+
+// MergePackageConfigs reads the turbo.json in each workspace package, if it has one, and adds
+// its tasks to the pipeline as package tasks (pkg#task). A package's task definition starts
+// from the root's definition for that package and task, and each key set in the package's
+// turbo.json replaces the root's value. The exceptions are env, passThroughEnv, dotEnv and
+// the $ entries in dependsOn, which add to the root's lists.
+func (tj *TurboJSON) MergePackageConfigs(rootPath AbsolutePath, packageInfos map[interface{}]*PackageJSON) error {
+	// Visit packages in a stable order, so that errors are reported consistently
+	pkgNames := make([]string, 0, len(packageInfos))
+	for pkgName := range packageInfos {
+		pkgNames = append(pkgNames, fmt.Sprintf("%v", pkgName))
+	}
+	sort.Strings(pkgNames)
+	for _, pkgName := range pkgNames {
+		if pkgName == util.RootPkgName {
+			continue
+		}
+		pkg := packageInfos[pkgName]
+		configPath := rootPath.Join(pkg.Dir.ToString(), configFile)
+		if !configPath.FileExists() {
+			continue
+		}
+		relativePath, err := rootPath.RelativePathString(configPath.ToString())
+		if err != nil {
+			return err
+		}
+		data, err := configPath.ReadFile()
+		if err != nil {
+			return err
+		}
+		tasks, err := parsePackageTurboJSON(relativePath, data)
+		if err != nil {
+			return err
+		}
+		if tj.Pipeline == nil {
+			tj.Pipeline = make(Pipeline)
+		}
+		// Resolve every task against the root pipeline before adding any, so
+		// that tasks in the same file don't affect each other
+		taskNames := make([]string, 0, len(tasks))
+		for task := range tasks {
+			taskNames = append(taskNames, task)
+		}
+		sort.Strings(taskNames)
+		merged := make(map[string]TaskDefinition, len(tasks))
+		mergedSources := make(map[string]TaskDefinitionSources, len(tasks))
+		for _, task := range taskNames {
+			rawPipeline := tasks[task]
+			taskID := util.GetTaskId(pkgName, task)
+			taskDefinition, ok := tj.Pipeline.GetTaskDefinition(taskID)
+			if !ok {
+				// Packages can also define tasks that the root pipeline doesn't have
+				taskDefinition = defaultTaskDefinition()
+			}
+			if err := taskDefinition.merge(rawPipeline); err != nil {
+				return fmt.Errorf("%v: pipeline.%v: %w", relativePath, task, err)
+			}
+			merged[taskID] = taskDefinition
+			sources, ok := tj.TaskDefinitionSources(taskID)
+			if !ok {
+				sources = defaultTaskDefinitionSources()
+			}
+			mergedSources[taskID] = sources.record(rawPipeline, relativePath)
+		}
+		if tj.sources == nil {
+			tj.sources = make(map[string]TaskDefinitionSources)
+		}
+		for taskID, taskDefinition := range merged {
+			tj.Pipeline[taskID] = taskDefinition
+			tj.sources[taskID] = mergedSources[taskID]
+		}
+	}
+	return nil
+}
+
+// parsePackageTurboJSON validates the contents of a package's turbo.json, and returns the
+// definitions of the tasks it configures
+func parsePackageTurboJSON(file string, data []byte) (map[string]*pipelineJSON, error) {
+	if err := ValidatePackageTurboJSON(file, data, nil); err != nil {
+		return nil, err
+	}
+	var packageConfig struct {
+		Pipeline map[string]*pipelineJSON `json:"pipeline"`
+	}
+	if err := jsonc.Unmarshal(data, &packageConfig); err != nil {
+		return nil, fmt.Errorf("%v: %w", file, err)
+	}
+	return packageConfig.Pipeline, nil
+}
@@ -0,0 +1,116 @@
+package fs
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vercel/turborepo/cli/internal/turbopath"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+func writePackageConfig(t *testing.T, rootPath AbsolutePath, dir string, contents string) {
+	t.Helper()
+	configPath := rootPath.Join(dir, configFile)
+	if err := configPath.EnsureDir(); err != nil {
+		t.Fatalf("failed to create %v: %v", dir, err)
+	}
+	if err := configPath.WriteFile([]byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write %v: %v", configPath, err)
+	}
+}
+
+func rootTurboJSON(t *testing.T, contents string) *TurboJSON {
+	t.Helper()
+	turboJSON := &TurboJSON{}
+	if err := json.Unmarshal([]byte(contents), turboJSON); err != nil {
+		t.Fatalf("failed to parse root turbo.json: %v", err)
+	}
+	return turboJSON
+}
+
+func TestMergePackageConfigs(t *testing.T) {
+	rootPath := AbsolutePath(t.TempDir())
+	turboJSON := rootTurboJSON(t, `{
+		"pipeline": {
+			"build": {"dependsOn": ["^build", "$NODE_ENV"], "outputs": ["dist/**"], "env": ["API_*"]},
+			"test": {"dependsOn": ["build"], "inputs": ["src/**"]}
+		}
+	}`)
+	writePackageConfig(t, rootPath, filepath.Join("apps", "web"), `{
+		// comments are allowed, as in the root turbo.json
+		"extends": ["//"],
+		"pipeline": {
+			"build": {"outputs": [".next/**"], "env": ["!API_SECRET", "NEXT_*"]},
+			"deploy": {"dependsOn": ["build"], "cache": false}
+		}
+	}`)
+	writePackageConfig(t, rootPath, filepath.Join("packages", "ui"), `{
+		"pipeline": {"test": {"dependsOn": [], "inputs": ["src/**", "stories/**"]}}
+	}`)
+	packageInfos := map[interface{}]*PackageJSON{
+		util.RootPkgName: {Name: util.RootPkgName, Dir: turbopath.AnchoredSystemPath("")},
+		"web":            {Name: "web", Dir: turbopath.AnchoredSystemPath(filepath.Join("apps", "web"))},
+		"ui":             {Name: "ui", Dir: turbopath.AnchoredSystemPath(filepath.Join("packages", "ui"))},
+		// packages without a turbo.json only use the root pipeline
+		"docs": {Name: "docs", Dir: turbopath.AnchoredSystemPath(filepath.Join("apps", "docs"))},
+	}
+
+	if err := turboJSON.MergePackageConfigs(rootPath, packageInfos); err != nil {
+		t.Fatalf("MergePackageConfigs() error: %v", err)
+	}
+
+	assert.Len(t, turboJSON.Pipeline, 5)
+	webBuild := turboJSON.Pipeline["web#build"]
+	assert.Equal(t, []string{".next/**"}, webBuild.Outputs)
+	assert.Equal(t, []string{"build"}, webBuild.TopologicalDependencies)
+	assert.Equal(t, []string{"NODE_ENV", "API_*", "!API_SECRET", "NEXT_*"}, webBuild.EnvVarDependencies)
+	webDeploy := turboJSON.Pipeline["web#deploy"]
+	assert.Equal(t, []string{"build"}, webDeploy.TaskDependencies)
+	assert.False(t, webDeploy.ShouldCache)
+	assert.Equal(t, defaultOutputs, webDeploy.Outputs)
+	uiTest := turboJSON.Pipeline["ui#test"]
+	assert.Equal(t, []string{}, uiTest.TaskDependencies)
+	assert.Equal(t, []string{"src/**", "stories/**"}, uiTest.Inputs)
+
+	// The root definitions are unchanged
+	build := turboJSON.Pipeline["build"]
+	assert.Equal(t, []string{"dist/**"}, build.Outputs)
+	assert.Equal(t, []string{"NODE_ENV", "API_*"}, build.EnvVarDependencies)
+	test := turboJSON.Pipeline["test"]
+	assert.Equal(t, []string{"build"}, test.TaskDependencies)
+}
+
+func TestMergePackageConfigs_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		err      string
+	}{
+		{"root-only key", `{"globalDependencies": [".env"]}`, `"globalDependencies" can only be set in the root turbo.json`},
+		{"unknown key", `{"pipelines": {}}`, `unknown key "pipelines"`},
+		{"extends another config", `{"extends": ["@acme/turbo-config"]}`, "can only extend the root turbo.json"},
+		{"package task", `{"pipeline": {"web#build": {}}}`, `use "build" instead`},
+		{"unknown task key", `{"pipeline": {"build": {"dependOn": ["^build"]}}}`, `unknown key "dependOn"`},
+		{"invalid env", `{"pipeline": {"build": {"env": ["$API_KEY"]}}}`, "pipeline.build.env[0]: invalid env var pattern"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rootPath := AbsolutePath(t.TempDir())
+			writePackageConfig(t, rootPath, "web", tc.contents)
+			turboJSON := rootTurboJSON(t, `{"pipeline": {"build": {}}}`)
+			packageInfos := map[interface{}]*PackageJSON{
+				"web": {Name: "web", Dir: turbopath.AnchoredSystemPath("web")},
+			}
+			err := turboJSON.MergePackageConfigs(rootPath, packageInfos)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.HasPrefix(err.Error(), filepath.Join("web", configFile)+":1:") || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got error %q, want one mentioning %q", err, tc.err)
+			}
+		})
+	}
+}
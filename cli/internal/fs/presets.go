@@ -0,0 +1,232 @@
+package fs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"muzzammil.xyz/jsonc"
+)
+
+// presetJSON is the part of a preset that is shared with the turbo.json that extends it
+type presetJSON struct {
+	Extends            []string                 `json:"extends,omitempty"`
+	GlobalDependencies []string                 `json:"globalDependencies,omitempty"`
+	GlobalEnv          []string                 `json:"globalEnv,omitempty"`
+	Pipeline           map[string]*pipelineJSON `json:"pipeline,omitempty"`
+}
+
+// preset is a shareable configuration that a turbo.json extends
+type preset struct {
+	// source is the preset's path relative to the repo root
+	source string
+	config *presetJSON
+}
+
+// presetResolver finds the presets that a turbo.json extends, and the presets that they extend
+type presetResolver struct {
+	rootPath     AbsolutePath
+	packageInfos map[interface{}]*PackageJSON
+	// presets are in the order they're applied, so that each preset comes after those it extends
+	presets []*preset
+	loaded  map[AbsolutePath]bool
+}
+
+// ApplyPresets reads the presets listed in the "extends" key of the root turbo.json, and
+// rebuilds the pipeline so that each task starts from the presets' definition of it. Presets
+// are applied in order, after any presets they extend themselves, followed by the root
+// turbo.json. A preset is either a path to a file or directory, starting with ./ or ../, or
+// the name of a workspace package or a package installed in node_modules, optionally followed
+// by a path within that package. Presets that point at a directory or a package use its
+// turbo.json. Keys of a task definition merge in the same way as in a package's turbo.json,
+// and the globalDependencies and globalEnv of presets are added to the root's.
+func (tj *TurboJSON) ApplyPresets(rootPath AbsolutePath, packageInfos map[interface{}]*PackageJSON) error {
+	if len(tj.Extends) == 0 {
+		return nil
+	}
+	rootSource := tj.source
+	if rootSource == "" {
+		rootSource = configFile
+	}
+	resolver := &presetResolver{
+		rootPath:     rootPath,
+		packageInfos: packageInfos,
+		loaded:       make(map[AbsolutePath]bool),
+	}
+	if err := resolver.resolve(rootPath, tj.Extends, []string{rootSource}); err != nil {
+		return err
+	}
+
+	pipeline := make(Pipeline)
+	sources := make(map[string]TaskDefinitionSources)
+	apply := func(rawPipeline map[string]*pipelineJSON, source string) error {
+		tasks := make([]string, 0, len(rawPipeline))
+		for task := range rawPipeline {
+			tasks = append(tasks, task)
+		}
+		sort.Strings(tasks)
+		for _, task := range tasks {
+			taskPipeline := rawPipeline[task]
+			if taskPipeline == nil {
+				taskPipeline = &pipelineJSON{}
+			}
+			taskDefinition, ok := pipeline[task]
+			if !ok {
+				taskDefinition = defaultTaskDefinition()
+			}
+			if err := taskDefinition.merge(taskPipeline); err != nil {
+				return fmt.Errorf("%v: pipeline.%v: %w", source, task, err)
+			}
+			pipeline[task] = taskDefinition
+			taskSources, ok := sources[task]
+			if !ok {
+				taskSources = defaultTaskDefinitionSources()
+			}
+			sources[task] = taskSources.record(taskPipeline, source)
+		}
+		return nil
+	}
+
+	var globalDependencies []string
+	var globalEnv []string
+	for _, p := range resolver.presets {
+		if err := apply(p.config.Pipeline, p.source); err != nil {
+			return err
+		}
+		globalDependencies = append(globalDependencies, p.config.GlobalDependencies...)
+		globalEnv = append(globalEnv, p.config.GlobalEnv...)
+		tj.presetFiles = append(tj.presetFiles, p.source)
+	}
+	if err := apply(tj.rawPipeline, rootSource); err != nil {
+		return err
+	}
+	tj.Pipeline = pipeline
+	tj.sources = sources
+	tj.GlobalDependencies = append(globalDependencies, tj.GlobalDependencies...)
+	tj.GlobalEnv = append(globalEnv, tj.GlobalEnv...)
+	return nil
+}
+
+// PresetFiles returns the paths, relative to the repo root, of the presets that were applied
+// by ApplyPresets
+func (tj *TurboJSON) PresetFiles() []string {
+	return tj.presetFiles
+}
+
+// resolve loads the presets in extends, which is in the file at the end of chain. Relative
+// paths and installed packages are looked up from dir.
+func (r *presetResolver) resolve(dir AbsolutePath, extends []string, chain []string) error {
+	from := chain[len(chain)-1]
+	for _, specifier := range extends {
+		path, err := r.locate(dir, specifier)
+		if err != nil {
+			return fmt.Errorf("%v: extends: %w", from, err)
+		}
+		source, err := r.rootPath.RelativePathString(path.ToString())
+		if err != nil {
+			return err
+		}
+		for _, ancestor := range chain {
+			if ancestor == source {
+				return fmt.Errorf("%v: extends: %q creates a cycle: %v", from, specifier, strings.Join(append(chain, source), " -> "))
+			}
+		}
+		// A preset that is extended more than once is only applied the first time
+		if r.loaded[path] {
+			continue
+		}
+		r.loaded[path] = true
+		data, err := path.ReadFile()
+		if err != nil {
+			return fmt.Errorf("%v: extends: failed to read preset %q: %w", from, specifier, err)
+		}
+		if err := ValidatePresetTurboJSON(source, data, nil); err != nil {
+			return err
+		}
+		config := &presetJSON{}
+		if err := jsonc.Unmarshal(data, config); err != nil {
+			return fmt.Errorf("%v: %w", source, err)
+		}
+		if err := r.resolve(path.Dir(), config.Extends, append(chain, source)); err != nil {
+			return err
+		}
+		r.presets = append(r.presets, &preset{source: source, config: config})
+	}
+	return nil
+}
+
+// locate returns the config file that a preset specifier refers to
+func (r *presetResolver) locate(dir AbsolutePath, specifier string) (AbsolutePath, error) {
+	if specifier == _rootConfigReference {
+		return "", fmt.Errorf("%q can only be extended by a package's turbo.json", _rootConfigReference)
+	}
+	if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") {
+		return presetConfigFile(dir.Join(filepath.FromSlash(specifier)))
+	}
+	name, subpath := splitPackageSpecifier(specifier)
+	if name == "" || filepath.IsAbs(specifier) {
+		return "", fmt.Errorf("invalid preset %q: expected a relative path starting with ./ or ../, or a package name", specifier)
+	}
+	pkgDir, ok := r.packageDir(dir, name)
+	if !ok {
+		return "", fmt.Errorf("can't find preset %q: %q isn't a workspace package, or installed in node_modules", specifier, name)
+	}
+	if subpath == "" {
+		return presetConfigFile(pkgDir)
+	}
+	return presetConfigFile(pkgDir.Join(filepath.FromSlash(subpath)))
+}
+
+// packageDir returns the directory of the named package, which is either a workspace package
+// or installed in a node_modules directory between dir and the repo root
+func (r *presetResolver) packageDir(dir AbsolutePath, name string) (AbsolutePath, bool) {
+	if pkg, ok := r.packageInfos[name]; ok {
+		return r.rootPath.Join(pkg.Dir.ToString()), true
+	}
+	for {
+		candidate := dir.Join("node_modules", filepath.FromSlash(name))
+		if candidate.DirExists() {
+			return candidate, true
+		}
+		parent := dir.Dir()
+		if dir == r.rootPath || parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// presetConfigFile returns the config file at path, which is its turbo.json if it's a directory
+func presetConfigFile(path AbsolutePath) (AbsolutePath, error) {
+	if path.DirExists() {
+		path = path.Join(configFile)
+	}
+	if !path.FileExists() {
+		return "", fmt.Errorf("preset %v doesn't exist", path)
+	}
+	return path, nil
+}
+
+// splitPackageSpecifier splits a specifier such as "@acme/turbo-config/strict.json" into the
+// package name and the path within the package. The name is empty if the specifier isn't valid.
+func splitPackageSpecifier(specifier string) (string, string) {
+	nameParts := 1
+	if strings.HasPrefix(specifier, "@") {
+		nameParts = 2
+	}
+	parts := strings.SplitN(specifier, "/", nameParts+1)
+	if len(parts) < nameParts {
+		return "", ""
+	}
+	for _, part := range parts[:nameParts] {
+		if part == "" || part == "@" || part == "." || part == ".." {
+			return "", ""
+		}
+	}
+	name := strings.Join(parts[:nameParts], "/")
+	if len(parts) > nameParts {
+		return name, parts[nameParts]
+	}
+	return name, ""
+}
@@ -0,0 +1,166 @@
+package fs
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vercel/turborepo/cli/internal/turbopath"
+)
+
+func writeConfigFile(t *testing.T, path AbsolutePath, contents string) {
+	t.Helper()
+	if err := path.EnsureDir(); err != nil {
+		t.Fatalf("failed to create directory for %v: %v", path, err)
+	}
+	if err := path.WriteFile([]byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write %v: %v", path, err)
+	}
+}
+
+func TestApplyPresets(t *testing.T) {
+	rootPath := AbsolutePath(t.TempDir())
+	presetDir := rootPath.Join("node_modules", "@acme", "turbo-config")
+	writeConfigFile(t, presetDir.Join("base.json"), `{
+		"globalEnv": ["CI"],
+		"pipeline": {
+			"lint": {"outputs": []}
+		}
+	}`)
+	writeConfigFile(t, presetDir.Join("turbo.json"), `{
+		// presets can extend other presets
+		"extends": ["./base.json"],
+		"globalDependencies": ["tsconfig.json"],
+		"pipeline": {
+			"build": {"dependsOn": ["^build"], "outputs": ["dist/**"], "env": ["NODE_ENV"]},
+			"test": {"dependsOn": ["build"]}
+		}
+	}`)
+	writeConfigFile(t, rootPath.Join("packages", "config", "strict.json"), `{
+		"pipeline": {"test": {"cache": false}}
+	}`)
+	packageInfos := map[interface{}]*PackageJSON{
+		"config": {Name: "config", Dir: turbopath.AnchoredSystemPath(filepath.Join("packages", "config"))},
+	}
+	turboJSON := rootTurboJSON(t, `{
+		"extends": ["@acme/turbo-config", "config/strict.json"],
+		"globalEnv": ["API_URL"],
+		"pipeline": {
+			"build": {"outputs": [".next/**"], "env": ["NEXT_*"]},
+			"deploy": {"dependsOn": ["build"]}
+		}
+	}`)
+
+	if err := turboJSON.ApplyPresets(rootPath, packageInfos); err != nil {
+		t.Fatalf("ApplyPresets() error: %v", err)
+	}
+
+	assert.Len(t, turboJSON.Pipeline, 4)
+	build := turboJSON.Pipeline["build"]
+	assert.Equal(t, []string{"build"}, build.TopologicalDependencies)
+	assert.Equal(t, []string{".next/**"}, build.Outputs)
+	assert.Equal(t, []string{"NODE_ENV", "NEXT_*"}, build.EnvVarDependencies)
+	test := turboJSON.Pipeline["test"]
+	assert.Equal(t, []string{"build"}, test.TaskDependencies)
+	assert.False(t, test.ShouldCache)
+	assert.Equal(t, []string{}, turboJSON.Pipeline["lint"].Outputs)
+	assert.Equal(t, defaultOutputs, turboJSON.Pipeline["deploy"].Outputs)
+	assert.Equal(t, []string{"tsconfig.json"}, turboJSON.GlobalDependencies)
+	assert.Equal(t, []string{"CI", "API_URL"}, turboJSON.GlobalEnv)
+
+	presetFile := filepath.Join("node_modules", "@acme", "turbo-config", "turbo.json")
+	strictFile := filepath.Join("packages", "config", "strict.json")
+	assert.Equal(t, []string{
+		filepath.Join("node_modules", "@acme", "turbo-config", "base.json"),
+		presetFile,
+		strictFile,
+	}, turboJSON.PresetFiles())
+	buildSources, _ := turboJSON.TaskDefinitionSources("build")
+	assert.Equal(t, []string{presetFile}, buildSources["dependsOn"])
+	assert.Equal(t, []string{"turbo.json"}, buildSources["outputs"])
+	assert.Equal(t, []string{presetFile, "turbo.json"}, buildSources["env"])
+	testSources, _ := turboJSON.TaskDefinitionSources("test")
+	assert.Equal(t, []string{strictFile}, testSources["cache"])
+}
+
+func TestApplyPresetsErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		files   map[string]string
+		extends string
+		want    string
+	}{
+		{
+			name:    "missing package",
+			extends: `["@acme/turbo-config"]`,
+			want:    `turbo.json: extends: can't find preset "@acme/turbo-config": "@acme/turbo-config" isn't a workspace package, or installed in node_modules`,
+		},
+		{
+			name:    "missing file",
+			extends: `["./presets/base.json"]`,
+			want:    "turbo.json: extends: preset ",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"presets/a.json": `{"extends": ["./b.json"]}`,
+				"presets/b.json": `{"extends": ["./a.json"]}`,
+			},
+			extends: `["./presets/a.json"]`,
+			want: `extends: "./a.json" creates a cycle: turbo.json -> ` + filepath.Join("presets", "a.json") + " -> " +
+				filepath.Join("presets", "b.json") + " -> " + filepath.Join("presets", "a.json"),
+		},
+		{
+			name: "remote cache in a preset",
+			files: map[string]string{
+				"presets/base.json": `{"remoteCache": {"signature": true}}`,
+			},
+			extends: `["./presets/base.json"]`,
+			want:    `"remoteCache" can only be set in the root turbo.json, not in a preset`,
+		},
+		{
+			name:    "invalid specifier",
+			extends: `["/etc/turbo.json"]`,
+			want:    `invalid preset "/etc/turbo.json"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rootPath := AbsolutePath(t.TempDir())
+			for file, contents := range tc.files {
+				writeConfigFile(t, rootPath.Join(filepath.FromSlash(file)), contents)
+			}
+			turboJSON := rootTurboJSON(t, `{"extends": `+tc.extends+`, "pipeline": {"build": {}}}`)
+			err := turboJSON.ApplyPresets(rootPath, map[interface{}]*PackageJSON{})
+			if err == nil {
+				t.Fatalf("ApplyPresets() expected an error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("ApplyPresets() error = %v, want it to contain %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func Test_splitPackageSpecifier(t *testing.T) {
+	testCases := []struct {
+		specifier   string
+		wantName    string
+		wantSubpath string
+	}{
+		{"turbo-config", "turbo-config", ""},
+		{"turbo-config/strict.json", "turbo-config", "strict.json"},
+		{"@acme/turbo-config", "@acme/turbo-config", ""},
+		{"@acme/turbo-config/presets/strict.json", "@acme/turbo-config", "presets/strict.json"},
+		{"@acme", "", ""},
+		{"@/turbo-config", "", ""},
+		{"/turbo-config", "", ""},
+	}
+	for _, tc := range testCases {
+		name, subpath := splitPackageSpecifier(tc.specifier)
+		if name != tc.wantName || subpath != tc.wantSubpath {
+			t.Errorf("splitPackageSpecifier(%q) = %q, %q, want %q, %q", tc.specifier, name, subpath, tc.wantName, tc.wantSubpath)
+		}
+	}
+}
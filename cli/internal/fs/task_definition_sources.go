@@ -0,0 +1,152 @@
+package fs
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// SourceDefault is the source of task definition keys that aren't set in any config file
+const SourceDefault = "default"
+
+// TaskDefinitionKeys are the keys of a task definition in turbo.json
+var TaskDefinitionKeys = []string{
+	"dependsOn",
+	"env",
+	"passThroughEnv",
+	"dotEnv",
+	"loadDotEnv",
+	"outputs",
+	"cache",
+	"inputs",
+	"outputMode",
+	"command",
+	"runner",
+	"cleanOutputs",
+}
+
+// TaskDefinitionSources records where each key of a task definition was set. Sources are
+// config files relative to the repo root, such as "turbo.json" or "apps/web/turbo.json", or
+// SourceDefault. Keys that add to a list, such as env, can have several sources.
+type TaskDefinitionSources map[string][]string
+
+func defaultTaskDefinitionSources() TaskDefinitionSources {
+	sources := make(TaskDefinitionSources, len(TaskDefinitionKeys))
+	for _, key := range TaskDefinitionKeys {
+		sources[key] = []string{SourceDefault}
+	}
+	return sources
+}
+
+// record returns a copy of these sources, updated for the keys that rawPipeline sets in the
+// given source. This mirrors how TaskDefinition.merge applies rawPipeline.
+func (s TaskDefinitionSources) record(rawPipeline *pipelineJSON, source string) TaskDefinitionSources {
+	sources := make(TaskDefinitionSources, len(s))
+	for key, keySources := range s {
+		sources[key] = keySources
+	}
+	replace := func(key string) {
+		sources[key] = []string{source}
+	}
+	add := func(key string) {
+		keySources := sources[key]
+		if len(keySources) == 1 && keySources[0] == SourceDefault {
+			sources[key] = []string{source}
+			return
+		}
+		for _, existing := range keySources {
+			if existing == source {
+				return
+			}
+		}
+		sources[key] = append(append([]string{}, keySources...), source)
+	}
+
+	if rawPipeline.DependsOn != nil {
+		replace("dependsOn")
+		for _, dependency := range rawPipeline.DependsOn {
+			if strings.HasPrefix(dependency, envPipelineDelimiter) {
+				add("env")
+				break
+			}
+		}
+	}
+	if len(rawPipeline.Env) > 0 {
+		add("env")
+	}
+	if len(rawPipeline.PassThroughEnv) > 0 {
+		add("passThroughEnv")
+	}
+	if len(rawPipeline.DotEnv) > 0 {
+		add("dotEnv")
+	}
+	if rawPipeline.LoadDotEnv != nil {
+		replace("loadDotEnv")
+	}
+	if rawPipeline.Outputs != nil {
+		replace("outputs")
+	}
+	if rawPipeline.Cache != nil {
+		replace("cache")
+	}
+	if rawPipeline.Inputs != nil {
+		replace("inputs")
+	}
+	if rawPipeline.OutputMode != nil {
+		replace("outputMode")
+	}
+	if rawPipeline.Command != nil {
+		replace("command")
+	}
+	if rawPipeline.Runner != nil {
+		replace("runner")
+	}
+	if rawPipeline.CleanOutputs != nil {
+		replace("cleanOutputs")
+	}
+	return sources
+}
+
+// UnmarshalJSON deserializes a TurboJSON, keeping the keys that each pipeline entry sets so
+// that their sources can be recorded
+func (tj *TurboJSON) UnmarshalJSON(data []byte) error {
+	type turboJSON TurboJSON
+	if err := json.Unmarshal(data, (*turboJSON)(tj)); err != nil {
+		return err
+	}
+	var rawConfig struct {
+		Pipeline map[string]*pipelineJSON `json:"pipeline"`
+	}
+	if err := json.Unmarshal(data, &rawConfig); err != nil {
+		return err
+	}
+	tj.rawPipeline = rawConfig.Pipeline
+	return nil
+}
+
+// recordSources notes that the pipeline entries of this TurboJSON were read from source
+func (tj *TurboJSON) recordSources(source string) {
+	tj.source = source
+	tj.sources = make(map[string]TaskDefinitionSources, len(tj.rawPipeline))
+	for key, rawPipeline := range tj.rawPipeline {
+		if rawPipeline == nil {
+			rawPipeline = &pipelineJSON{}
+		}
+		tj.sources[key] = defaultTaskDefinitionSources().record(rawPipeline, source)
+	}
+}
+
+// TaskDefinitionSources returns where each key of the given task's definition came from. The
+// task is resolved the same way as in Pipeline.GetTaskDefinition.
+func (tj *TurboJSON) TaskDefinitionSources(taskID string) (TaskDefinitionSources, bool) {
+	if sources, ok := tj.sources[taskID]; ok {
+		return sources, true
+	}
+	if _, ok := tj.Pipeline[taskID]; ok || !util.IsPackageTask(taskID) {
+		return nil, false
+	}
+	_, task := util.GetPackageTaskFromId(taskID)
+	sources, ok := tj.sources[task]
+	return sources, ok
+}
@@ -0,0 +1,51 @@
+package fs
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vercel/turborepo/cli/internal/turbopath"
+)
+
+func TestTaskDefinitionSources(t *testing.T) {
+	rootPath := AbsolutePath(t.TempDir())
+	turboJSON := rootTurboJSON(t, `{
+		"pipeline": {
+			"build": {"dependsOn": ["^build", "$NODE_ENV"], "outputs": ["dist/**"]},
+			"lint": {}
+		}
+	}`)
+	turboJSON.recordSources(configFile)
+	writePackageConfig(t, rootPath, filepath.Join("apps", "web"), `{
+		"pipeline": {"build": {"outputs": [".next/**"], "env": ["NEXT_*"]}}
+	}`)
+	packageInfos := map[interface{}]*PackageJSON{
+		"web": {Name: "web", Dir: turbopath.AnchoredSystemPath(filepath.Join("apps", "web"))},
+	}
+	if err := turboJSON.MergePackageConfigs(rootPath, packageInfos); err != nil {
+		t.Fatalf("MergePackageConfigs() error: %v", err)
+	}
+
+	build, ok := turboJSON.TaskDefinitionSources("build")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"turbo.json"}, build["dependsOn"])
+	assert.Equal(t, []string{"turbo.json"}, build["env"])
+	assert.Equal(t, []string{"turbo.json"}, build["outputs"])
+	assert.Equal(t, []string{SourceDefault}, build["cache"])
+
+	webConfig := filepath.Join("apps", "web", "turbo.json")
+	webBuild, ok := turboJSON.TaskDefinitionSources("web#build")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"turbo.json"}, webBuild["dependsOn"])
+	assert.Equal(t, []string{"turbo.json", webConfig}, webBuild["env"])
+	assert.Equal(t, []string{webConfig}, webBuild["outputs"])
+
+	// Package tasks without their own entry use the sources of the task
+	docsLint, ok := turboJSON.TaskDefinitionSources("docs#lint")
+	assert.True(t, ok)
+	assert.Equal(t, []string{SourceDefault}, docsLint["outputs"])
+
+	_, ok = turboJSON.TaskDefinitionSources("test")
+	assert.False(t, ok)
+}
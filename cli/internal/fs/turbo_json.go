@@ -1,25 +1,45 @@
 package fs
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"path"
+	"path/filepath"
 	"strings"
 
+	"github.com/vercel/turborepo/cli/internal/doublestar"
+	"github.com/vercel/turborepo/cli/internal/env"
 	"github.com/vercel/turborepo/cli/internal/util"
 	"muzzammil.xyz/jsonc"
 )
 
 // TurboJSON is the root turborepo configuration
 type TurboJSON struct {
+	// Presets whose configuration this one builds on
+	Extends []string `json:"extends,omitempty"`
 	// Global root filesystem dependencies
 	GlobalDependencies []string `json:"globalDependencies,omitempty"`
+	// Global env var patterns that affect the hashes of all tasks
+	GlobalEnv []string `json:"globalEnv,omitempty"`
 	// Pipeline is a map of Turbo pipeline entries which define the task graph
 	// and cache behavior on a per task or per package-task basis.
 	Pipeline Pipeline
 	// Configuration options when interfacing with the remote cache
 	RemoteCacheOptions RemoteCacheOptions `json:"remoteCache,omitempty"`
+	// The caches that artifacts are read from and written to, highest priority first
+	CacheTiers []CacheTier `json:"cacheTiers,omitempty"`
+
+	// The keys set by each pipeline entry, as written in the config
+	rawPipeline map[string]*pipelineJSON
+	// Where the keys of each pipeline entry came from
+	sources map[string]TaskDefinitionSources
+	// The file this configuration was read from
+	source string
+	// The presets applied by ApplyPresets
+	presetFiles []string
 }
 
 const configFile = "turbo.json"
@@ -38,12 +58,13 @@ func ReadTurboConfig(rootPath AbsolutePath, rootPackageJSON *PackageJSON) (*Turb
 			return nil, fmt.Errorf("Could not find %s. Follow directions at https://turborepo.org/docs/getting-started to create one", configFile)
 		}
 		log.Printf("[WARNING] Turbo configuration now lives in \"%s\". Migrate to %s by running \"npx @turbo/codemod create-turbo-config\"", configFile, configFile)
+		rootPackageJSON.LegacyTurboConfig.recordSources("package.json")
 		return rootPackageJSON.LegacyTurboConfig, nil
 	}
 
 	turboJSON, err := readTurboJSON(turboJSONPath)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", configFile, err)
+		return nil, err
 	}
 
 	if rootPackageJSON.LegacyTurboConfig != nil {
@@ -56,20 +77,18 @@ func ReadTurboConfig(rootPath AbsolutePath, rootPackageJSON *PackageJSON) (*Turb
 
 // readTurboJSON reads the configFile in to a struct
 func readTurboJSON(path AbsolutePath) (*TurboJSON, error) {
-	file, err := path.Open()
+	data, err := path.ReadFile()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: %w", configFile, err)
 	}
-	var turboJSON *TurboJSON
-	data, err := ioutil.ReadAll(file)
-	if err != nil {
+	if err := warnUnknownKeys(ValidateTurboJSON(configFile, data, nil)); err != nil {
 		return nil, err
 	}
-	err = jsonc.Unmarshal(data, &turboJSON)
-	if err != nil {
-		println("error unmarshalling", err.Error())
-		return nil, err
+	var turboJSON *TurboJSON
+	if err := jsonc.Unmarshal(data, &turboJSON); err != nil {
+		return nil, fmt.Errorf("%s: %w", configFile, err)
 	}
+	turboJSON.recordSources(configFile)
 	return turboJSON, nil
 }
 
@@ -77,14 +96,71 @@ func readTurboJSON(path AbsolutePath) (*TurboJSON, error) {
 type RemoteCacheOptions struct {
 	TeamID    string `json:"teamId,omitempty"`
 	Signature bool   `json:"signature,omitempty"`
+	// PublicKey is the base64 encoded Ed25519 key that remote cache artifacts are verified with
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+// ParseRemoteCachePublicKey decodes the remoteCache.publicKey option
+func ParseRemoteCachePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: expected a %v byte Ed25519 key, got %v bytes", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
 }
 
+// CacheTier is an entry of .cacheTiers in configFile
+type CacheTier struct {
+	// Type is CacheTierLocal or CacheTierRemote
+	Type string `json:"type"`
+	// Dir is the directory of a local tier, relative to the repo root. Local tiers without
+	// one use the --cache-dir.
+	Dir string `json:"dir,omitempty"`
+	// Policy is whether artifacts are read from the tier, written to it, or both. It
+	// defaults to CacheTierReadWrite.
+	Policy string `json:"policy,omitempty"`
+}
+
+// Types of cache tiers
+const (
+	// CacheTierLocal is a cache in a directory, such as the local cache or a shared NFS mount
+	CacheTierLocal = "local"
+	// CacheTierRemote is the Remote Cache
+	CacheTierRemote = "remote"
+)
+
+// CacheTierTypes are the valid values of a cache tier's type
+var CacheTierTypes = []string{CacheTierLocal, CacheTierRemote}
+
+// What turbo does with the artifacts in a cache tier
+const (
+	// CacheTierReadWrite restores artifacts from the tier, and stores artifacts in it
+	CacheTierReadWrite = "read-write"
+	// CacheTierReadOnly restores artifacts from the tier, but never stores any
+	CacheTierReadOnly = "read-only"
+	// CacheTierWriteOnly stores artifacts in the tier, but never restores any
+	CacheTierWriteOnly = "write-only"
+)
+
+// CacheTierPolicies are the valid values of a cache tier's policy
+var CacheTierPolicies = []string{CacheTierReadWrite, CacheTierReadOnly, CacheTierWriteOnly}
+
 type pipelineJSON struct {
-	Outputs    *[]string           `json:"outputs"`
-	Cache      *bool               `json:"cache,omitempty"`
-	DependsOn  []string            `json:"dependsOn,omitempty"`
-	Inputs     []string            `json:"inputs,omitempty"`
-	OutputMode util.TaskOutputMode `json:"outputMode,omitempty"`
+	Outputs        *[]string            `json:"outputs"`
+	Cache          *bool                `json:"cache,omitempty"`
+	DependsOn      []string             `json:"dependsOn,omitempty"`
+	Inputs         []string             `json:"inputs,omitempty"`
+	OutputMode     *util.TaskOutputMode `json:"outputMode,omitempty"`
+	Env            []string             `json:"env,omitempty"`
+	PassThroughEnv []string             `json:"passThroughEnv,omitempty"`
+	DotEnv         []string             `json:"dotEnv,omitempty"`
+	LoadDotEnv     *bool                `json:"loadDotEnv,omitempty"`
+	Command        *string              `json:"command,omitempty"`
+	Runner         *string              `json:"runner,omitempty"`
+	CleanOutputs   *string              `json:"cleanOutputs,omitempty"`
 }
 
 // Pipeline is a struct for deserializing .pipeline in configFile
@@ -126,6 +202,80 @@ type TaskDefinition struct {
 	TaskDependencies        []string
 	Inputs                  []string
 	OutputMode              util.TaskOutputMode
+	// PassThroughEnv lists patterns of env vars that are available to the task in strict
+	// env mode, but don't affect its hash
+	PassThroughEnv []string
+	// DotEnv lists .env files, relative to the repo root and to the package, whose contents
+	// affect the task's hash
+	DotEnv []string
+	// LoadDotEnv is true if the variables in DotEnv files should be passed to the task
+	LoadDotEnv bool
+	// Command is run with the system shell instead of the package.json script for the task. It
+	// can contain {package}, {dir} and {task} placeholders.
+	Command string
+	// Runner is how the task's package.json script is run. Scripts are run by the package
+	// manager unless this is RunnerShell.
+	Runner string
+	// CleanOutputs is when the files matching Outputs are removed. They're kept unless this is
+	// CleanOutputsRestore or CleanOutputsAlways.
+	CleanOutputs string
+}
+
+// Ways of running package.json scripts
+const (
+	// RunnerPackageManager runs scripts with `<package manager> run <script>`
+	RunnerPackageManager = "package-manager"
+	// RunnerShell runs scripts directly with the system shell, skipping the package manager
+	RunnerShell = "shell"
+)
+
+// TaskRunners are the valid values of a task's runner
+var TaskRunners = []string{RunnerPackageManager, RunnerShell}
+
+// When to remove a task's outputs
+const (
+	// CleanOutputsNever keeps existing outputs, and writes restored or rebuilt outputs over them
+	CleanOutputsNever = "never"
+	// CleanOutputsRestore removes existing outputs before restoring the task from the cache
+	CleanOutputsRestore = "restore"
+	// CleanOutputsAlways removes existing outputs before restoring the task from the cache,
+	// and before running it
+	CleanOutputsAlways = "always"
+)
+
+// CleanOutputsModes are the valid values of a task's cleanOutputs
+var CleanOutputsModes = []string{CleanOutputsNever, CleanOutputsRestore, CleanOutputsAlways}
+
+// CleansOutputsBeforeRestore returns true if the task's outputs are removed before it is
+// restored from the cache
+func (c *TaskDefinition) CleansOutputsBeforeRestore() bool {
+	return c.CleanOutputs == CleanOutputsRestore || c.CleanOutputs == CleanOutputsAlways
+}
+
+// CleansOutputsBeforeRun returns true if the task's outputs are removed before it runs
+func (c *TaskDefinition) CleansOutputsBeforeRun() bool {
+	return c.CleanOutputs == CleanOutputsAlways
+}
+
+// IsAffectedBy reports whether a change to the given file, a unix path relative to the
+// package directory, can affect the result of this task. Tasks without explicit inputs
+// depend on every file in their package.
+func (c *TaskDefinition) IsAffectedBy(pkgRelativeFile string) (bool, error) {
+	if len(c.Inputs) == 0 {
+		return true, nil
+	}
+	for _, input := range c.Inputs {
+		// Inputs are resolved relative to the package directory when hashing, which
+		// also cleans them, so "./src/**" and "src/**" are equivalent.
+		matches, err := doublestar.Match(path.Clean(filepath.ToSlash(input)), pkgRelativeFile)
+		if err != nil {
+			return false, fmt.Errorf("invalid input glob %v: %w", input, err)
+		}
+		if matches {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 const (
@@ -142,33 +292,90 @@ func (c *TaskDefinition) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	*c = defaultTaskDefinition()
+	return c.merge(rawPipeline)
+}
+
+// defaultTaskDefinition returns the definition of a task that doesn't configure anything
+func defaultTaskDefinition() TaskDefinition {
+	return TaskDefinition{
+		Outputs:                 defaultOutputs,
+		ShouldCache:             true,
+		EnvVarDependencies:      []string{},
+		TopologicalDependencies: []string{},
+		TaskDependencies:        []string{},
+	}
+}
+
+// merge applies the keys that are set in rawPipeline to this TaskDefinition. Keys that list
+// env vars or .env files add to the existing lists, and any other key replaces the current value.
+func (c *TaskDefinition) merge(rawPipeline *pipelineJSON) error {
+	// Copy the lists that are added to, so that they don't share storage with the definition
+	// this one is based on
+	c.EnvVarDependencies = append([]string{}, c.EnvVarDependencies...)
+	c.PassThroughEnv = append([]string(nil), c.PassThroughEnv...)
+	c.DotEnv = append([]string(nil), c.DotEnv...)
+
 	// We actually need a nil value to be able to unmarshal the json
 	// because we interpret the omission of outputs to be different
 	// from an empty array. We can't use omitempty because it will
 	// always unmarshal into an empty array which is not what we want.
 	if rawPipeline.Outputs != nil {
 		c.Outputs = *rawPipeline.Outputs
-	} else {
-		c.Outputs = defaultOutputs
 	}
-	if rawPipeline.Cache == nil {
-		c.ShouldCache = true
-	} else {
+	if rawPipeline.Cache != nil {
 		c.ShouldCache = *rawPipeline.Cache
 	}
-	c.EnvVarDependencies = []string{}
-	c.TopologicalDependencies = []string{}
-	c.TaskDependencies = []string{}
-	for _, dependency := range rawPipeline.DependsOn {
-		if strings.HasPrefix(dependency, envPipelineDelimiter) {
-			c.EnvVarDependencies = append(c.EnvVarDependencies, strings.TrimPrefix(dependency, envPipelineDelimiter))
-		} else if strings.HasPrefix(dependency, topologicalPipelineDelimiter) {
-			c.TopologicalDependencies = append(c.TopologicalDependencies, strings.TrimPrefix(dependency, topologicalPipelineDelimiter))
-		} else {
-			c.TaskDependencies = append(c.TaskDependencies, dependency)
+	if rawPipeline.DependsOn != nil {
+		c.TopologicalDependencies = []string{}
+		c.TaskDependencies = []string{}
+		for _, dependency := range rawPipeline.DependsOn {
+			if strings.HasPrefix(dependency, envPipelineDelimiter) {
+				c.EnvVarDependencies = append(c.EnvVarDependencies, strings.TrimPrefix(dependency, envPipelineDelimiter))
+			} else if strings.HasPrefix(dependency, topologicalPipelineDelimiter) {
+				c.TopologicalDependencies = append(c.TopologicalDependencies, strings.TrimPrefix(dependency, topologicalPipelineDelimiter))
+			} else {
+				c.TaskDependencies = append(c.TaskDependencies, dependency)
+			}
+		}
+	}
+	if err := env.ValidatePatterns(rawPipeline.Env); err != nil {
+		return fmt.Errorf("env: %w", err)
+	}
+	c.EnvVarDependencies = append(c.EnvVarDependencies, rawPipeline.Env...)
+	if err := env.ValidatePatterns(rawPipeline.PassThroughEnv); err != nil {
+		return fmt.Errorf("passThroughEnv: %w", err)
+	}
+	c.PassThroughEnv = append(c.PassThroughEnv, rawPipeline.PassThroughEnv...)
+	for _, dotEnvFile := range rawPipeline.DotEnv {
+		if dotEnvFile == "" || filepath.IsAbs(dotEnvFile) {
+			return fmt.Errorf("dotEnv: invalid file %q: expected a path relative to the package", dotEnvFile)
+		}
+	}
+	c.DotEnv = append(c.DotEnv, rawPipeline.DotEnv...)
+	if rawPipeline.LoadDotEnv != nil {
+		c.LoadDotEnv = *rawPipeline.LoadDotEnv
+	}
+	if rawPipeline.Inputs != nil {
+		c.Inputs = rawPipeline.Inputs
+	}
+	if rawPipeline.OutputMode != nil {
+		c.OutputMode = *rawPipeline.OutputMode
+	}
+	if rawPipeline.Command != nil {
+		c.Command = *rawPipeline.Command
+	}
+	if rawPipeline.Runner != nil {
+		if *rawPipeline.Runner != RunnerPackageManager && *rawPipeline.Runner != RunnerShell {
+			return fmt.Errorf("runner: invalid value %q. Expected %v or %v", *rawPipeline.Runner, RunnerPackageManager, RunnerShell)
+		}
+		c.Runner = *rawPipeline.Runner
+	}
+	if rawPipeline.CleanOutputs != nil {
+		if !util.SetFromStrings(CleanOutputsModes).Includes(*rawPipeline.CleanOutputs) {
+			return fmt.Errorf("cleanOutputs: invalid value %q. Expected one of %v", *rawPipeline.CleanOutputs, strings.Join(CleanOutputsModes, ", "))
 		}
+		c.CleanOutputs = *rawPipeline.CleanOutputs
 	}
-	c.Inputs = rawPipeline.Inputs
-	c.OutputMode = rawPipeline.OutputMode
 	return nil
 }
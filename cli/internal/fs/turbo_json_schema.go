@@ -0,0 +1,747 @@
+package fs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vercel/turborepo/cli/internal/env"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// ConfigError is a problem at a particular position in a configuration file
+type ConfigError struct {
+	File   string
+	Line   int
+	Column int
+	// Path is the location of the problem in the document, such as pipeline.build.outputs
+	Path    string
+	Message string
+	// unknownKey is whether the problem is a key that the schema doesn't have. These keys
+	// are ignored when the file is read, so they don't stop turbo from running.
+	unknownKey bool
+}
+
+func (e *ConfigError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%v:%v:%v: %v", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%v:%v:%v: %v: %v", e.File, e.Line, e.Column, e.Path, e.Message)
+}
+
+// ConfigErrors is a list of problems found in configuration files
+type ConfigErrors []*ConfigError
+
+func (errs ConfigErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// warnUnknownKeys logs the unknown keys in the ConfigErrors returned by validating the root
+// turbo.json as warnings, and returns an error with any other problems. Reading it to run
+// tasks only fails for values turbo can't use, so that keys from older or newer versions of
+// turbo don't stop a repo from running. `turbo config validate` reports every problem.
+func warnUnknownKeys(err error) error {
+	configErrors, ok := err.(ConfigErrors)
+	if !ok {
+		return err
+	}
+	var problems ConfigErrors
+	for _, configErr := range configErrors {
+		if configErr.unknownKey {
+			log.Printf("[WARNING] %v", configErr)
+		} else {
+			problems = append(problems, configErr)
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// TaskReferences are the tasks and packages that a turbo.json can refer to
+type TaskReferences struct {
+	// Pipeline is the complete pipeline, including the tasks defined by packages
+	Pipeline Pipeline
+	// PackageNames are the names of the workspace packages
+	PackageNames util.Set
+}
+
+// ValidateTurboJSON checks the contents of the root turbo.json against its schema, returning
+// ConfigErrors describing any problems. If refs is not nil, it also checks that the tasks
+// and packages it refers to exist.
+func ValidateTurboJSON(file string, data []byte, refs *TaskReferences) error {
+	return validateConfig(file, data, _turboJSONSchema, refs)
+}
+
+// ValidatePackageTurboJSON checks the contents of a workspace package's turbo.json, in the same
+// way as ValidateTurboJSON
+func ValidatePackageTurboJSON(file string, data []byte, refs *TaskReferences) error {
+	return validateConfig(file, data, _packageTurboJSONSchema, refs)
+}
+
+// ValidatePresetTurboJSON checks the contents of a preset that a turbo.json extends, in the
+// same way as ValidateTurboJSON
+func ValidatePresetTurboJSON(file string, data []byte, refs *TaskReferences) error {
+	return validateConfig(file, data, _presetTurboJSONSchema, refs)
+}
+
+// schemaNode describes the values that are allowed at a position in a configuration file
+type schemaNode struct {
+	kind jsonKind
+	// fields are the schemas of the keys that an object can have
+	fields map[string]*schemaNode
+	// unknownField returns the error for a key that isn't in fields
+	unknownField func(key string) string
+	// values is the schema of every value in an object with arbitrary keys
+	values *schemaNode
+	// checkKey validates the keys of an object with arbitrary keys
+	checkKey func(key string) error
+	// items is the schema of the items of an array
+	items *schemaNode
+	// enum lists the values a string can take
+	enum []string
+	// check validates a value that has the correct type
+	check func(node *jsonNode) error
+}
+
+var _stringSchema = &schemaNode{kind: jsonString}
+var _boolSchema = &schemaNode{kind: jsonBool}
+var _stringsSchema = &schemaNode{kind: jsonArray, items: _stringSchema}
+var _envPatternsSchema = &schemaNode{kind: jsonArray, items: &schemaNode{
+	kind: jsonString,
+	check: func(node *jsonNode) error {
+		return env.ValidatePatterns([]string{node.str})
+	},
+}}
+
+var _taskSchema = &schemaNode{
+	kind: jsonObject,
+	fields: map[string]*schemaNode{
+		"outputs":        _stringsSchema,
+		"cache":          _boolSchema,
+		"dependsOn":      _stringsSchema,
+		"inputs":         _stringsSchema,
+		"outputMode":     {kind: jsonString, enum: util.TaskOutputModeStrings},
+		"env":            _envPatternsSchema,
+		"passThroughEnv": _envPatternsSchema,
+		"dotEnv":         _stringsSchema,
+		"loadDotEnv":     _boolSchema,
+		"command":        _stringSchema,
+		"runner":         {kind: jsonString, enum: TaskRunners},
+		"cleanOutputs":   {kind: jsonString, enum: CleanOutputsModes},
+	},
+}
+
+var _extendsPresetsSchema = &schemaNode{kind: jsonArray, items: &schemaNode{
+	kind: jsonString,
+	check: func(node *jsonNode) error {
+		if node.str == _rootConfigReference {
+			return fmt.Errorf("%q can only be extended by a package's turbo.json", _rootConfigReference)
+		}
+		return nil
+	},
+}}
+
+var _cacheTiersSchema = &schemaNode{
+	kind: jsonArray,
+	items: &schemaNode{
+		kind: jsonObject,
+		fields: map[string]*schemaNode{
+			"type":   {kind: jsonString, enum: CacheTierTypes},
+			"dir":    _stringSchema,
+			"policy": {kind: jsonString, enum: CacheTierPolicies},
+		},
+		check: func(node *jsonNode) error {
+			tierType := node.get("type")
+			if tierType == nil {
+				return errors.New("a cache tier must have a \"type\"")
+			}
+			if tierType.str == CacheTierRemote && node.get("dir") != nil {
+				return fmt.Errorf("\"dir\" can only be set for %q cache tiers", CacheTierLocal)
+			}
+			return nil
+		},
+	},
+	check: func(node *jsonNode) error {
+		remoteTiers := 0
+		for _, item := range node.items {
+			if tierType := item.get("type"); tierType != nil && tierType.str == CacheTierRemote {
+				remoteTiers++
+			}
+		}
+		if remoteTiers > 1 {
+			return fmt.Errorf("there can only be one %q cache tier", CacheTierRemote)
+		}
+		return nil
+	},
+}
+
+var _turboJSONSchema = &schemaNode{
+	kind: jsonObject,
+	fields: map[string]*schemaNode{
+		"$schema":            _stringSchema,
+		"extends":            _extendsPresetsSchema,
+		"globalDependencies": _stringsSchema,
+		"globalEnv":          _envPatternsSchema,
+		"pipeline":           {kind: jsonObject, values: _taskSchema},
+		"remoteCache": {kind: jsonObject, fields: map[string]*schemaNode{
+			"teamId":    _stringSchema,
+			"signature": _boolSchema,
+			"publicKey": {kind: jsonString, check: func(node *jsonNode) error {
+				_, err := ParseRemoteCachePublicKey(node.str)
+				return err
+			}},
+		}},
+		"cacheTiers": _cacheTiersSchema,
+	},
+}
+
+var _packageTurboJSONSchema = &schemaNode{
+	kind: jsonObject,
+	fields: map[string]*schemaNode{
+		"$schema": _stringSchema,
+		"extends": {kind: jsonArray, items: _stringSchema, check: func(node *jsonNode) error {
+			if len(node.items) != 1 || node.items[0].str != _rootConfigReference {
+				return fmt.Errorf("a package's turbo.json can only extend the root turbo.json, using [%q]", _rootConfigReference)
+			}
+			return nil
+		}},
+		"pipeline": {kind: jsonObject, values: _taskSchema, checkKey: func(task string) error {
+			if util.IsPackageTask(task) {
+				_, taskName := util.GetPackageTaskFromId(task)
+				return fmt.Errorf("tasks in a package's turbo.json apply to that package, use %q instead", taskName)
+			}
+			return nil
+		}},
+	},
+	unknownField: func(key string) string {
+		if _, ok := _turboJSONSchema.fields[key]; ok {
+			return fmt.Sprintf("%q can only be set in the root turbo.json", key)
+		}
+		return fmt.Sprintf("unknown key %q. A package's turbo.json can only set \"extends\" and \"pipeline\"", key)
+	},
+}
+
+var _presetFields = map[string]*schemaNode{
+	"$schema":            _stringSchema,
+	"extends":            _extendsPresetsSchema,
+	"globalDependencies": _stringsSchema,
+	"globalEnv":          _envPatternsSchema,
+	"pipeline":           {kind: jsonObject, values: _taskSchema},
+}
+
+var _presetTurboJSONSchema = &schemaNode{
+	kind:   jsonObject,
+	fields: _presetFields,
+	unknownField: func(key string) string {
+		if key == "remoteCache" || key == "cacheTiers" {
+			return fmt.Sprintf("%q can only be set in the root turbo.json, not in a preset", key)
+		}
+		return unknownKeyMessage(key, _presetFields)
+	},
+}
+
+// configValidator collects the problems found in a configuration file
+type configValidator struct {
+	file   string
+	data   []byte
+	errors ConfigErrors
+}
+
+func validateConfig(file string, data []byte, schema *schemaNode, refs *TaskReferences) error {
+	v := &configValidator{file: file, data: data}
+	root, err := parseJSONC(data)
+	if err != nil {
+		syntaxErr := err.(*jsonSyntaxError)
+		v.report(syntaxErr.offset, "", syntaxErr.message)
+		return v.errors
+	}
+	v.validate(root, schema, "")
+	if len(v.errors) == 0 && refs != nil {
+		v.validateReferences(root, refs)
+	}
+	if len(v.errors) > 0 {
+		return v.errors
+	}
+	return nil
+}
+
+func (v *configValidator) report(offset int, path string, message string) {
+	v.errors = append(v.errors, v.newError(offset, path, message))
+}
+
+func (v *configValidator) reportUnknownKey(offset int, path string, message string) {
+	configErr := v.newError(offset, path, message)
+	configErr.unknownKey = true
+	v.errors = append(v.errors, configErr)
+}
+
+func (v *configValidator) newError(offset int, path string, message string) *ConfigError {
+	line, column := lineAndColumn(v.data, offset)
+	return &ConfigError{
+		File:    v.file,
+		Line:    line,
+		Column:  column,
+		Path:    path,
+		Message: message,
+	}
+}
+
+func (v *configValidator) validate(node *jsonNode, schema *schemaNode, path string) {
+	if node.kind != schema.kind {
+		v.report(node.offset, path, fmt.Sprintf("expected %v, got %v", schema.kind, node.kind))
+		return
+	}
+	switch node.kind {
+	case jsonObject:
+		for _, member := range node.members {
+			memberPath := joinConfigPath(path, member.key)
+			if schema.values != nil {
+				if schema.checkKey != nil {
+					if err := schema.checkKey(member.key); err != nil {
+						v.report(member.keyOffset, memberPath, err.Error())
+						continue
+					}
+				}
+				v.validate(member.value, schema.values, memberPath)
+			} else if fieldSchema, ok := schema.fields[member.key]; ok {
+				v.validate(member.value, fieldSchema, memberPath)
+			} else if schema.unknownField != nil {
+				v.reportUnknownKey(member.keyOffset, path, schema.unknownField(member.key))
+			} else {
+				v.reportUnknownKey(member.keyOffset, path, unknownKeyMessage(member.key, schema.fields))
+			}
+		}
+	case jsonArray:
+		for i, item := range node.items {
+			v.validate(item, schema.items, fmt.Sprintf("%v[%v]", path, i))
+		}
+	case jsonString:
+		if len(schema.enum) > 0 && !containsString(schema.enum, node.str) {
+			v.report(node.offset, path, fmt.Sprintf("invalid value %q. Expected one of: %v", node.str, strings.Join(schema.enum, ", ")))
+			return
+		}
+	}
+	if schema.check != nil {
+		if err := schema.check(node); err != nil {
+			v.report(node.offset, path, err.Error())
+		}
+	}
+}
+
+// validateReferences checks that the tasks in dependsOn and the packages in pkg#task entries
+// exist. It must only be called on documents that match their schema.
+func (v *configValidator) validateReferences(root *jsonNode, refs *TaskReferences) {
+	pipeline := root.get("pipeline")
+	if pipeline == nil {
+		return
+	}
+	for _, task := range pipeline.members {
+		taskPath := joinConfigPath("pipeline", task.key)
+		if util.IsPackageTask(task.key) {
+			if pkg, _ := util.GetPackageTaskFromId(task.key); !refs.hasPackage(pkg) {
+				v.report(task.keyOffset, taskPath, fmt.Sprintf("package %q doesn't exist", pkg))
+			}
+		}
+		dependsOn := task.value.get("dependsOn")
+		if dependsOn == nil {
+			continue
+		}
+		for i, dependency := range dependsOn.items {
+			dependencyPath := fmt.Sprintf("%v.dependsOn[%v]", taskPath, i)
+			name := dependency.str
+			if strings.HasPrefix(name, envPipelineDelimiter) {
+				continue
+			}
+			name = strings.TrimPrefix(name, topologicalPipelineDelimiter)
+			if util.IsPackageTask(name) {
+				pkg, _ := util.GetPackageTaskFromId(name)
+				if !refs.hasPackage(pkg) {
+					v.report(dependency.offset, dependencyPath, fmt.Sprintf("package %q doesn't exist", pkg))
+				} else if _, ok := refs.Pipeline.GetTaskDefinition(name); !ok {
+					v.report(dependency.offset, dependencyPath, fmt.Sprintf("task %q isn't defined in the pipeline", name))
+				}
+			} else if !refs.Pipeline.HasTask(name) {
+				v.report(dependency.offset, dependencyPath, fmt.Sprintf("task %q isn't defined in the pipeline", name))
+			}
+		}
+	}
+}
+
+func (refs *TaskReferences) hasPackage(pkg string) bool {
+	return pkg == util.RootPkgName || refs.PackageNames.Includes(pkg)
+}
+
+// unknownKeyMessage describes a key that isn't one of fields, suggesting the field it's
+// closest to
+func unknownKeyMessage(key string, fields map[string]*schemaNode) string {
+	message := fmt.Sprintf("unknown key %q", key)
+	if suggestion := closestKey(key, fields); suggestion != "" {
+		message += fmt.Sprintf(". Did you mean %q?", suggestion)
+	}
+	return message
+}
+
+func joinConfigPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// closestKey returns the key in fields that key is most likely a misspelling of, if any
+func closestKey(key string, fields map[string]*schemaNode) string {
+	candidates := make([]string, 0, len(fields))
+	for field := range fields {
+		candidates = append(candidates, field)
+	}
+	sort.Strings(candidates)
+	closest := ""
+	closestDistance := 3
+	for _, candidate := range candidates {
+		if distance := editDistance(strings.ToLower(key), strings.ToLower(candidate)); distance < closestDistance {
+			closest = candidate
+			closestDistance = distance
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// lineAndColumn converts a byte offset in data to a 1-based line and column
+func lineAndColumn(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line := 1 + strings.Count(string(data[:offset]), "\n")
+	lineStart := strings.LastIndex(string(data[:offset]), "\n") + 1
+	return line, utf8.RuneCount(data[lineStart:offset]) + 1
+}
+
+type jsonKind int
+
+const (
+	jsonObject jsonKind = iota
+	jsonArray
+	jsonString
+	jsonNumber
+	jsonBool
+	jsonNull
+)
+
+func (k jsonKind) String() string {
+	switch k {
+	case jsonObject:
+		return "an object"
+	case jsonArray:
+		return "an array"
+	case jsonString:
+		return "a string"
+	case jsonNumber:
+		return "a number"
+	case jsonBool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
+
+// jsonNode is a value in a JSON document, along with its offset in the document
+type jsonNode struct {
+	offset int
+	kind   jsonKind
+	// members are the keys and values of an object, in the order they appear
+	members []jsonMember
+	// items are the values of an array
+	items []*jsonNode
+	// str is the value of a string
+	str string
+}
+
+type jsonMember struct {
+	key       string
+	keyOffset int
+	value     *jsonNode
+}
+
+// get returns the value of the given key in an object, or nil
+func (n *jsonNode) get(key string) *jsonNode {
+	for _, member := range n.members {
+		if member.key == key {
+			return member.value
+		}
+	}
+	return nil
+}
+
+type jsonSyntaxError struct {
+	offset  int
+	message string
+}
+
+func (e *jsonSyntaxError) Error() string {
+	return e.message
+}
+
+// jsoncParser parses JSON with comments, keeping track of where each value is. Unlike
+// jsonc.ToJSON, it keeps the offsets of the original document.
+type jsoncParser struct {
+	data []byte
+	pos  int
+}
+
+func parseJSONC(data []byte) (*jsonNode, error) {
+	p := &jsoncParser{data: stripJSONComments(data)}
+	p.skipWhitespace()
+	node, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if p.pos < len(p.data) {
+		return nil, p.unexpected("end of file")
+	}
+	return node, nil
+}
+
+// stripJSONComments replaces comments with spaces, keeping line breaks so that offsets
+// in the result are the same as in data
+func stripJSONComments(data []byte) []byte {
+	stripped := make([]byte, len(data))
+	copy(stripped, data)
+	inString := false
+	for i := 0; i < len(stripped); i++ {
+		ch := stripped[i]
+		if inString {
+			if ch == '\\' {
+				i++
+			} else if ch == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case ch == '"':
+			inString = true
+		case ch == '#' || (ch == '/' && i+1 < len(stripped) && stripped[i+1] == '/'):
+			for ; i < len(stripped) && stripped[i] != '\n'; i++ {
+				stripped[i] = ' '
+			}
+		case ch == '/' && i+1 < len(stripped) && stripped[i+1] == '*':
+			end := strings.Index(string(stripped[i+2:]), "*/")
+			if end == -1 {
+				// Leave unterminated comments for the parser to report
+				return stripped
+			}
+			for j := i; j < i+2+end+2; j++ {
+				if stripped[j] != '\n' {
+					stripped[j] = ' '
+				}
+			}
+			i += 2 + end + 1
+		}
+	}
+	return stripped
+}
+
+func (p *jsoncParser) skipWhitespace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsoncParser) unexpected(expected string) error {
+	if p.pos >= len(p.data) {
+		return &jsonSyntaxError{offset: p.pos, message: fmt.Sprintf("unexpected end of file, expected %v", expected)}
+	}
+	r, _ := utf8.DecodeRune(p.data[p.pos:])
+	return &jsonSyntaxError{offset: p.pos, message: fmt.Sprintf("unexpected %q, expected %v", r, expected)}
+}
+
+func (p *jsoncParser) parseValue() (*jsonNode, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.unexpected("a value")
+	}
+	switch ch := p.data[p.pos]; {
+	case ch == '{':
+		return p.parseObject()
+	case ch == '[':
+		return p.parseArray()
+	case ch == '"':
+		offset := p.pos
+		str, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &jsonNode{offset: offset, kind: jsonString, str: str}, nil
+	case ch == 't' || ch == 'f' || ch == 'n':
+		for _, literal := range []string{"true", "false", "null"} {
+			if strings.HasPrefix(string(p.data[p.pos:]), literal) {
+				kind := jsonBool
+				if literal == "null" {
+					kind = jsonNull
+				}
+				node := &jsonNode{offset: p.pos, kind: kind}
+				p.pos += len(literal)
+				return node, nil
+			}
+		}
+	case ch == '-' || (ch >= '0' && ch <= '9'):
+		offset := p.pos
+		for p.pos < len(p.data) && strings.IndexByte("+-.0123456789eE", p.data[p.pos]) != -1 {
+			p.pos++
+		}
+		if _, err := strconv.ParseFloat(string(p.data[offset:p.pos]), 64); err != nil {
+			return nil, &jsonSyntaxError{offset: offset, message: fmt.Sprintf("invalid number %v", string(p.data[offset:p.pos]))}
+		}
+		return &jsonNode{offset: offset, kind: jsonNumber}, nil
+	}
+	return nil, p.unexpected("a value")
+}
+
+func (p *jsoncParser) parseString() (string, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.data); p.pos++ {
+		switch p.data[p.pos] {
+		case '\\':
+			p.pos++
+		case '\n':
+			return "", &jsonSyntaxError{offset: start, message: "unterminated string"}
+		case '"':
+			p.pos++
+			var str string
+			if err := json.Unmarshal(p.data[start:p.pos], &str); err != nil {
+				return "", &jsonSyntaxError{offset: start, message: "invalid string"}
+			}
+			return str, nil
+		}
+	}
+	return "", &jsonSyntaxError{offset: start, message: "unterminated string"}
+}
+
+func (p *jsoncParser) parseObject() (*jsonNode, error) {
+	node := &jsonNode{offset: p.pos, kind: jsonObject}
+	p.pos++
+	p.skipWhitespace()
+	if p.pos < len(p.data) && p.data[p.pos] == '}' {
+		p.pos++
+		return node, nil
+	}
+	for {
+		if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+			return nil, p.unexpected("a key")
+		}
+		keyOffset := p.pos
+		key, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		p.skipWhitespace()
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return nil, p.unexpected("':'")
+		}
+		p.pos++
+		p.skipWhitespace()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if node.get(key) != nil {
+			return nil, &jsonSyntaxError{offset: keyOffset, message: fmt.Sprintf("duplicate key %q", key)}
+		}
+		node.members = append(node.members, jsonMember{key: key, keyOffset: keyOffset, value: value})
+		p.skipWhitespace()
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+			p.skipWhitespace()
+			continue
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == '}' {
+			p.pos++
+			return node, nil
+		}
+		return nil, p.unexpected("',' or '}'")
+	}
+}
+
+func (p *jsoncParser) parseArray() (*jsonNode, error) {
+	node := &jsonNode{offset: p.pos, kind: jsonArray}
+	p.pos++
+	p.skipWhitespace()
+	if p.pos < len(p.data) && p.data[p.pos] == ']' {
+		p.pos++
+		return node, nil
+	}
+	for {
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		node.items = append(node.items, item)
+		p.skipWhitespace()
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+			p.skipWhitespace()
+			continue
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return node, nil
+		}
+		return nil, p.unexpected("',' or ']'")
+	}
+}
@@ -0,0 +1,167 @@
+package fs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+func assertConfigErrors(t *testing.T, err error, expected []string) {
+	t.Helper()
+	if len(expected) == 0 {
+		if err != nil {
+			t.Fatalf("expected no problems, got:\n%v", err)
+		}
+		return
+	}
+	configErrors, ok := err.(ConfigErrors)
+	if !ok {
+		t.Fatalf("expected ConfigErrors, got %v", err)
+	}
+	got := make([]string, len(configErrors))
+	for i, configErr := range configErrors {
+		got[i] = configErr.Error()
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got problems:\n%v\nwant:\n%v", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestValidateTurboJSON(t *testing.T) {
+	data := `{
+  // comments don't affect positions
+  "$schema": "https://turborepo.org/schema.json",
+  "pipeline": {
+    /* nor do block
+       comments */ "build": {"dependOn": ["^build"], "outputs": "dist/**"},
+    "test": {"outputMode": "quiet", "cache": false},
+    "lint": {"env": ["$API_KEY"]}
+  },
+  "globalDependencies": [".env", 1],
+  "remoteCache": {"signature": true, "team": "acme", "publicKey": "c2hvcnQ="},
+  "baseBranch": "origin/main"
+}`
+	err := ValidateTurboJSON("turbo.json", []byte(data), nil)
+	assertConfigErrors(t, err, []string{
+		`turbo.json:6:30: pipeline.build: unknown key "dependOn". Did you mean "dependsOn"?`,
+		`turbo.json:6:65: pipeline.build.outputs: expected an array, got a string`,
+		`turbo.json:7:28: pipeline.test.outputMode: invalid value "quiet". Expected one of: full, none, hash-only, new-only`,
+		`turbo.json:8:22: pipeline.lint.env[0]: invalid env var pattern "$API_KEY": variable names should not be prefixed with $`,
+		`turbo.json:10:34: globalDependencies[1]: expected a string, got a number`,
+		`turbo.json:11:38: remoteCache: unknown key "team". Did you mean "teamId"?`,
+		`turbo.json:11:67: remoteCache.publicKey: invalid public key: expected a 32 byte Ed25519 key, got 5 bytes`,
+		`turbo.json:12:3: unknown key "baseBranch"`,
+	})
+}
+
+func TestValidateTurboJSON_CacheTiers(t *testing.T) {
+	data := `{
+  "pipeline": {},
+  "cacheTiers": [
+    {"type": "local"},
+    {"type": "local", "dir": "/mnt/turbo", "policy": "read"},
+    {"type": "remote", "dir": "remote"},
+    {"policy": "write-only"},
+    {"type": "remote", "policy": "read-only"}
+  ]
+}`
+	err := ValidateTurboJSON("turbo.json", []byte(data), nil)
+	assertConfigErrors(t, err, []string{
+		`turbo.json:5:54: cacheTiers[1].policy: invalid value "read". Expected one of: read-write, read-only, write-only`,
+		`turbo.json:6:5: cacheTiers[2]: "dir" can only be set for "local" cache tiers`,
+		`turbo.json:7:5: cacheTiers[3]: a cache tier must have a "type"`,
+		`turbo.json:3:17: cacheTiers: there can only be one "remote" cache tier`,
+	})
+}
+
+func TestValidateTurboJSON_SyntaxErrors(t *testing.T) {
+	testCases := []struct {
+		data     string
+		expected string
+	}{
+		{"{\n  \"pipeline\": {\n    \"build\": {},\n  }\n}", `turbo.json:4:3: unexpected '}', expected a key`},
+		{"{\"pipeline\": {\"build\": {}}", `turbo.json:1:27: unexpected end of file, expected ',' or '}'`},
+		{"{\"pipeline\": {}, \"pipeline\": {}}", `turbo.json:1:18: duplicate key "pipeline"`},
+		{"{\"pipeline\": tru}", `turbo.json:1:14: unexpected 't', expected a value`},
+		{"/* unterminated {}", `turbo.json:1:1: unexpected '/', expected a value`},
+	}
+	for _, tc := range testCases {
+		err := ValidateTurboJSON("turbo.json", []byte(tc.data), nil)
+		assertConfigErrors(t, err, []string{tc.expected})
+	}
+}
+
+func TestValidateTurboJSON_References(t *testing.T) {
+	data := `{
+  "pipeline": {
+    "build": {"dependsOn": ["^build", "$NODE_ENV"]},
+    "test": {"dependsOn": ["build", "lint", "web#build", "docs#build", "//#check"]},
+    "web#deploy": {"dependsOn": ["web#build"]},
+    "api#deploy": {},
+    "//#check": {}
+  }
+}`
+	refs := &TaskReferences{
+		Pipeline: Pipeline{
+			"build":      defaultTaskDefinition(),
+			"test":       defaultTaskDefinition(),
+			"web#deploy": defaultTaskDefinition(),
+			"api#deploy": defaultTaskDefinition(),
+			"//#check":   defaultTaskDefinition(),
+		},
+		PackageNames: make(util.Set),
+	}
+	refs.PackageNames.Add("web")
+	refs.PackageNames.Add("docs")
+	err := ValidateTurboJSON("turbo.json", []byte(data), refs)
+	assertConfigErrors(t, err, []string{
+		`turbo.json:4:37: pipeline.test.dependsOn[1]: task "lint" isn't defined in the pipeline`,
+		`turbo.json:6:5: pipeline.api#deploy: package "api" doesn't exist`,
+	})
+
+	// Structural problems are reported without checking references
+	err = ValidateTurboJSON("turbo.json", []byte(`{"pipeline": {"test": {"dependsOn": ["lint"], "cache": 1}}}`), refs)
+	assertConfigErrors(t, err, []string{
+		`turbo.json:1:56: pipeline.test.cache: expected a boolean, got a number`,
+	})
+}
+
+func TestValidatePackageTurboJSON(t *testing.T) {
+	data := `{
+  "extends": ["//"],
+  "globalEnv": ["CI"],
+  "pipeline": {
+    "web#build": {},
+    "test": {"dependsOn": ["build", "deploy"]}
+  }
+}`
+	refs := &TaskReferences{
+		Pipeline:     Pipeline{"build": defaultTaskDefinition(), "web#test": defaultTaskDefinition()},
+		PackageNames: make(util.Set),
+	}
+	err := ValidatePackageTurboJSON("apps/web/turbo.json", []byte(data), nil)
+	assertConfigErrors(t, err, []string{
+		`apps/web/turbo.json:3:3: "globalEnv" can only be set in the root turbo.json`,
+		`apps/web/turbo.json:5:5: pipeline.web#build: tasks in a package's turbo.json apply to that package, use "build" instead`,
+	})
+	err = ValidatePackageTurboJSON("apps/web/turbo.json", []byte(`{"pipeline": {"test": {"dependsOn": ["build", "deploy"]}}}`), refs)
+	assertConfigErrors(t, err, []string{
+		`apps/web/turbo.json:1:47: pipeline.test.dependsOn[1]: task "deploy" isn't defined in the pipeline`,
+	})
+}
+
+func TestValidatePresetTurboJSON(t *testing.T) {
+	data := `{
+  "extends": ["./base.json", "//"],
+  "pipeline": {"build": {"outputs": ["dist/**"]}},
+  "remoteCache": {"signature": true},
+  "globalEnvs": ["CI"]
+}`
+	err := ValidatePresetTurboJSON("presets/turbo.json", []byte(data), nil)
+	assertConfigErrors(t, err, []string{
+		`presets/turbo.json:2:30: extends[1]: "//" can only be extended by a package's turbo.json`,
+		`presets/turbo.json:4:3: "remoteCache" can only be set in the root turbo.json, not in a preset`,
+		`presets/turbo.json:5:3: unknown key "globalEnvs". Did you mean "globalEnv"?`,
+	})
+}
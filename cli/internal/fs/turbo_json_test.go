@@ -1,6 +1,9 @@
 package fs
 
 import (
+	"bytes"
+	"encoding/json"
+	"log"
 	"os"
 	"strings"
 	"testing"
@@ -46,7 +49,8 @@ func Test_ReadTurboConfig(t *testing.T) {
 		"lint": {
 			Outputs:                 []string{},
 			TopologicalDependencies: []string{},
-			EnvVarDependencies:      []string{"MY_VAR"},
+			EnvVarDependencies:      []string{"MY_VAR", "API_*", "!API_SECRET"},
+			PassThroughEnv:          []string{"AWS_*"},
 			TaskDependencies:        []string{},
 			ShouldCache:             true,
 			OutputMode:              util.NewTaskOutput,
@@ -70,7 +74,7 @@ func Test_ReadTurboConfig(t *testing.T) {
 		},
 	}
 
-	remoteCacheOptionsExpected := RemoteCacheOptions{"team_id", true}
+	remoteCacheOptionsExpected := RemoteCacheOptions{TeamID: "team_id", Signature: true}
 	if len(turboJSON.Pipeline) != len(pipelineExpected) {
 		expectedKeys := []string{}
 		for k := range pipelineExpected {
@@ -90,4 +94,100 @@ func Test_ReadTurboConfig(t *testing.T) {
 		assert.EqualValuesf(t, expectedTaskDefinition, actualTaskDefinition, "task definition mismatch for %v", taskName)
 	}
 	assert.EqualValues(t, remoteCacheOptionsExpected, turboJSON.RemoteCacheOptions)
+	assert.EqualValues(t, []string{"CI_*"}, turboJSON.GlobalEnv)
+}
+
+func Test_ReadTurboConfig_UnknownKeys(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	repoRoot := AbsolutePath(t.TempDir())
+	data := `{
+  "baseBranch": "origin/main",
+  "pipeline": {"build": {"outputs": ["dist/**"], "experimental": true}}
+}`
+	if err := repoRoot.Join(configFile).WriteFile([]byte(data), 0644); err != nil {
+		t.Fatalf("failed to write turbo.json: %v", err)
+	}
+	turboJSON, err := ReadTurboConfig(repoRoot, &PackageJSON{})
+	if err != nil {
+		t.Fatalf("expected a turbo.json with unknown keys to load, got %v", err)
+	}
+	assert.Equal(t, []string{"dist/**"}, turboJSON.Pipeline["build"].Outputs)
+	assert.Contains(t, logs.String(), `[WARNING] turbo.json:2:3: unknown key "baseBranch"`)
+	assert.Contains(t, logs.String(), `[WARNING] turbo.json:3:50: pipeline.build: unknown key "experimental"`)
+
+	// Values turbo can't use still stop it from running
+	if err := repoRoot.Join(configFile).WriteFile([]byte(`{"baseBranch": "main", "pipeline": {"build": {"outputs": "dist/**"}}}`), 0644); err != nil {
+		t.Fatalf("failed to write turbo.json: %v", err)
+	}
+	_, err = ReadTurboConfig(repoRoot, &PackageJSON{})
+	assert.EqualError(t, err, `turbo.json:1:58: pipeline.build.outputs: expected an array, got a string`)
+}
+
+func TestTaskDefinition_InvalidEnv(t *testing.T) {
+	testCases := []string{
+		`{"env": ["$API_KEY"]}`,
+		`{"passThroughEnv": [""]}`,
+		`{"dotEnv": ["/etc/.env"]}`,
+	}
+	for _, tc := range testCases {
+		var taskDefinition TaskDefinition
+		if err := json.Unmarshal([]byte(tc), &taskDefinition); err == nil {
+			t.Errorf("expected an error parsing %v", tc)
+		}
+	}
+}
+
+func TestTaskDefinition_CleanOutputs(t *testing.T) {
+	testCases := []struct {
+		data          string
+		beforeRestore bool
+		beforeRun     bool
+	}{
+		{`{}`, false, false},
+		{`{"cleanOutputs": "never"}`, false, false},
+		{`{"cleanOutputs": "restore"}`, true, false},
+		{`{"cleanOutputs": "always"}`, true, true},
+	}
+	for _, tc := range testCases {
+		var taskDefinition TaskDefinition
+		if err := json.Unmarshal([]byte(tc.data), &taskDefinition); err != nil {
+			t.Fatalf("failed to parse %v: %v", tc.data, err)
+		}
+		assert.Equal(t, tc.beforeRestore, taskDefinition.CleansOutputsBeforeRestore(), tc.data)
+		assert.Equal(t, tc.beforeRun, taskDefinition.CleansOutputsBeforeRun(), tc.data)
+	}
+
+	var taskDefinition TaskDefinition
+	err := json.Unmarshal([]byte(`{"cleanOutputs": "sometimes"}`), &taskDefinition)
+	assert.EqualError(t, err, `cleanOutputs: invalid value "sometimes". Expected one of never, restore, always`)
+}
+
+func TestTaskDefinition_IsAffectedBy(t *testing.T) {
+	testCases := []struct {
+		inputs   []string
+		file     string
+		affected bool
+	}{
+		{nil, "README.md", true},
+		{[]string{"src/**"}, "src/index.ts", true},
+		{[]string{"src/**"}, "src/deep/index.ts", true},
+		{[]string{"src/**"}, "README.md", false},
+		{[]string{"./src/**/*.ts"}, "src/index.ts", true},
+		{[]string{"src/**/*.ts"}, "src/index.md", false},
+		{[]string{"docs/*.md", "src/**"}, "docs/intro.md", true},
+		{[]string{"*.json"}, "package.json", true},
+		{[]string{"*.json"}, "config/app.json", false},
+	}
+	for _, tc := range testCases {
+		td := &TaskDefinition{Inputs: tc.inputs}
+		affected, err := td.IsAffectedBy(tc.file)
+		if err != nil {
+			t.Errorf("inputs %v, file %v: %v", tc.inputs, tc.file, err)
+		} else if affected != tc.affected {
+			t.Errorf("inputs %v, file %v: got affected %v, want %v", tc.inputs, tc.file, affected, tc.affected)
+		}
+	}
 }
@@ -0,0 +1,320 @@
+// Package gitignore implements the rules git uses to decide whether a path in
+// a working tree is ignored. It exists so that we can enumerate the same set
+// of files that `git` would when we are operating without a git repository,
+// for instance inside of a pruned Docker context.
+//
+// The following sources are consulted, from lowest to highest precedence:
+// - `.git/info/exclude` at the root
+// - `.gitignore` at the root
+// - `.gitignore` in every directory between the root and the path
+//
+// User-level configuration (`core.excludesFile`) is intentionally not
+// consulted: it varies from machine to machine and would make the result
+// depend on who is running the command.
+package gitignore
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/vercel/turborepo/cli/internal/turbopath"
+)
+
+// rule is a single compiled line from an ignore file.
+type rule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// matches reports whether the rule applies to a path which is relative to the
+// directory containing the ignore file that the rule came from.
+func (r *rule) matches(path string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return r.pattern.MatchString(path)
+}
+
+// parseLines compiles the lines of an ignore file into rules. Blank lines,
+// comments, and lines which cannot be compiled are skipped.
+func parseLines(lines []string) []*rule {
+	var rules []*rule
+	for _, line := range lines {
+		if r := parseLine(line); r != nil {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// parseLine follows the pattern format described in `git help gitignore`.
+func parseLine(line string) *rule {
+	line = strings.TrimSuffix(line, "\r")
+
+	// A blank line matches nothing and a line starting with # is a comment.
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	// Trailing spaces are ignored unless they are escaped with a backslash.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+
+	r := &rule{}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+
+	// A trailing slash means that the pattern only matches directories.
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	// A slash at the beginning or in the middle anchors the pattern to the
+	// directory containing the ignore file. Otherwise it matches at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	segments := strings.Split(line, "/")
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		if segment == "**" {
+			if last {
+				// A trailing "/**" matches everything inside.
+				expr.WriteString(".*")
+			} else {
+				// A leading "**/" or a "/**/" matches zero or more directories.
+				expr.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+		expr.WriteString(translateSegment(segment))
+		if !last {
+			expr.WriteString("/")
+		}
+	}
+	expr.WriteString("$")
+
+	pattern, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil
+	}
+	r.pattern = pattern
+	return r
+}
+
+// translateSegment converts a single path segment of a glob into a regular
+// expression. Wildcards never match a path separator.
+func translateSegment(segment string) string {
+	var expr strings.Builder
+	runes := []rune(segment)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+			}
+		case '*':
+			// Collapse runs of stars; inside of a segment "**" is the same as "*".
+			for i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+			}
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			class, consumed := translateClass(runes[i:])
+			if consumed == 0 {
+				expr.WriteString(regexp.QuoteMeta(string(c)))
+			} else {
+				expr.WriteString(class)
+				i += consumed - 1
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String()
+}
+
+// translateClass converts a bracket expression at the start of runes into a
+// regular expression character class. It returns the number of runes consumed,
+// which is zero if the bracket expression is not terminated.
+func translateClass(runes []rune) (string, int) {
+	i := 1
+	negate := false
+	if i < len(runes) && (runes[i] == '!' || runes[i] == '^') {
+		negate = true
+		i++
+	}
+	var class strings.Builder
+	// A "]" immediately after the opening bracket is a literal.
+	first := true
+	for ; i < len(runes); i++ {
+		c := runes[i]
+		if c == ']' && !first {
+			if negate {
+				return "[^/" + class.String() + "]", i + 1
+			}
+			return "[" + class.String() + "]", i + 1
+		}
+		first = false
+		switch c {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				if runes[i] == '-' {
+					class.WriteString(`\-`)
+				} else {
+					class.WriteString(regexp.QuoteMeta(string(runes[i])))
+				}
+			}
+		case '-':
+			class.WriteRune(c)
+		default:
+			class.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return "", 0
+}
+
+// Matcher answers whether paths anchored at a repository root are ignored.
+// Ignore files are read lazily as directories are visited and are cached,
+// so a single Matcher can be shared across goroutines.
+type Matcher struct {
+	root    turbopath.AbsoluteSystemPath
+	exclude []*rule
+
+	mu      sync.Mutex
+	rules   map[string][]*rule
+	ignored map[string]bool
+}
+
+// NewMatcher creates a Matcher for the tree rooted at root.
+func NewMatcher(root turbopath.AbsoluteSystemPath) (*Matcher, error) {
+	exclude, err := readRules(root.Join(".git", "info", "exclude"))
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{
+		root:    root,
+		exclude: exclude,
+		rules:   make(map[string][]*rule),
+		ignored: make(map[string]bool),
+	}, nil
+}
+
+// readRules reads an ignore file. A missing file contains no rules.
+func readRules(path turbopath.AbsoluteSystemPath) ([]*rule, error) {
+	contents, err := os.ReadFile(path.ToString())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseLines(strings.Split(string(contents), "\n")), nil
+}
+
+// rulesFor returns the rules from the .gitignore file in the given directory.
+func (m *Matcher) rulesFor(dir string) ([]*rule, error) {
+	m.mu.Lock()
+	rules, ok := m.rules[dir]
+	m.mu.Unlock()
+	if ok {
+		return rules, nil
+	}
+	path := turbopath.AnchoredUnixPathFromUpstream(dir).ToSystemPath().RestoreAnchor(m.root).Join(".gitignore")
+	rules, err := readRules(path)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.rules[dir] = rules
+	m.mu.Unlock()
+	return rules, nil
+}
+
+// IsIgnored reports whether the given path is ignored. A path is ignored if it
+// matches a rule itself, or if any of its parent directories is ignored: as in
+// git, it is not possible to re-include a file whose parent is excluded.
+func (m *Matcher) IsIgnored(path turbopath.AnchoredUnixPath, isDir bool) (bool, error) {
+	segments := strings.Split(path.ToString(), "/")
+	for i := 1; i < len(segments); i++ {
+		ignored, err := m.isDirIgnored(segments[:i])
+		if err != nil || ignored {
+			return ignored, err
+		}
+	}
+	return m.matches(segments, isDir)
+}
+
+// isDirIgnored reports whether a directory is ignored, assuming that none of
+// its parents are. Results are memoized as every file in the directory will
+// ask the same question.
+func (m *Matcher) isDirIgnored(segments []string) (bool, error) {
+	key := strings.Join(segments, "/")
+	m.mu.Lock()
+	ignored, ok := m.ignored[key]
+	m.mu.Unlock()
+	if ok {
+		return ignored, nil
+	}
+	ignored, err := m.matches(segments, true)
+	if err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	m.ignored[key] = ignored
+	m.mu.Unlock()
+	return ignored, nil
+}
+
+// matches finds the highest precedence rule which applies to the path. Rules
+// in deeper directories override shallower ones, and within a single file
+// the last matching line wins.
+func (m *Matcher) matches(segments []string, isDir bool) (bool, error) {
+	for _, segment := range segments {
+		// git never considers its own metadata to be part of the tree.
+		if segment == ".git" {
+			return true, nil
+		}
+	}
+	for i := len(segments) - 1; i >= 0; i-- {
+		rules, err := m.rulesFor(strings.Join(segments[:i], "/"))
+		if err != nil {
+			return false, err
+		}
+		if r := lastMatch(rules, strings.Join(segments[i:], "/"), isDir); r != nil {
+			return !r.negate, nil
+		}
+	}
+	if r := lastMatch(m.exclude, strings.Join(segments, "/"), isDir); r != nil {
+		return !r.negate, nil
+	}
+	return false, nil
+}
+
+func lastMatch(rules []*rule, path string, isDir bool) *rule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].matches(path, isDir) {
+			return rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package gitignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/turbopath"
+)
+
+func Test_parseLine(t *testing.T) {
+	testCases := []struct {
+		line    string
+		path    string
+		isDir   bool
+		matches bool
+	}{
+		{"foo", "foo", false, true},
+		{"foo", "a/b/foo", false, true},
+		{"foo", "foobar", false, false},
+		{"foo/", "foo", false, false},
+		{"foo/", "foo", true, true},
+		{"foo/", "a/foo", true, true},
+		{"/foo", "foo", false, true},
+		{"/foo", "a/foo", false, false},
+		{"a/foo", "a/foo", false, true},
+		{"a/foo", "b/a/foo", false, false},
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"logs/*.log", "logs/debug.log", false, true},
+		{"logs/*.log", "logs/sub/debug.log", false, false},
+		{"**/logs", "logs", true, true},
+		{"**/logs", "a/b/logs", true, true},
+		{"logs/**", "logs/a/b", false, true},
+		{"logs/**", "logs", true, false},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"debug?.log", "debug1.log", false, true},
+		{"debug?.log", "debug10.log", false, false},
+		{"debug[0-9].log", "debug1.log", false, true},
+		{"debug[!0-9].log", "debug1.log", false, false},
+		{"debug[!0-9].log", "debuga.log", false, true},
+		{`\#file`, "#file", false, true},
+		{`\!file`, "!file", false, true},
+		{"trailing  ", "trailing", false, true},
+		{`trailing\ `, "trailing ", false, true},
+		{"a.b", "aXb", false, false},
+	}
+	for _, tc := range testCases {
+		r := parseLine(tc.line)
+		if r == nil {
+			t.Errorf("%v: failed to parse", tc.line)
+			continue
+		}
+		if got := r.matches(tc.path, tc.isDir); got != tc.matches {
+			t.Errorf("%v matching %v (isDir %v): got %v, want %v", tc.line, tc.path, tc.isDir, got, tc.matches)
+		}
+	}
+
+	for _, line := range []string{"", "# comment", "/", "   "} {
+		if r := parseLine(line); r != nil {
+			t.Errorf("%q: expected no rule, got %v", line, r.pattern)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	root := t.TempDir()
+	ignoreFiles := map[string]string{
+		".git/info/exclude":        "excluded\nkept-by-gitignore\n",
+		".gitignore":               "*.log\n!important.log\ndist/\n!kept-by-gitignore\n/rooted\n",
+		"pkg/.gitignore":           "!pkg.log\nlocal\n",
+		"pkg/nested/.gitignore":    "*.log\n",
+		"pkg/reinclude/.gitignore": "!*.txt\n",
+	}
+	for path, contents := range ignoreFiles {
+		filename := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatalf("failed to create directory for %v: %v", path, err)
+		}
+		if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write %v: %v", path, err)
+		}
+	}
+
+	matcher, err := NewMatcher(turbopath.AbsoluteSystemPathFromUpstream(root))
+	if err != nil {
+		t.Fatalf("failed to create matcher: %v", err)
+	}
+
+	testCases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"file.txt", false, false},
+		{"debug.log", false, true},
+		{"important.log", false, false},
+		{"excluded", false, true},
+		{"kept-by-gitignore", false, false},
+		{"rooted", false, true},
+		{"pkg/rooted", false, false},
+		{"dist", true, true},
+		{"dist", false, false},
+		{"dist/file.txt", false, true},
+		{"pkg/dist/file.txt", false, true},
+		{"pkg/debug.log", false, true},
+		{"pkg/pkg.log", false, false},
+		{"pkg/local", false, true},
+		{"pkg/sub/local", true, true},
+		{"pkg/nested/pkg.log", false, true},
+		{"pkg/nested/local", false, true},
+		{"pkg/nested/file.txt", false, false},
+		// A file cannot be re-included if its parent directory is excluded.
+		{"pkg/reinclude/dist/file.txt", false, true},
+		{".git", true, true},
+		{".git/config", false, true},
+	}
+	for _, tc := range testCases {
+		got, err := matcher.IsIgnored(turbopath.AnchoredUnixPathFromUpstream(tc.path), tc.isDir)
+		if err != nil {
+			t.Errorf("%v: %v", tc.path, err)
+		} else if got != tc.ignored {
+			t.Errorf("%v (isDir %v): got ignored %v, want %v", tc.path, tc.isDir, got, tc.ignored)
+		}
+	}
+}
@@ -6,8 +6,10 @@ package nodes
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/util"
 )
 
 // PackageTask represents running a particular task in a particular package
@@ -19,13 +21,42 @@ type PackageTask struct {
 	TaskDefinition *fs.TaskDefinition
 }
 
-// Command returns the script for this task from package.json and a boolean indicating
-// whether or not it exists
+// Command returns the command for this task and a boolean indicating whether or not it
+// exists. This is the command from the task's definition, with its placeholders filled in,
+// or otherwise the task's script from package.json.
 func (pt *PackageTask) Command() (string, bool) {
+	if pt.TaskDefinition != nil && pt.TaskDefinition.Command != "" {
+		return pt.expandCommand(pt.TaskDefinition.Command), true
+	}
 	cmd, ok := pt.Pkg.Scripts[pt.Task]
 	return cmd, ok
 }
 
+// RunsDirectly returns true if this task's command is run with the system shell, rather
+// than as a package.json script by the package manager
+func (pt *PackageTask) RunsDirectly() bool {
+	if pt.TaskDefinition == nil {
+		return false
+	}
+	return pt.TaskDefinition.Command != "" || pt.TaskDefinition.Runner == fs.RunnerShell
+}
+
+// expandCommand replaces the placeholders in a command from a task definition: {package}
+// with the name of the package, {dir} with its directory relative to the repo root, and
+// {task} with the name of the task. The values are quoted for the system shell, as package
+// directories may contain spaces or other characters the shell would interpret.
+func (pt *PackageTask) expandCommand(command string) string {
+	dir := pt.Pkg.Dir.ToStringDuringMigration()
+	if dir == "" {
+		dir = "."
+	}
+	return strings.NewReplacer(
+		"{package}", util.QuoteShellArg(pt.PackageName),
+		"{dir}", util.QuoteShellArg(dir),
+		"{task}", util.QuoteShellArg(pt.Task),
+	).Replace(command)
+}
+
 // OutputPrefix returns the prefix to be used for logging and ui for this task
 func (pt *PackageTask) OutputPrefix() string {
 	return fmt.Sprintf("%v:%v", pt.PackageName, pt.Task)
@@ -0,0 +1,353 @@
+package run
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+	"github.com/pyr-sh/dag"
+	"github.com/spf13/cobra"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/context"
+	"github.com/vercel/turborepo/cli/internal/core"
+	"github.com/vercel/turborepo/cli/internal/doublestar"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/scm"
+	"github.com/vercel/turborepo/cli/internal/scope"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// AffectedCommand is a Command implementation that lists the tasks affected by changes in a git range
+type AffectedCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+var _affectedLong = `
+List the tasks that are affected by the changes in a range of git history.
+
+A task is affected if a changed file in its package matches the task's
+"inputs", or any file in its package changed when it has no "inputs".
+Tasks that depend on an affected task are affected too. Changes to
+global dependencies, turbo.json, the presets it extends, the root
+package.json or the lockfile affect every task.
+
+The result is printed as JSON.
+`
+
+// Reasons that a task can be affected
+const (
+	affectedByGlobal     = "global"
+	affectedByInputs     = "inputs"
+	affectedByDependency = "dependency"
+)
+
+type affectedOpts struct {
+	base               string
+	head               string
+	deepenShallowClone bool
+}
+
+type affectedRange struct {
+	From         string `json:"from"`
+	ResolvedFrom string `json:"resolvedFrom"`
+	MergeBase    string `json:"mergeBase"`
+	To           string `json:"to"`
+}
+
+type affectedTask struct {
+	TaskID  string `json:"taskId"`
+	Task    string `json:"task"`
+	Package string `json:"package"`
+	Reason  string `json:"reason"`
+	// ChangedFiles are the changed files, relative to the package, which match the task's inputs
+	ChangedFiles []string `json:"changedFiles,omitempty"`
+	// AffectedBy are the affected tasks that this task depends on
+	AffectedBy []string `json:"affectedBy,omitempty"`
+}
+
+type affectedResult struct {
+	Range        affectedRange  `json:"range"`
+	ChangedFiles []string       `json:"changedFiles"`
+	GlobalChange string         `json:"globalChange,omitempty"`
+	Tasks        []affectedTask `json:"tasks"`
+}
+
+func getAffectedCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	opts := &affectedOpts{}
+	cmd := &cobra.Command{
+		Use:                   "turbo query affected <task> [...<task>] [<flags>]",
+		Short:                 "List the tasks affected by changes in a git range",
+		Long:                  _affectedLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("at least one task must be specified")
+			}
+			result, err := queryAffected(cmd.Context(), config, output, opts, args)
+			if err != nil {
+				return err
+			}
+			bytes, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return errors.Wrap(err, "failed to render JSON")
+			}
+			output.Output(string(bytes))
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&opts.base, "base", "origin/HEAD", "The git ref to compare against. Use <remote>/HEAD to compare with the remote's default branch.")
+	flags.StringVar(&opts.head, "head", "HEAD", "The git ref containing the changes. Uncommitted changes are always included.")
+	flags.BoolVar(&opts.deepenShallowClone, "deepen-shallow-clone", false, "In a shallow clone, fetch history from the remote until the merge base is found.")
+	return cmd
+}
+
+// Synopsis of the query affected command
+func (c *AffectedCommand) Synopsis() string {
+	cmd := getAffectedCmd(c.Config, c.UI)
+	return cmd.Short
+}
+
+// Help returns information about the `query affected` command
+func (c *AffectedCommand) Help() string {
+	cmd := getAffectedCmd(c.Config, c.UI)
+	return util.HelpForCobraCmd(cmd)
+}
+
+// Run prints the tasks affected by changes in a git range
+func (c *AffectedCommand) Run(args []string) int {
+	cmd := getAffectedCmd(c.Config, c.UI)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		c.Config.Logger.Error("error", err)
+		c.UI.Error(fmt.Sprintf("%s%s", ui.ERROR_PREFIX, color.RedString(" %v", err)))
+		return 1
+	}
+	return 0
+}
+
+func queryAffected(ctx gocontext.Context, config *config.Config, output cli.Ui, opts *affectedOpts, targets []string) (*affectedResult, error) {
+	rootPackageJSON, err := fs.ReadPackageJSON(config.Cwd.Join("package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	turboJSON, err := fs.ReadTurboConfig(config.Cwd, rootPackageJSON)
+	if err != nil {
+		return nil, err
+	}
+	runOpts := getDefaultOptions(config)
+	pkgDepGraph, err := context.New(context.WithGraph(config.Cwd, rootPackageJSON, runOpts.cacheOpts.Dir))
+	if err != nil {
+		return nil, err
+	}
+	if err := turboJSON.ApplyPresets(config.Cwd, pkgDepGraph.PackageInfos); err != nil {
+		return nil, err
+	}
+	if err := turboJSON.MergePackageConfigs(config.Cwd, pkgDepGraph.PackageInfos); err != nil {
+		return nil, err
+	}
+	if err := util.ValidateGraph(&pkgDepGraph.TopologicalGraph); err != nil {
+		return nil, errors.Wrap(err, "Invalid package dependency graph")
+	}
+	pipeline := turboJSON.Pipeline
+	if err := validateTasks(pipeline, targets); err != nil {
+		return nil, err
+	}
+
+	scmInstance, err := scm.FromInRepo(config.Cwd.ToStringDuringMigration())
+	if err != nil {
+		if errors.Is(err, scm.ErrFallback) {
+			return nil, errors.New("finding affected tasks requires a git repository")
+		}
+		return nil, errors.Wrap(err, "failed to create SCM")
+	}
+	gitRange, err := scmInstance.ResolveRange(opts.base, opts.head, opts.deepenShallowClone)
+	if err != nil {
+		return nil, err
+	}
+	// Report on stderr so that the JSON on stdout is unaffected
+	output.Warn(ui.Dim(fmt.Sprintf("• Comparing %v", gitRange)))
+	scmChangedFiles, err := scmInstance.ChangedFiles(gitRange.MergeBase, opts.head, true, config.Cwd.ToStringDuringMigration())
+	if err != nil {
+		return nil, err
+	}
+	changedFiles := make([]string, len(scmChangedFiles))
+	for i, file := range scmChangedFiles {
+		changedFiles[i] = filepath.ToSlash(file)
+	}
+	sort.Strings(changedFiles)
+
+	globalFiles := []string{"turbo.json", "package.json", pkgDepGraph.PackageManager.Lockfile}
+	for _, presetFile := range turboJSON.PresetFiles() {
+		globalFiles = append(globalFiles, filepath.ToSlash(presetFile))
+	}
+	globalChange, err := findGlobalChange(changedFiles, turboJSON.GlobalDependencies, globalFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	allPkgs := make(util.Set)
+	for _, v := range pkgDepGraph.TopologicalGraph.Vertices() {
+		if v != core.ROOT_NODE_NAME {
+			allPkgs.Add(v)
+		}
+	}
+	for _, target := range targets {
+		if _, ok := pipeline[util.RootTaskID(target)]; ok {
+			allPkgs.Add(util.RootPkgName)
+			break
+		}
+	}
+	g := &completeGraph{
+		TopologicalGraph: pkgDepGraph.TopologicalGraph,
+		Pipeline:         pipeline,
+		PackageInfos:     pkgDepGraph.PackageInfos,
+		RootNode:         pkgDepGraph.RootNode,
+	}
+	engine, err := buildTaskGraph(&g.TopologicalGraph, pipeline, &runSpec{
+		Targets:      targets,
+		FilteredPkgs: allPkgs,
+		Opts:         runOpts,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error preparing engine")
+	}
+	tasks, err := findAffectedTasks(ctx, g, engine, changedFiles, globalChange != "")
+	if err != nil {
+		return nil, err
+	}
+	return &affectedResult{
+		Range: affectedRange{
+			From:         gitRange.FromRef,
+			ResolvedFrom: gitRange.ResolvedFromRef,
+			MergeBase:    gitRange.MergeBase,
+			To:           gitRange.ToRef,
+		},
+		ChangedFiles: changedFiles,
+		GlobalChange: globalChange,
+		Tasks:        tasks,
+	}, nil
+}
+
+// findGlobalChange returns the first changed file which affects every task, either because it
+// matches one of the global dependency globs or because it is one of globalFiles.
+func findGlobalChange(changedFiles []string, globalDependencies []string, globalFiles []string) (string, error) {
+	var globs []string
+	for _, dependency := range globalDependencies {
+		// $ENV_VAR dependencies can't be detected from git history
+		if !strings.HasPrefix(dependency, "$") {
+			globs = append(globs, filepath.ToSlash(dependency))
+		}
+	}
+	for _, file := range changedFiles {
+		for _, globalFile := range globalFiles {
+			if file == globalFile {
+				return file, nil
+			}
+		}
+		for _, glob := range globs {
+			matches, err := doublestar.Match(glob, file)
+			if err != nil {
+				return "", fmt.Errorf("invalid global dependency glob %v: %w", glob, err)
+			}
+			if matches {
+				return file, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// findAffectedTasks returns the tasks in the task graph whose inputs include one of the changed
+// files, and the tasks which depend on those. changedFiles are repo-relative unix paths.
+func findAffectedTasks(ctx gocontext.Context, g *completeGraph, engine *core.Scheduler, changedFiles []string, isGlobalChange bool) ([]affectedTask, error) {
+	changedFilesByPackage := make(map[string][]string)
+	for _, file := range changedFiles {
+		pkgName := scope.PackageForFile(filepath.FromSlash(file), g.PackageInfos)
+		changedFilesByPackage[pkgName] = append(changedFilesByPackage[pkgName], file)
+	}
+
+	affected := make(map[string]*affectedTask)
+	visitor := g.getPackageTaskVisitor(ctx, func(ctx gocontext.Context, pt *nodes.PackageTask) error {
+		if isGlobalChange {
+			affected[pt.TaskID] = &affectedTask{TaskID: pt.TaskID, Task: pt.Task, Package: pt.PackageName, Reason: affectedByGlobal}
+			return nil
+		}
+		var matchingFiles []string
+		for _, file := range changedFilesByPackage[pt.PackageName] {
+			pkgRelativeFile, err := filepath.Rel(pt.Pkg.Dir.ToStringDuringMigration(), filepath.FromSlash(file))
+			if err != nil {
+				return err
+			}
+			pkgRelativeFile = filepath.ToSlash(pkgRelativeFile)
+			isAffected, err := pt.TaskDefinition.IsAffectedBy(pkgRelativeFile)
+			if err != nil {
+				return errors.Wrapf(err, "task %v", pt.TaskID)
+			}
+			if isAffected {
+				matchingFiles = append(matchingFiles, pkgRelativeFile)
+			}
+		}
+		if len(matchingFiles) > 0 {
+			affected[pt.TaskID] = &affectedTask{TaskID: pt.TaskID, Task: pt.Task, Package: pt.PackageName, Reason: affectedByInputs, ChangedFiles: matchingFiles}
+		}
+		return nil
+	})
+	for _, v := range engine.TaskGraph.Vertices() {
+		taskID := dag.VertexName(v)
+		if strings.Contains(taskID, core.ROOT_NODE_NAME) {
+			continue
+		}
+		if err := visitor(taskID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Anything depending on a directly affected task is affected as well
+	directlyAffected := make([]string, 0, len(affected))
+	for taskID := range affected {
+		directlyAffected = append(directlyAffected, taskID)
+	}
+	sort.Strings(directlyAffected)
+	for _, taskID := range directlyAffected {
+		dependents, err := engine.TaskGraph.Descendents(taskID)
+		if err != nil {
+			return nil, err
+		}
+		for _, dependent := range dependents {
+			dependentID := dag.VertexName(dependent)
+			if strings.Contains(dependentID, core.ROOT_NODE_NAME) {
+				continue
+			}
+			task, ok := affected[dependentID]
+			if !ok {
+				pkgName, taskName := util.GetPackageTaskFromId(dependentID)
+				task = &affectedTask{TaskID: dependentID, Task: taskName, Package: pkgName, Reason: affectedByDependency}
+				affected[dependentID] = task
+			}
+			if task.Reason == affectedByDependency {
+				task.AffectedBy = append(task.AffectedBy, taskID)
+			}
+		}
+	}
+
+	tasks := make([]affectedTask, 0, len(affected))
+	for _, task := range affected {
+		tasks = append(tasks, *task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].TaskID < tasks[j].TaskID
+	})
+	return tasks, nil
+}
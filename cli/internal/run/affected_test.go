@@ -0,0 +1,139 @@
+package run
+
+import (
+	gocontext "context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pyr-sh/dag"
+	"github.com/stretchr/testify/assert"
+	"github.com/vercel/turborepo/cli/internal/core"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/turbopath"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+func Test_findAffectedTasks(t *testing.T) {
+	topoGraph := dag.AcyclicGraph{}
+	topoGraph.Add(core.ROOT_NODE_NAME)
+	topoGraph.Add("lib")
+	topoGraph.Add("web")
+	topoGraph.Add("docs")
+	topoGraph.Connect(dag.BasicEdge("web", "lib"))
+	topoGraph.Connect(dag.BasicEdge("lib", core.ROOT_NODE_NAME))
+	topoGraph.Connect(dag.BasicEdge("docs", core.ROOT_NODE_NAME))
+
+	packageInfos := map[interface{}]*fs.PackageJSON{
+		util.RootPkgName: {Name: "root"},
+		"lib":            {Name: "lib", Dir: turbopath.AnchoredSystemPath(filepath.FromSlash("packages/lib"))},
+		"web":            {Name: "web", Dir: turbopath.AnchoredSystemPath(filepath.FromSlash("apps/web"))},
+		"docs":           {Name: "docs", Dir: turbopath.AnchoredSystemPath(filepath.FromSlash("apps/docs"))},
+	}
+	pipeline := fs.Pipeline{
+		"build": {
+			TopologicalDependencies: []string{"build"},
+			Inputs:                  []string{"src/**"},
+		},
+		"test": {
+			TaskDependencies: []string{"build"},
+		},
+	}
+	g := &completeGraph{
+		TopologicalGraph: topoGraph,
+		Pipeline:         pipeline,
+		PackageInfos:     packageInfos,
+		RootNode:         core.ROOT_NODE_NAME,
+	}
+	filteredPkgs := make(util.Set)
+	filteredPkgs.Add("lib")
+	filteredPkgs.Add("web")
+	filteredPkgs.Add("docs")
+	engine, err := buildTaskGraph(&g.TopologicalGraph, pipeline, &runSpec{
+		Targets:      []string{"build", "test"},
+		FilteredPkgs: filteredPkgs,
+		Opts:         &Opts{},
+	})
+	if err != nil {
+		t.Fatalf("failed to build task graph: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		changedFiles   []string
+		isGlobalChange bool
+		expected       []affectedTask
+	}{
+		{
+			name:         "no changes",
+			changedFiles: []string{},
+			expected:     []affectedTask{},
+		},
+		{
+			name:         "file outside of task inputs",
+			changedFiles: []string{"packages/lib/README.md"},
+			expected: []affectedTask{
+				{TaskID: "lib#test", Task: "test", Package: "lib", Reason: affectedByInputs, ChangedFiles: []string{"README.md"}},
+			},
+		},
+		{
+			name:         "file in task inputs",
+			changedFiles: []string{"packages/lib/src/index.ts"},
+			expected: []affectedTask{
+				{TaskID: "lib#build", Task: "build", Package: "lib", Reason: affectedByInputs, ChangedFiles: []string{"src/index.ts"}},
+				{TaskID: "lib#test", Task: "test", Package: "lib", Reason: affectedByInputs, ChangedFiles: []string{"src/index.ts"}},
+				{TaskID: "web#build", Task: "build", Package: "web", Reason: affectedByDependency, AffectedBy: []string{"lib#build"}},
+				{TaskID: "web#test", Task: "test", Package: "web", Reason: affectedByDependency, AffectedBy: []string{"lib#build"}},
+			},
+		},
+		{
+			name:         "file in the root package",
+			changedFiles: []string{"README.md"},
+			expected:     []affectedTask{},
+		},
+		{
+			name:           "global change",
+			changedFiles:   []string{"turbo.json"},
+			isGlobalChange: true,
+			expected: []affectedTask{
+				{TaskID: "docs#build", Task: "build", Package: "docs", Reason: affectedByGlobal},
+				{TaskID: "docs#test", Task: "test", Package: "docs", Reason: affectedByGlobal},
+				{TaskID: "lib#build", Task: "build", Package: "lib", Reason: affectedByGlobal},
+				{TaskID: "lib#test", Task: "test", Package: "lib", Reason: affectedByGlobal},
+				{TaskID: "web#build", Task: "build", Package: "web", Reason: affectedByGlobal},
+				{TaskID: "web#test", Task: "test", Package: "web", Reason: affectedByGlobal},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tasks, err := findAffectedTasks(gocontext.Background(), g, engine, tc.changedFiles, tc.isGlobalChange)
+			if err != nil {
+				t.Fatalf("failed to find affected tasks: %v", err)
+			}
+			assert.Equal(t, tc.expected, tasks)
+		})
+	}
+}
+
+func Test_findGlobalChange(t *testing.T) {
+	globalFiles := []string{"turbo.json", "package.json", "pnpm-lock.yaml"}
+	testCases := []struct {
+		changedFiles       []string
+		globalDependencies []string
+		expected           string
+	}{
+		{[]string{"packages/lib/package.json"}, nil, ""},
+		{[]string{"README.md", "pnpm-lock.yaml"}, nil, "pnpm-lock.yaml"},
+		{[]string{"tsconfig.base.json"}, []string{"$NODE_ENV", "*.base.json"}, "tsconfig.base.json"},
+		{[]string{"config/nested/tsconfig.base.json"}, []string{"*.base.json"}, ""},
+		{[]string{"config/nested/tsconfig.base.json"}, []string{"config/**"}, "config/nested/tsconfig.base.json"},
+	}
+	for _, tc := range testCases {
+		globalChange, err := findGlobalChange(tc.changedFiles, tc.globalDependencies, globalFiles)
+		if err != nil {
+			t.Errorf("%v: %v", tc.changedFiles, err)
+		} else if globalChange != tc.expected {
+			t.Errorf("%v with global dependencies %v: got %q, want %q", tc.changedFiles, tc.globalDependencies, globalChange, tc.expected)
+		}
+	}
+}
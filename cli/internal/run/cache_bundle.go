@@ -0,0 +1,188 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/vercel/turborepo/cli/internal/cache"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/signals"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// CacheExportCommand is a Command implementation that packs the local cache entries of the
+// tasks a run would execute into a bundle
+type CacheExportCommand struct {
+	Config        *config.Config
+	UI            *cli.ColoredUi
+	SignalWatcher *signals.Watcher
+}
+
+// CacheImportCommand is a Command implementation that loads a bundle into the local cache
+type CacheImportCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+var _cacheExportLong = `
+Pack the local cache entries of the tasks that "turbo run" would execute
+with the same tasks and flags into a bundle, which "turbo cache import" can
+load into another machine's local cache. Tasks are hashed the same way as
+with --dry-run, and nothing is run. If remote cache signing is enabled,
+each artifact is signed, and the signatures are checked on import.
+
+Arguments passed after '--' are hashed as arguments to the named tasks.
+`
+
+var _cacheImportLong = `
+Load the artifacts in a bundle made by "turbo cache export" into the local
+cache. If remote cache signing is enabled, every artifact must have a valid
+signature. Entries that are already in the cache are kept.
+`
+
+func getCacheExportCmd(config *config.Config, output cli.Ui, signalWatcher *signals.Watcher) *cobra.Command {
+	var opts *Opts
+	var flags *pflag.FlagSet
+	var bundle string
+	cmd := &cobra.Command{
+		Use:                   "turbo cache export <task> [...<task>] --output <file> [<flags>] -- <args passed to tasks>",
+		Short:                 "Pack the local cache entries of tasks into a bundle",
+		Long:                  _cacheExportLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, passThroughArgs := parseTasksAndPassthroughArgs(args, flags)
+			if len(tasks) == 0 {
+				return errors.New("at least one task must be specified")
+			}
+			if bundle == "" {
+				return errors.New("--output is required")
+			}
+			opts.runOpts.passThroughArgs = passThroughArgs
+			opts.runOpts.exportBundle = fs.ResolveUnknownPath(config.Cwd, bundle)
+			run := configureRun(config, output, opts, signalWatcher)
+			return run.run(cmd.Context(), tasks)
+		},
+	}
+	flags = cmd.Flags()
+	opts = optsFromFlags(flags, config)
+	flags.StringVarP(&bundle, "output", "o", "", "The file to write the bundle to.")
+	return cmd
+}
+
+// exportBundle packs the local cache entries of the given tasks into the bundle file
+func (r *run) exportBundle(tasks []hashedTask, rs *runSpec) error {
+	bundle := rs.Opts.runOpts.exportBundle
+	hashes := make([]string, len(tasks))
+	for i, task := range tasks {
+		hashes[i] = task.Hash
+	}
+	if err := bundle.EnsureDir(); err != nil {
+		return err
+	}
+	file, err := bundle.Create()
+	if err != nil {
+		return err
+	}
+	missing, err := cache.ExportBundle(file, rs.Opts.cacheOpts, r.config.RemoteConfig.TeamID, hashes)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = bundle.Remove()
+		return errors.Wrap(err, "failed to export the cache")
+	}
+
+	missingHashes := make(util.Set)
+	for _, hash := range missing {
+		missingHashes.Add(hash)
+	}
+	var missingTasks []string
+	for _, task := range tasks {
+		if missingHashes.Includes(task.Hash) {
+			missingTasks = append(missingTasks, task.TaskID)
+		}
+	}
+	if len(missingTasks) > 0 {
+		r.logWarning("", fmt.Errorf("no local cache entries for %v", strings.Join(missingTasks, ", ")))
+	}
+	r.ui.Output(fmt.Sprintf("Exported %v of %v tasks to %v", len(tasks)-len(missingTasks), len(tasks), bundle))
+	return nil
+}
+
+type cacheImportOpts struct {
+	cacheOpts cache.Opts
+}
+
+func getCacheImportCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	opts := &cacheImportOpts{
+		cacheOpts: getDefaultOptions(config).cacheOpts,
+	}
+	cmd := &cobra.Command{
+		Use:                   "turbo cache import <file> [<flags>]",
+		Short:                 "Load a bundle made by turbo cache export into the local cache",
+		Long:                  _cacheImportLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			remoteCacheOpts, err := readRemoteCacheOptions(config)
+			if err != nil {
+				return err
+			}
+			opts.cacheOpts.RemoteCacheOpts = remoteCacheOpts
+			file, err := os.Open(fs.ResolveUnknownPath(config.Cwd, args[0]).ToString())
+			if err != nil {
+				return err
+			}
+			defer func() { _ = file.Close() }()
+			imported, err := cache.ImportBundle(file, opts.cacheOpts, config.RemoteConfig.TeamID)
+			if len(imported) > 0 {
+				output.Output(fmt.Sprintf("Imported %v cache entries", len(imported)))
+			}
+			return err
+		},
+	}
+	flags := cmd.Flags()
+	cache.AddFlags(&opts.cacheOpts, flags, config.Cwd)
+	noopPersistentOptsDuringMigration(flags)
+	return cmd
+}
+
+// Synopsis of the cache export command
+func (c *CacheExportCommand) Synopsis() string {
+	return getCacheExportCmd(c.Config, c.UI, c.SignalWatcher).Short
+}
+
+// Help returns information about the `cache export` command
+func (c *CacheExportCommand) Help() string {
+	return util.HelpForCobraCmd(getCacheExportCmd(c.Config, c.UI, c.SignalWatcher))
+}
+
+// Run packs the local cache entries of tasks into a bundle
+func (c *CacheExportCommand) Run(args []string) int {
+	return runCacheCmd(c.Config, c.UI, getCacheExportCmd(c.Config, c.UI, c.SignalWatcher), args)
+}
+
+// Synopsis of the cache import command
+func (c *CacheImportCommand) Synopsis() string {
+	return getCacheImportCmd(c.Config, c.UI).Short
+}
+
+// Help returns information about the `cache import` command
+func (c *CacheImportCommand) Help() string {
+	return util.HelpForCobraCmd(getCacheImportCmd(c.Config, c.UI))
+}
+
+// Run loads a bundle into the local cache
+func (c *CacheImportCommand) Run(args []string) int {
+	return runCacheCmd(c.Config, c.UI, getCacheImportCmd(c.Config, c.UI), args)
+}
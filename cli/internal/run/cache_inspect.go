@@ -0,0 +1,400 @@
+package run
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/vercel/turborepo/cli/internal/cache"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// CacheListCommand is a Command implementation that lists the entries in the local cache
+type CacheListCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+// CacheShowCommand is a Command implementation that prints the files and logs of a cache entry
+type CacheShowCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+// CacheExtractCommand is a Command implementation that restores a cache entry into a directory
+type CacheExtractCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+// CacheRemoveCommand is a Command implementation that deletes an entry from the local cache
+type CacheRemoveCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+var _cacheListLong = `
+List the entries in the local cache, newest first, with the size of their
+files, how long the task that produced them took, and when they were stored.
+The remote cache can't be listed.
+`
+
+var _cacheShowLong = `
+Print the files stored in a cache entry, along with the logs of the task
+that produced it. Use --remote to download the entry from the remote cache
+instead, verifying its signature if signing is enabled.
+`
+
+var _cacheExtractLong = `
+Restore the files of a cache entry into the directory given with --to,
+without touching the repo. Files are written at their paths relative to the
+repo root. Use --remote to download the entry from the remote cache instead.
+`
+
+var _cacheRemoveLong = `
+Delete an entry from the local cache, so that the next run of its task is a
+cache miss. Entries can't be deleted from the remote cache.
+`
+
+type cacheInspectOpts struct {
+	remote    bool
+	to        fs.AbsolutePath
+	cacheOpts cache.Opts
+}
+
+// addFlags adds the flags shared by the cache commands. Only commands that can read from the
+// remote cache get --remote.
+func (opts *cacheInspectOpts) addFlags(cmd *cobra.Command, config *config.Config, withRemote bool) {
+	flags := cmd.Flags()
+	if withRemote {
+		flags.BoolVar(&opts.remote, "remote", false, "Read the entry from the remote cache instead of the local cache.")
+	}
+	cache.AddFlags(&opts.cacheOpts, flags, config.Cwd)
+	noopPersistentOptsDuringMigration(flags)
+}
+
+// newCacheInspector returns an Inspector for the local cache, or for the remote cache if
+// --remote was given
+func newCacheInspector(config *config.Config, opts *cacheInspectOpts) (cache.Inspector, error) {
+	if !opts.remote {
+		return cache.NewLocalInspector(opts.cacheOpts.Dir), nil
+	}
+	apiClient := config.NewClient()
+	if !apiClient.IsLoggedIn() {
+		return nil, errors.New("the remote cache isn't set up. Run \"turbo login\" and \"turbo link\" first")
+	}
+	remoteCacheOpts, err := readRemoteCacheOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	opts.cacheOpts.RemoteCacheOpts = remoteCacheOpts
+	return cache.NewRemoteInspector(opts.cacheOpts, config.RemoteConfig.TeamID, apiClient), nil
+}
+
+// readRemoteCacheOptions reads the remoteCache options, such as signing, from turbo.json
+func readRemoteCacheOptions(config *config.Config) (fs.RemoteCacheOptions, error) {
+	rootPackageJSON, err := fs.ReadPackageJSON(config.Cwd.Join("package.json"))
+	if err != nil {
+		return fs.RemoteCacheOptions{}, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	turboJSON, err := fs.ReadTurboConfig(config.Cwd, rootPackageJSON)
+	if err != nil {
+		return fs.RemoteCacheOptions{}, err
+	}
+	return turboJSON.RemoteCacheOptions, nil
+}
+
+// cacheEntryError adds the hash to errors that don't say which entry they're about
+func cacheEntryError(hash string, err error) error {
+	if errors.Is(err, cache.ErrEntryNotFound) {
+		return fmt.Errorf("no cache entry for %v", hash)
+	}
+	return err
+}
+
+func getCacheListCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	opts := &cacheInspectOpts{
+		cacheOpts: getDefaultOptions(config).cacheOpts,
+	}
+	cmd := &cobra.Command{
+		Use:                   "turbo cache ls [<flags>]",
+		Short:                 "List the entries in the local cache",
+		Long:                  _cacheListLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inspector, err := newCacheInspector(config, opts)
+			if err != nil {
+				return err
+			}
+			entries, err := inspector.List()
+			if err != nil {
+				return err
+			}
+			output.Output(renderCacheEntries(entries, time.Now()))
+			return nil
+		},
+	}
+	opts.addFlags(cmd, config, false)
+	return cmd
+}
+
+// renderCacheEntries formats the entries as a table, with their ages relative to now
+func renderCacheEntries(entries []cache.Entry, now time.Time) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, util.Sprintf("${BOLD}HASH\tSIZE\tDURATION\tAGE${RESET}"))
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", entry.Hash, formatSize(entry.Size), formatDuration(entry.Duration), formatAge(now.Sub(entry.Created)))
+	}
+	w.Flush()
+	fmt.Fprintf(&b, "%v entries, %v", len(entries), formatSize(size))
+	return b.String()
+}
+
+// formatSize formats a number of bytes, using the largest unit it's at least one of
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%v B", size)
+	}
+	value := float64(size)
+	suffixes := []string{"KiB", "MiB", "GiB", "TiB"}
+	for i, suffix := range suffixes {
+		value /= unit
+		if value < unit || i == len(suffixes)-1 {
+			return fmt.Sprintf("%.1f %v", value, suffix)
+		}
+	}
+	return ""
+}
+
+// formatDuration formats a task duration given in milliseconds
+func formatDuration(milliseconds int) string {
+	duration := time.Duration(milliseconds) * time.Millisecond
+	if duration < time.Second {
+		return duration.String()
+	}
+	return duration.Round(100 * time.Millisecond).String()
+}
+
+// formatAge formats how long ago an entry was stored, in its largest whole unit
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%vm ago", int(age/time.Minute))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%vh ago", int(age/time.Hour))
+	default:
+		return fmt.Sprintf("%vd ago", int(age/(24*time.Hour)))
+	}
+}
+
+func getCacheShowCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	opts := &cacheInspectOpts{
+		cacheOpts: getDefaultOptions(config).cacheOpts,
+	}
+	cmd := &cobra.Command{
+		Use:                   "turbo cache show <hash> [<flags>]",
+		Short:                 "Print the files and logs stored in a cache entry",
+		Long:                  _cacheShowLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inspector, err := newCacheInspector(config, opts)
+			if err != nil {
+				return err
+			}
+			entry, err := inspector.Show(args[0])
+			if err != nil {
+				return cacheEntryError(args[0], err)
+			}
+			output.Output(renderCacheEntry(entry))
+			return nil
+		},
+	}
+	opts.addFlags(cmd, config, true)
+	return cmd
+}
+
+// renderCacheEntry formats an entry's files, followed by its logs
+func renderCacheEntry(entry *cache.Entry) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, util.Sprintf("${CYAN}${BOLD}%s${RESET}", entry.Hash))
+	fmt.Fprintf(&b, "size %v, duration %v\n\n", formatSize(entry.Size), formatDuration(entry.Duration))
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, file := range entry.Files {
+		switch {
+		case file.LinkTarget != "":
+			fmt.Fprintf(w, "%v\t%v\n", file.Mode, file.Path+" -> "+file.LinkTarget)
+		case file.Mode.IsDir():
+			fmt.Fprintf(w, "%v\t%v\n", file.Mode, file.Path+"/")
+		default:
+			digest := file.Digest
+			if digest == "" {
+				digest = ui.Dim("(no digest)")
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", file.Mode, file.Path, formatSize(file.Size), digest)
+		}
+	}
+	w.Flush()
+	logs := make([]string, 0, len(entry.Logs))
+	for name := range entry.Logs {
+		logs = append(logs, name)
+	}
+	sort.Strings(logs)
+	for _, name := range logs {
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, util.Sprintf("${BOLD}%s${RESET}", name))
+		fmt.Fprint(&b, entry.Logs[name])
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func getCacheExtractCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	opts := &cacheInspectOpts{
+		cacheOpts: getDefaultOptions(config).cacheOpts,
+	}
+	cmd := &cobra.Command{
+		Use:                   "turbo cache extract <hash> --to <dir> [<flags>]",
+		Short:                 "Restore the files of a cache entry into a directory",
+		Long:                  _cacheExtractLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("to") {
+				return errors.New("--to is required")
+			}
+			inspector, err := newCacheInspector(config, opts)
+			if err != nil {
+				return err
+			}
+			if err := inspector.Extract(args[0], opts.to); err != nil {
+				return cacheEntryError(args[0], err)
+			}
+			output.Output(fmt.Sprintf("Extracted %v to %v", args[0], opts.to))
+			return nil
+		},
+	}
+	fs.AbsolutePathVar(cmd.Flags(), &opts.to, "to", config.Cwd, "The directory to extract the entry's files into.", "")
+	opts.addFlags(cmd, config, true)
+	return cmd
+}
+
+func getCacheRemoveCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	opts := &cacheInspectOpts{
+		cacheOpts: getDefaultOptions(config).cacheOpts,
+	}
+	cmd := &cobra.Command{
+		Use:                   "turbo cache rm <hash> [<flags>]",
+		Short:                 "Delete an entry from the local cache",
+		Long:                  _cacheRemoveLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inspector, err := newCacheInspector(config, opts)
+			if err != nil {
+				return err
+			}
+			if err := inspector.Remove(args[0]); err != nil {
+				return cacheEntryError(args[0], err)
+			}
+			output.Output(fmt.Sprintf("Removed %v", args[0]))
+			return nil
+		},
+	}
+	opts.addFlags(cmd, config, false)
+	return cmd
+}
+
+// runCacheCmd runs one of the cache commands, and reports its error
+func runCacheCmd(c *config.Config, output *cli.ColoredUi, cmd *cobra.Command, args []string) int {
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		c.Logger.Error("error", err)
+		output.Error(fmt.Sprintf("%s%s", ui.ERROR_PREFIX, color.RedString(" %v", err)))
+		return 1
+	}
+	return 0
+}
+
+// Synopsis of the cache ls command
+func (c *CacheListCommand) Synopsis() string {
+	return getCacheListCmd(c.Config, c.UI).Short
+}
+
+// Help returns information about the `cache ls` command
+func (c *CacheListCommand) Help() string {
+	return util.HelpForCobraCmd(getCacheListCmd(c.Config, c.UI))
+}
+
+// Run lists the entries in the local cache
+func (c *CacheListCommand) Run(args []string) int {
+	return runCacheCmd(c.Config, c.UI, getCacheListCmd(c.Config, c.UI), args)
+}
+
+// Synopsis of the cache show command
+func (c *CacheShowCommand) Synopsis() string {
+	return getCacheShowCmd(c.Config, c.UI).Short
+}
+
+// Help returns information about the `cache show` command
+func (c *CacheShowCommand) Help() string {
+	return util.HelpForCobraCmd(getCacheShowCmd(c.Config, c.UI))
+}
+
+// Run prints the files and logs of a cache entry
+func (c *CacheShowCommand) Run(args []string) int {
+	return runCacheCmd(c.Config, c.UI, getCacheShowCmd(c.Config, c.UI), args)
+}
+
+// Synopsis of the cache extract command
+func (c *CacheExtractCommand) Synopsis() string {
+	return getCacheExtractCmd(c.Config, c.UI).Short
+}
+
+// Help returns information about the `cache extract` command
+func (c *CacheExtractCommand) Help() string {
+	return util.HelpForCobraCmd(getCacheExtractCmd(c.Config, c.UI))
+}
+
+// Run restores a cache entry into a directory
+func (c *CacheExtractCommand) Run(args []string) int {
+	return runCacheCmd(c.Config, c.UI, getCacheExtractCmd(c.Config, c.UI), args)
+}
+
+// Synopsis of the cache rm command
+func (c *CacheRemoveCommand) Synopsis() string {
+	return getCacheRemoveCmd(c.Config, c.UI).Short
+}
+
+// Help returns information about the `cache rm` command
+func (c *CacheRemoveCommand) Help() string {
+	return util.HelpForCobraCmd(getCacheRemoveCmd(c.Config, c.UI))
+}
+
+// Run deletes an entry from the local cache
+func (c *CacheRemoveCommand) Run(args []string) int {
+	return runCacheCmd(c.Config, c.UI, getCacheRemoveCmd(c.Config, c.UI), args)
+}
@@ -0,0 +1,62 @@
+package run
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vercel/turborepo/cli/internal/cache"
+)
+
+func Test_formatSize(t *testing.T) {
+	testCases := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1024:            "1.0 KiB",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 40:         "3.0 TiB",
+		4 << 50:         "4096.0 TiB",
+	}
+	for size, want := range testCases {
+		if got := formatSize(size); got != want {
+			t.Errorf("formatSize(%v) got %v, want %v", size, got, want)
+		}
+	}
+}
+
+func Test_formatAge(t *testing.T) {
+	testCases := map[time.Duration]string{
+		10 * time.Second:       "just now",
+		90 * time.Second:       "1m ago",
+		5 * time.Hour:          "5h ago",
+		(3*24 + 5) * time.Hour: "3d ago",
+	}
+	for age, want := range testCases {
+		if got := formatAge(age); got != want {
+			t.Errorf("formatAge(%v) got %v, want %v", age, got, want)
+		}
+	}
+}
+
+func Test_renderCacheEntries(t *testing.T) {
+	now := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+	entries := []cache.Entry{
+		{Hash: "2a3c3d4b1e7f8a9c", Size: 2048, Duration: 1500, Created: now.Add(-2 * time.Hour)},
+		{Hash: "9f8e7d6c5b4a3928", Size: 100, Duration: 80, Created: now.Add(-50 * time.Hour)},
+	}
+	rendered := renderCacheEntries(entries, now)
+	lines := strings.Split(rendered, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header, 2 entries and a summary, got:\n%v", rendered)
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "2a3c3d4b1e7f8a9c 2.0 KiB 1.5s 2h ago" {
+		t.Errorf("got entry %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "9f8e7d6c5b4a3928 100 B 80ms 2d ago" {
+		t.Errorf("got entry %q", lines[2])
+	}
+	if lines[3] != "2 entries, 2.1 KiB" {
+		t.Errorf("got summary %q", lines[3])
+	}
+}
@@ -0,0 +1,94 @@
+package run
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/cli"
+	"github.com/spf13/cobra"
+	"github.com/vercel/turborepo/cli/internal/cache"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// CacheVerifyCommand is a Command implementation that checks the local cache for corrupt entries
+type CacheVerifyCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+var _cacheVerifyLong = `
+Check every entry in the local cache against the digests of its files that
+were recorded when it was stored. Corrupt entries are moved to the
+quarantine directory inside the cache, so that they're no longer restored.
+Entries stored by older versions of turbo have no digests, and are reported
+as unverified. Exits with an error if any entry is corrupt.
+`
+
+type cacheVerifyOpts struct {
+	cacheOpts cache.Opts
+}
+
+func getCacheVerifyCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	opts := &cacheVerifyOpts{
+		cacheOpts: getDefaultOptions(config).cacheOpts,
+	}
+	cmd := &cobra.Command{
+		Use:                   "turbo cache verify [<flags>]",
+		Short:                 "Check the local cache for corrupt entries",
+		Long:                  _cacheVerifyLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results, err := cache.VerifyLocal(opts.cacheOpts.Dir)
+			if err != nil {
+				return err
+			}
+			corrupt := 0
+			unverified := 0
+			for _, result := range results {
+				switch {
+				case result.Err != nil:
+					corrupt++
+					status := "corrupt"
+					if result.Quarantined {
+						status = "corrupt, quarantined"
+					}
+					output.Output(fmt.Sprintf("%v %v", result.Hash, color.RedString("%v: %v", status, result.Err)))
+				case result.Unverified:
+					unverified++
+					output.Output(fmt.Sprintf("%v %v", result.Hash, ui.Dim("unverified")))
+				}
+			}
+			output.Output(fmt.Sprintf("%v entries checked, %v corrupt, %v unverified", len(results), corrupt, unverified))
+			if corrupt > 0 {
+				return fmt.Errorf("found %v corrupt cache entries", corrupt)
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	cache.AddFlags(&opts.cacheOpts, flags, config.Cwd)
+	noopPersistentOptsDuringMigration(flags)
+	return cmd
+}
+
+// Synopsis of the cache verify command
+func (c *CacheVerifyCommand) Synopsis() string {
+	cmd := getCacheVerifyCmd(c.Config, c.UI)
+	return cmd.Short
+}
+
+// Help returns information about the `cache verify` command
+func (c *CacheVerifyCommand) Help() string {
+	cmd := getCacheVerifyCmd(c.Config, c.UI)
+	return util.HelpForCobraCmd(cmd)
+}
+
+// Run checks the local cache for corrupt entries
+func (c *CacheVerifyCommand) Run(args []string) int {
+	return runCacheCmd(c.Config, c.UI, getCacheVerifyCmd(c.Config, c.UI), args)
+}
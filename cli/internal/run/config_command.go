@@ -0,0 +1,187 @@
+package run
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/context"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// ConfigValidateCommand is a Command implementation that checks turbo.json files for problems
+type ConfigValidateCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+var _configValidateLong = `
+Check the root turbo.json, and the turbo.json of each workspace package,
+for problems. This reports unknown keys, values of the wrong type, tasks in
+"dependsOn" that aren't defined in the pipeline, and "<package>#<task>"
+entries for packages that don't exist, along with where they are.
+`
+
+// turboJSONFile is a turbo.json to validate
+type turboJSONFile struct {
+	// path is relative to the repo root
+	path      string
+	data      []byte
+	isPackage bool
+}
+
+func (f *turboJSONFile) validate(refs *fs.TaskReferences) error {
+	if f.isPackage {
+		return fs.ValidatePackageTurboJSON(f.path, f.data, refs)
+	}
+	return fs.ValidateTurboJSON(f.path, f.data, refs)
+}
+
+func getConfigValidateCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "turbo config validate",
+		Short:                 "Check turbo.json files for problems",
+		Long:                  _configValidateLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files, problems, err := validateConfigFiles(config)
+			if err != nil {
+				return err
+			}
+			if len(problems) > 0 {
+				for _, problem := range problems {
+					output.Error(problem.Error())
+				}
+				return fmt.Errorf("found %v in %v", countOf("problem", len(problems)), countOf("turbo.json file", len(files)))
+			}
+			output.Output(fmt.Sprintf("%v No problems found in %v", color.GreenString("✓"), countOf("turbo.json file", len(files))))
+			return nil
+		},
+	}
+	return cmd
+}
+
+// countOf formats a count of things, such as "1 problem" or "2 problems"
+func countOf(noun string, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%v %v", count, noun)
+	}
+	return fmt.Sprintf("%v %vs", count, noun)
+}
+
+// validateConfigFiles returns the turbo.json files in the repo, and the problems found in them
+func validateConfigFiles(config *config.Config) ([]*turboJSONFile, fs.ConfigErrors, error) {
+	rootPackageJSON, err := fs.ReadPackageJSON(config.Cwd.Join("package.json"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	rootConfigPath := config.Cwd.Join("turbo.json")
+	if !rootConfigPath.FileExists() {
+		if rootPackageJSON.LegacyTurboConfig != nil {
+			return nil, nil, errors.New("turbo configuration is in the \"turbo\" key of package.json, which can't be validated. Migrate to turbo.json by running \"npx @turbo/codemod create-turbo-config\"")
+		}
+		return nil, nil, errors.New("could not find turbo.json")
+	}
+	rootData, err := rootConfigPath.ReadFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []*turboJSONFile{{path: "turbo.json", data: rootData}}
+
+	pkgDepGraph, err := context.New(context.WithGraph(config.Cwd, rootPackageJSON, getDefaultOptions(config).cacheOpts.Dir))
+	if err != nil {
+		return nil, nil, err
+	}
+	pkgNames := append([]string{}, pkgDepGraph.PackageNames...)
+	sort.Strings(pkgNames)
+	for _, pkgName := range pkgNames {
+		pkg := pkgDepGraph.PackageInfos[pkgName]
+		configPath := config.Cwd.Join(pkg.Dir.ToString(), "turbo.json")
+		if !configPath.FileExists() {
+			continue
+		}
+		path, err := config.Cwd.RelativePathString(configPath.ToString())
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := configPath.ReadFile()
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, &turboJSONFile{path: path, data: data, isPackage: true})
+	}
+
+	// Check the structure of every file first, as references can only be
+	// resolved once all of the files can be read
+	var problems fs.ConfigErrors
+	for _, file := range files {
+		if err := file.validate(nil); err != nil {
+			problems = append(problems, err.(fs.ConfigErrors)...)
+		}
+	}
+	if len(problems) > 0 {
+		return files, problems, nil
+	}
+
+	turboJSON, err := fs.ReadTurboConfig(config.Cwd, rootPackageJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := turboJSON.ApplyPresets(config.Cwd, pkgDepGraph.PackageInfos); err != nil {
+		// Problems in presets are reported like those in the repo's own turbo.json files
+		var presetProblems fs.ConfigErrors
+		if errors.As(err, &presetProblems) {
+			return files, presetProblems, nil
+		}
+		return nil, nil, err
+	}
+	if err := turboJSON.MergePackageConfigs(config.Cwd, pkgDepGraph.PackageInfos); err != nil {
+		return nil, nil, err
+	}
+	refs := &fs.TaskReferences{
+		Pipeline:     turboJSON.Pipeline,
+		PackageNames: make(util.Set),
+	}
+	for _, pkgName := range pkgNames {
+		refs.PackageNames.Add(pkgName)
+	}
+	for _, file := range files {
+		if err := file.validate(refs); err != nil {
+			problems = append(problems, err.(fs.ConfigErrors)...)
+		}
+	}
+	return files, problems, nil
+}
+
+// Synopsis of the config validate command
+func (c *ConfigValidateCommand) Synopsis() string {
+	cmd := getConfigValidateCmd(c.Config, c.UI)
+	return cmd.Short
+}
+
+// Help returns information about the `config validate` command
+func (c *ConfigValidateCommand) Help() string {
+	cmd := getConfigValidateCmd(c.Config, c.UI)
+	return util.HelpForCobraCmd(cmd)
+}
+
+// Run checks turbo.json files for problems
+func (c *ConfigValidateCommand) Run(args []string) int {
+	cmd := getConfigValidateCmd(c.Config, c.UI)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		c.Config.Logger.Error("error", err)
+		c.UI.Error(fmt.Sprintf("%s%s", ui.ERROR_PREFIX, color.RedString(" %v", err)))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,449 @@
+package run
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/cli"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/vercel/turborepo/cli/internal/cache"
+	"github.com/vercel/turborepo/cli/internal/config"
+	"github.com/vercel/turborepo/cli/internal/context"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// ConfigShowCommand is a Command implementation that prints the configuration turbo uses
+type ConfigShowCommand struct {
+	Config *config.Config
+	UI     *cli.ColoredUi
+}
+
+var _configShowLong = `
+Print the configuration that turbo uses in this repo, and where each value
+came from: a flag, an env var, a config file, or turbo's defaults. This
+covers the remote cache, the local cache, and the definition of every task
+in the pipeline after package turbo.json files are merged in. Use --package
+to also print each task's definition as it applies to a workspace package.
+`
+
+// _redactedToken is shown in place of the token, so that it doesn't end up in logs
+const _redactedToken = "<redacted>"
+
+type configShowOpts struct {
+	json      bool
+	packages  []string
+	cacheOpts cache.Opts
+}
+
+// shownSetting is a configuration value and where it came from. Settings that
+// aren't set have no value and no source.
+type shownSetting struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source,omitempty"`
+}
+
+// namedSetting is a setting and the name it's shown with
+type namedSetting struct {
+	name    string
+	setting shownSetting
+}
+
+// shownTaskKey is a key of a task definition and the config files that set it
+type shownTaskKey struct {
+	Value   interface{} `json:"value"`
+	Sources []string    `json:"sources"`
+}
+
+// shownTaskDefinition maps the keys of a task definition, as they're named in turbo.json, to
+// their effective values
+type shownTaskDefinition map[string]shownTaskKey
+
+type shownRemoteCache struct {
+	APIURL    shownSetting `json:"apiUrl"`
+	LoginURL  shownSetting `json:"loginUrl"`
+	TeamID    shownSetting `json:"teamId"`
+	TeamSlug  shownSetting `json:"teamSlug"`
+	Token     shownSetting `json:"token"`
+	Signature shownSetting `json:"signature"`
+	PublicKey shownSetting `json:"publicKey"`
+}
+
+type shownLocalCache struct {
+	Dir        shownSetting `json:"dir"`
+	RemoteOnly shownSetting `json:"remoteOnly"`
+	Workers    shownSetting `json:"workers"`
+}
+
+type shownConfig struct {
+	RemoteCache        shownRemoteCache               `json:"remoteCache"`
+	LocalCache         shownLocalCache                `json:"localCache"`
+	CacheTiers         shownSetting                   `json:"cacheTiers"`
+	GlobalDependencies shownSetting                   `json:"globalDependencies"`
+	GlobalEnv          shownSetting                   `json:"globalEnv"`
+	Pipeline           map[string]shownTaskDefinition `json:"pipeline"`
+	// Packages maps the packages given with --package to the definitions of their tasks
+	Packages map[string]map[string]shownTaskDefinition `json:"packages,omitempty"`
+}
+
+func getConfigShowCmd(config *config.Config, output cli.Ui) *cobra.Command {
+	opts := &configShowOpts{
+		cacheOpts: getDefaultOptions(config).cacheOpts,
+	}
+	cmd := &cobra.Command{
+		Use:                   "turbo config show [<flags>]",
+		Short:                 "Print the configuration turbo uses, and where each value came from",
+		Long:                  _configShowLong,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		DisableFlagsInUseLine: true,
+		Args:                  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shown, err := showConfig(config, cmd.Flags(), opts)
+			if err != nil {
+				return err
+			}
+			if opts.json {
+				bytes, err := json.MarshalIndent(shown, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "failed to render JSON")
+				}
+				output.Output(string(bytes))
+				return nil
+			}
+			output.Output(shown.render())
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.BoolVar(&opts.json, "json", false, "Print the configuration as JSON.")
+	flags.StringArrayVar(&opts.packages, "package", nil, "Also print the definitions of tasks as they apply to the given package. Can be repeated.")
+	cache.AddFlags(&opts.cacheOpts, flags, config.Cwd)
+	noopPersistentOptsDuringMigration(flags)
+	return cmd
+}
+
+// showConfig resolves the configuration of the repo, along with the source of each value
+func showConfig(config *config.Config, flags *pflag.FlagSet, opts *configShowOpts) (*shownConfig, error) {
+	rootPackageJSON, err := fs.ReadPackageJSON(config.Cwd.Join("package.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	pkgDepGraph, err := context.New(context.WithGraph(config.Cwd, rootPackageJSON, opts.cacheOpts.Dir))
+	if err != nil {
+		return nil, err
+	}
+	turboJSON, err := fs.ReadTurboConfig(config.Cwd, rootPackageJSON)
+	if err != nil {
+		return nil, err
+	}
+	if err := turboJSON.ApplyPresets(config.Cwd, pkgDepGraph.PackageInfos); err != nil {
+		return nil, err
+	}
+	if err := turboJSON.MergePackageConfigs(config.Cwd, pkgDepGraph.PackageInfos); err != nil {
+		return nil, err
+	}
+	// The root configuration is in package.json for repos that haven't migrated to turbo.json
+	rootSource := "turbo.json"
+	if !config.Cwd.Join("turbo.json").FileExists() {
+		rootSource = "package.json"
+	}
+
+	cacheDir, err := config.Cwd.RelativePathString(opts.cacheOpts.Dir.ToString())
+	if err != nil {
+		return nil, err
+	}
+	cacheOpts := opts.cacheOpts
+	cacheOpts.Tiers = turboJSON.CacheTiers
+	token := shownSetting{Source: config.RemoteConfigSources.Token}
+	if config.RemoteConfig.Token != "" {
+		token.Value = _redactedToken
+	}
+	shown := &shownConfig{
+		RemoteCache: shownRemoteCache{
+			APIURL:    setting(config.RemoteConfig.APIURL, config.RemoteConfigSources.APIURL),
+			LoginURL:  setting(config.LoginURL, config.RemoteConfigSources.LoginURL),
+			TeamID:    setting(config.RemoteConfig.TeamID, config.RemoteConfigSources.TeamID),
+			TeamSlug:  setting(config.RemoteConfig.TeamSlug, config.RemoteConfigSources.TeamSlug),
+			Token:     token,
+			Signature: shownSetting{Value: turboJSON.RemoteCacheOptions.Signature, Source: sourceIf(turboJSON.RemoteCacheOptions.Signature, rootSource)},
+			PublicKey: setting(turboJSON.RemoteCacheOptions.PublicKey, rootSource),
+		},
+		LocalCache: shownLocalCache{
+			Dir:        shownSetting{Value: cacheDir, Source: flagSource(flags, "cache-dir")},
+			RemoteOnly: shownSetting{Value: opts.cacheOpts.SkipFilesystem, Source: flagSource(flags, "remote-only")},
+			Workers:    shownSetting{Value: opts.cacheOpts.Workers, Source: fs.SourceDefault},
+		},
+		CacheTiers:         shownSetting{Value: showCacheTiers(cacheOpts.EffectiveTiers(), cacheDir), Source: cacheTiersSource(flags, len(turboJSON.CacheTiers) > 0, rootSource)},
+		GlobalDependencies: shownSetting{Value: listValue(turboJSON.GlobalDependencies), Source: sourceIf(len(turboJSON.GlobalDependencies) > 0, rootSource)},
+		GlobalEnv:          shownSetting{Value: listValue(turboJSON.GlobalEnv), Source: sourceIf(len(turboJSON.GlobalEnv) > 0, rootSource)},
+		Pipeline:           make(map[string]shownTaskDefinition, len(turboJSON.Pipeline)),
+	}
+	for taskID, taskDefinition := range turboJSON.Pipeline {
+		sources, _ := turboJSON.TaskDefinitionSources(taskID)
+		shown.Pipeline[taskID] = showTaskDefinition(taskDefinition, sources)
+	}
+
+	for _, pkgName := range opts.packages {
+		if _, ok := pkgDepGraph.PackageInfos[pkgName]; !ok {
+			return nil, fmt.Errorf("package %q doesn't exist", pkgName)
+		}
+		if shown.Packages == nil {
+			shown.Packages = make(map[string]map[string]shownTaskDefinition)
+		}
+		tasks := make(map[string]shownTaskDefinition)
+		for key := range turboJSON.Pipeline {
+			task := key
+			if util.IsPackageTask(key) {
+				var keyPkgName string
+				keyPkgName, task = util.GetPackageTaskFromId(key)
+				if keyPkgName != pkgName {
+					continue
+				}
+			}
+			taskID := util.GetTaskId(pkgName, task)
+			taskDefinition, _ := turboJSON.Pipeline.GetTaskDefinition(taskID)
+			sources, _ := turboJSON.TaskDefinitionSources(taskID)
+			tasks[task] = showTaskDefinition(taskDefinition, sources)
+		}
+		shown.Packages[pkgName] = tasks
+	}
+	return shown, nil
+}
+
+// setting returns a shownSetting for a string value, which is unset if it's empty
+func setting(value string, source string) shownSetting {
+	if value == "" {
+		return shownSetting{}
+	}
+	return shownSetting{Value: value, Source: source}
+}
+
+// sourceIf returns source if a value was configured, and the default source otherwise
+func sourceIf(configured bool, source string) string {
+	if configured {
+		return source
+	}
+	return fs.SourceDefault
+}
+
+// flagSource returns the source of a value that can only be set by the given flag
+func flagSource(flags *pflag.FlagSet, name string) string {
+	if flags.Changed(name) {
+		return "--" + name
+	}
+	return fs.SourceDefault
+}
+
+// showCacheTiers describes each cache tier as its type, its directory if it's a local tier,
+// and its policy. Local tiers without a directory use cacheDir.
+func showCacheTiers(tiers []fs.CacheTier, cacheDir string) []string {
+	shown := make([]string, len(tiers))
+	for i, tier := range tiers {
+		switch {
+		case tier.Type == fs.CacheTierRemote:
+			shown[i] = fmt.Sprintf("%v (%v)", tier.Type, tier.Policy)
+		case tier.Dir != "":
+			shown[i] = fmt.Sprintf("%v %v (%v)", tier.Type, tier.Dir, tier.Policy)
+		default:
+			shown[i] = fmt.Sprintf("%v %v (%v)", tier.Type, cacheDir, tier.Policy)
+		}
+	}
+	return shown
+}
+
+// cacheTiersSource returns the flags that changed the cache tiers, or else where they were
+// configured
+func cacheTiersSource(flags *pflag.FlagSet, configured bool, rootSource string) string {
+	var changed []string
+	for _, name := range []string{"cache", "remote-cache-read-only", "remote-only"} {
+		if flags.Changed(name) {
+			changed = append(changed, "--"+name)
+		}
+	}
+	if len(changed) > 0 {
+		return strings.Join(changed, ", ")
+	}
+	return sourceIf(configured, rootSource)
+}
+
+// listValue returns l, or an empty list if it's nil, so that lists are always shown as lists
+func listValue(l []string) []string {
+	if l == nil {
+		return []string{}
+	}
+	return l
+}
+
+// showTaskDefinition converts a task definition back to the keys used in turbo.json
+func showTaskDefinition(taskDefinition fs.TaskDefinition, sources fs.TaskDefinitionSources) shownTaskDefinition {
+	dependsOn := append([]string{}, taskDefinition.TaskDependencies...)
+	for _, dependency := range taskDefinition.TopologicalDependencies {
+		dependsOn = append(dependsOn, "^"+dependency)
+	}
+	outputMode, err := util.ToTaskOutputModeString(taskDefinition.OutputMode)
+	if err != nil {
+		outputMode = fmt.Sprintf("%v", taskDefinition.OutputMode)
+	}
+	runner := taskDefinition.Runner
+	if runner == "" {
+		runner = fs.RunnerPackageManager
+	}
+	cleanOutputs := taskDefinition.CleanOutputs
+	if cleanOutputs == "" {
+		cleanOutputs = fs.CleanOutputsNever
+	}
+	var command interface{}
+	if taskDefinition.Command != "" {
+		command = taskDefinition.Command
+	}
+	values := map[string]interface{}{
+		"dependsOn":      dependsOn,
+		"env":            listValue(taskDefinition.EnvVarDependencies),
+		"passThroughEnv": listValue(taskDefinition.PassThroughEnv),
+		"dotEnv":         listValue(taskDefinition.DotEnv),
+		"loadDotEnv":     taskDefinition.LoadDotEnv,
+		"outputs":        listValue(taskDefinition.Outputs),
+		"cache":          taskDefinition.ShouldCache,
+		"inputs":         listValue(taskDefinition.Inputs),
+		"outputMode":     outputMode,
+		"command":        command,
+		"runner":         runner,
+		"cleanOutputs":   cleanOutputs,
+	}
+	shown := make(shownTaskDefinition, len(values))
+	for _, key := range fs.TaskDefinitionKeys {
+		keySources := sources[key]
+		if len(keySources) == 0 {
+			keySources = []string{fs.SourceDefault}
+		}
+		shown[key] = shownTaskKey{Value: values[key], Sources: keySources}
+	}
+	return shown
+}
+
+// formatValue formats a configuration value for the text output
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "-"
+	case []string:
+		if len(v) == 0 {
+			return "-"
+		}
+		return strings.Join(v, ", ")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatSource formats where a value came from for the text output
+func formatSource(sources ...string) string {
+	if len(sources) == 0 || sources[0] == "" {
+		return ui.Dim("(not set)")
+	}
+	return ui.Dim(fmt.Sprintf("(%v)", strings.Join(sources, ", ")))
+}
+
+// render formats the configuration as text, with one setting per line
+func (s *shownConfig) render() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	section := func(title string) {
+		fmt.Fprintln(w, util.Sprintf("${CYAN}${BOLD}%s${RESET}", title))
+	}
+	row := func(indent string, name string, value interface{}, source string) {
+		fmt.Fprintf(w, "%v%v\t%v\t%v\n", indent, name, formatValue(value), source)
+	}
+	settings := func(rows []namedSetting) {
+		for _, r := range rows {
+			row("  ", r.name, r.setting.Value, formatSource(r.setting.Source))
+		}
+		fmt.Fprintln(w)
+	}
+	tasks := func(indent string, definitions map[string]shownTaskDefinition) {
+		names := make([]string, 0, len(definitions))
+		for name := range definitions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(w, util.Sprintf("%s${BOLD}%s${RESET}", indent, name))
+			for _, key := range fs.TaskDefinitionKeys {
+				shown := definitions[name][key]
+				row(indent+"  ", key, shown.Value, formatSource(shown.Sources...))
+			}
+		}
+	}
+
+	section("Remote Cache")
+	settings([]namedSetting{
+		{"apiUrl", s.RemoteCache.APIURL},
+		{"loginUrl", s.RemoteCache.LoginURL},
+		{"teamId", s.RemoteCache.TeamID},
+		{"teamSlug", s.RemoteCache.TeamSlug},
+		{"token", s.RemoteCache.Token},
+		{"signature", s.RemoteCache.Signature},
+		{"publicKey", s.RemoteCache.PublicKey},
+	})
+	section("Local Cache")
+	settings([]namedSetting{
+		{"dir", s.LocalCache.Dir},
+		{"remoteOnly", s.LocalCache.RemoteOnly},
+		{"workers", s.LocalCache.Workers},
+	})
+	section("Cache Tiers")
+	settings([]namedSetting{
+		{"cacheTiers", s.CacheTiers},
+	})
+	section("Global")
+	settings([]namedSetting{
+		{"globalDependencies", s.GlobalDependencies},
+		{"globalEnv", s.GlobalEnv},
+	})
+	section("Pipeline")
+	tasks("  ", s.Pipeline)
+	pkgNames := make([]string, 0, len(s.Packages))
+	for pkgName := range s.Packages {
+		pkgNames = append(pkgNames, pkgName)
+	}
+	sort.Strings(pkgNames)
+	for _, pkgName := range pkgNames {
+		fmt.Fprintln(w)
+		section(fmt.Sprintf("Package %v", pkgName))
+		tasks("  ", s.Packages[pkgName])
+	}
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Synopsis of the config show command
+func (c *ConfigShowCommand) Synopsis() string {
+	cmd := getConfigShowCmd(c.Config, c.UI)
+	return cmd.Short
+}
+
+// Help returns information about the `config show` command
+func (c *ConfigShowCommand) Help() string {
+	cmd := getConfigShowCmd(c.Config, c.UI)
+	return util.HelpForCobraCmd(cmd)
+}
+
+// Run prints the configuration turbo uses
+func (c *ConfigShowCommand) Run(args []string) int {
+	cmd := getConfigShowCmd(c.Config, c.UI)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		c.Config.Logger.Error("error", err)
+		c.UI.Error(fmt.Sprintf("%s%s", ui.ERROR_PREFIX, color.RedString(" %v", err)))
+		return 1
+	}
+	return 0
+}
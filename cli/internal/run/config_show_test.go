@@ -0,0 +1,77 @@
+package run
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+func Test_showTaskDefinition(t *testing.T) {
+	taskDefinition := fs.TaskDefinition{
+		Outputs:                 []string{".next/**"},
+		ShouldCache:             true,
+		EnvVarDependencies:      []string{"API_*"},
+		TopologicalDependencies: []string{"build"},
+		TaskDependencies:        []string{"codegen"},
+		OutputMode:              util.NewTaskOutput,
+	}
+	sources := fs.TaskDefinitionSources{
+		"dependsOn": {"turbo.json"},
+		"env":       {"turbo.json", "apps/web/turbo.json"},
+		"outputs":   {"apps/web/turbo.json"},
+	}
+
+	shown := showTaskDefinition(taskDefinition, sources)
+
+	expected := map[string]shownTaskKey{
+		"dependsOn":  {Value: []string{"codegen", "^build"}, Sources: []string{"turbo.json"}},
+		"env":        {Value: []string{"API_*"}, Sources: []string{"turbo.json", "apps/web/turbo.json"}},
+		"inputs":     {Value: []string{}, Sources: []string{fs.SourceDefault}},
+		"outputMode": {Value: "new-only", Sources: []string{fs.SourceDefault}},
+		"cache":      {Value: true, Sources: []string{fs.SourceDefault}},
+	}
+	for key, want := range expected {
+		if got := shown[key]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", key, got, want)
+		}
+	}
+	if len(shown) != len(fs.TaskDefinitionKeys) {
+		t.Errorf("got %v keys, want %v", len(shown), len(fs.TaskDefinitionKeys))
+	}
+}
+
+func Test_showCacheTiers(t *testing.T) {
+	shown := showCacheTiers([]fs.CacheTier{
+		{Type: fs.CacheTierLocal, Policy: fs.CacheTierReadWrite},
+		{Type: fs.CacheTierLocal, Dir: "/mnt/turbo", Policy: fs.CacheTierWriteOnly},
+		{Type: fs.CacheTierRemote, Policy: fs.CacheTierReadOnly},
+	}, ".cache")
+	expected := []string{"local .cache (read-write)", "local /mnt/turbo (write-only)", "remote (read-only)"}
+	if !reflect.DeepEqual(shown, expected) {
+		t.Errorf("got %v, want %v", shown, expected)
+	}
+}
+
+func Test_shownConfigRender(t *testing.T) {
+	shown := &shownConfig{
+		RemoteCache: shownRemoteCache{
+			APIURL:   shownSetting{Value: "https://vercel.com/api", Source: fs.SourceDefault},
+			TeamSlug: shownSetting{Value: "acme", Source: "TURBO_TEAM"},
+			Token:    shownSetting{Value: _redactedToken, Source: "--token"},
+		},
+		Pipeline: map[string]shownTaskDefinition{
+			"build": showTaskDefinition(fs.TaskDefinition{Outputs: []string{"dist/**"}}, fs.TaskDefinitionSources{"outputs": {"turbo.json"}}),
+		},
+	}
+
+	rendered := shown.render()
+
+	for _, want := range []string{"acme", "TURBO_TEAM", _redactedToken, "dist/**", "(turbo.json)", "(not set)"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("rendered config is missing %q:\n%v", want, rendered)
+		}
+	}
+}
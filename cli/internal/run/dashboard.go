@@ -0,0 +1,516 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/cli"
+	"github.com/spf13/pflag"
+	"github.com/vercel/turborepo/cli/internal/ui"
+	"golang.org/x/term"
+)
+
+// ui custom flag
+const (
+	// _uiStream prints task output as it is written
+	_uiStream = "stream"
+	// _uiTui shows an interactive dashboard of task states
+	_uiTui = "tui"
+)
+
+const _uiHelp = `Set how the progress of a run is shown. Use "stream" to
+print task output as it is written. Use "tui" for an
+interactive dashboard of task states, which falls back
+to "stream" when stdout isn't a terminal. Only the output
+of failed tasks is printed once the dashboard closes.`
+
+// uiValue implements a flag that only accepts the supported ui modes
+type uiValue struct {
+	opts *runOpts
+}
+
+var _ pflag.Value = &uiValue{}
+
+func (u *uiValue) String() string {
+	if u.opts.tui {
+		return _uiTui
+	}
+	return _uiStream
+}
+
+func (u *uiValue) Set(value string) error {
+	switch value {
+	case _uiStream:
+		u.opts.tui = false
+	case _uiTui:
+		u.opts.tui = true
+	default:
+		return fmt.Errorf("invalid ui: %v. Expected %v or %v", value, _uiStream, _uiTui)
+	}
+	return nil
+}
+
+func (u *uiValue) Type() string {
+	return "string"
+}
+
+// dashboardStatus is the state of a task as shown in the dashboard
+type dashboardStatus int
+
+const (
+	dashboardQueued dashboardStatus = iota
+	dashboardRunning
+	dashboardBuilt
+	dashboardCached
+	dashboardFailed
+	dashboardStopped
+	// dashboardSkipped is used for tasks that finished without running, either because
+	// the package has no such script or because the run was stopped before they started.
+	dashboardSkipped
+)
+
+var (
+	_dashboardQueuedColor  = color.New(color.Faint)
+	_dashboardRunningColor = color.New(color.FgYellow)
+	_dashboardDoneColor    = color.New(color.FgGreen)
+	_dashboardFailedColor  = color.New(color.FgRed)
+	_dashboardSelectColor  = color.New(color.Bold)
+)
+
+// dashboardRefreshInterval is how often the dashboard is redrawn
+const dashboardRefreshInterval = 100 * time.Millisecond
+
+// dashboardInputStopTimeout is how long closing the dashboard waits for keyboard input to stop
+// being read. Checking for input only blocks for dashboardRefreshInterval, but a read can block
+// for longer if there turns out to be nothing to read.
+const dashboardInputStopTimeout = 10 * dashboardRefreshInterval
+
+// dashboardTailLines is how many of the most recent lines of each task's output are kept
+// to show beneath the table
+const dashboardTailLines = 200
+
+// dashboard draws a live table of the tasks in a run on the terminal's alternate screen,
+// along with the tail of the selected task's output. The output of running and failed tasks is
+// buffered in memory while the dashboard is shown, and only the last lines of successful tasks
+// are kept. When it is closed, the output of failed tasks is printed, as it is with the grouped
+// log order.
+type dashboard struct {
+	runState *RunState
+	taskIDs  []string
+	out      io.Writer
+	groups   *taskOutputGroups
+	// messages holds output that isn't associated with a task until the dashboard is closed
+	messages *taskOutputGroup
+	// interrupt is called when the user presses ctrl+c, as the terminal won't send a signal in raw mode
+	interrupt func()
+	size      func() (int, int, error)
+	// waitForInput checks whether there are keys to read, it is replaced in tests
+	waitForInput func(file *os.File, timeout time.Duration) (bool, error)
+
+	mu       sync.Mutex
+	outputs  map[string]*taskOutputGroup
+	recent   map[string]*recentLines
+	finished map[string]bool
+	selected int
+	offset   int
+	// follow keeps a running task selected until the user picks one
+	follow      bool
+	expanded    bool
+	interactive bool
+
+	rendering   bool
+	stopCh      chan struct{}
+	doneCh      chan struct{}
+	inputDoneCh chan struct{}
+	restoreOnce sync.Once
+	restoreFns  []func()
+}
+
+// newDashboard creates a dashboard for the given tasks, which are shown in the given order.
+// Output printed once the dashboard is closed goes to terminal, stdout and stderr.
+func newDashboard(runState *RunState, taskIDs []string, terminal cli.Ui, stdout io.Writer, stderr io.Writer, interrupt func()) *dashboard {
+	groups := newTaskOutputGroups(terminal, stdout, stderr)
+	return &dashboard{
+		runState:     runState,
+		taskIDs:      taskIDs,
+		out:          stdout,
+		groups:       groups,
+		messages:     groups.newGroup(""),
+		interrupt:    interrupt,
+		waitForInput: waitForInput,
+		outputs:      make(map[string]*taskOutputGroup),
+		recent:       make(map[string]*recentLines),
+		finished:     make(map[string]bool),
+		follow:       true,
+		expanded:     true,
+		stopCh:       make(chan struct{}),
+		doneCh:       make(chan struct{}),
+		inputDoneCh:  make(chan struct{}),
+	}
+}
+
+// start takes over the terminal and begins drawing the dashboard
+func (d *dashboard) start() error {
+	stdoutFd := int(os.Stdout.Fd())
+	d.size = func() (int, int, error) {
+		return term.GetSize(stdoutFd)
+	}
+	if _, _, err := d.size(); err != nil {
+		return fmt.Errorf("failed to get terminal size: %w", err)
+	}
+	stdinFd := int(os.Stdin.Fd())
+	if term.IsTerminal(stdinFd) {
+		state, err := term.MakeRaw(stdinFd)
+		if err != nil {
+			return fmt.Errorf("failed to read keyboard input: %w", err)
+		}
+		d.restoreFns = append(d.restoreFns, func() { _ = term.Restore(stdinFd, state) })
+		d.interactive = true
+		go d.readInput(os.Stdin)
+	}
+	// Draw on the alternate screen with the cursor hidden, so that the terminal's
+	// scrollback is left intact once we're done.
+	_, _ = io.WriteString(d.out, "\x1b[?1049h\x1b[?25l")
+	d.restoreFns = append(d.restoreFns, func() { _, _ = io.WriteString(d.out, "\x1b[?25h\x1b[?1049l") })
+	d.rendering = true
+	go d.renderLoop()
+	return nil
+}
+
+// restore stops drawing the dashboard and returns the terminal to its previous state.
+// It is safe to call more than once, and from a signal handler. If keyboard input doesn't
+// stop being read in time, the terminal is restored anyway rather than hanging turbo.
+func (d *dashboard) restore() {
+	d.restoreOnce.Do(func() {
+		close(d.stopCh)
+		if d.rendering {
+			<-d.doneCh
+		}
+		if d.interactive {
+			select {
+			case <-d.inputDoneCh:
+			case <-time.After(dashboardInputStopTimeout):
+			}
+		}
+		for i := len(d.restoreFns) - 1; i >= 0; i-- {
+			d.restoreFns[i]()
+		}
+	})
+}
+
+// stop restores the terminal, then prints any messages that were sent while the dashboard
+// was shown, followed by the output of failed tasks.
+func (d *dashboard) stop() {
+	d.restore()
+	d.groups.finish(d.messages, false)
+	d.groups.printFailures()
+}
+
+// taskOutput returns the sink for the terminal output of the given task
+func (d *dashboard) taskOutput(taskID string) *taskOutputGroup {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	group := d.groups.newGroup(taskID)
+	group.recent = newRecentLines(dashboardTailLines)
+	d.outputs[taskID] = group
+	d.recent[taskID] = group.recent
+	return group
+}
+
+// finish records that the given task is no longer running. The output of failed tasks is
+// held onto until the dashboard is closed, while successful tasks only keep their last lines.
+func (d *dashboard) finish(taskID string, failed bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.finished[taskID] = true
+	group, ok := d.outputs[taskID]
+	if !ok {
+		return
+	}
+	delete(d.outputs, taskID)
+	if failed {
+		d.groups.finish(group, true)
+	}
+}
+
+func (d *dashboard) renderLoop() {
+	defer close(d.doneCh)
+	ticker := time.NewTicker(dashboardRefreshInterval)
+	defer ticker.Stop()
+	for {
+		d.draw()
+		select {
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *dashboard) draw() {
+	width, height, err := d.size()
+	if err != nil {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	for i, line := range d.render(width, height, time.Now()) {
+		if i > 0 {
+			// The terminal may be in raw mode, so we have to return the cursor ourselves
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString(line)
+		sb.WriteString("\x1b[K")
+	}
+	sb.WriteString("\x1b[J")
+	_, _ = io.WriteString(d.out, sb.String())
+}
+
+// taskStatus must be called with d.mu held
+func (d *dashboard) taskStatus(taskID string, now time.Time) (dashboardStatus, time.Duration) {
+	state, ok := d.runState.TaskState(taskID)
+	if !ok {
+		return dashboardQueued, 0
+	}
+	switch state.Status {
+	case TargetBuilding:
+		if d.finished[taskID] {
+			return dashboardSkipped, 0
+		}
+		return dashboardRunning, now.Sub(state.StartAt)
+	case TargetBuildStopped:
+		return dashboardStopped, state.Duration
+	case TargetBuilt:
+		return dashboardBuilt, state.Duration
+	case TargetCached:
+		return dashboardCached, state.Duration
+	default:
+		return dashboardFailed, state.Duration
+	}
+}
+
+func formatDashboardStatus(status dashboardStatus, duration time.Duration) (string, string, *color.Color) {
+	duration = duration.Truncate(100 * time.Millisecond)
+	switch status {
+	case dashboardRunning:
+		return "▶", fmt.Sprintf("running %v", duration), _dashboardRunningColor
+	case dashboardBuilt:
+		return "✓", fmt.Sprintf("done %v", duration), _dashboardDoneColor
+	case dashboardCached:
+		return "✓", "cached", _dashboardDoneColor
+	case dashboardFailed:
+		return "✗", fmt.Sprintf("failed %v", duration), _dashboardFailedColor
+	case dashboardStopped:
+		return "■", fmt.Sprintf("stopped %v", duration), _dashboardFailedColor
+	case dashboardSkipped:
+		return "-", "skipped", _dashboardQueuedColor
+	default:
+		return "·", "queued", _dashboardQueuedColor
+	}
+}
+
+// render returns the lines of the dashboard for a terminal of the given size
+func (d *dashboard) render(width int, height int, now time.Time) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	statuses := make([]dashboardStatus, len(d.taskIDs))
+	durations := make([]time.Duration, len(d.taskIDs))
+	counts := make(map[dashboardStatus]int)
+	nameWidth := 0
+	for i, taskID := range d.taskIDs {
+		statuses[i], durations[i] = d.taskStatus(taskID, now)
+		counts[statuses[i]]++
+		if len(taskID) > nameWidth {
+			nameWidth = len(taskID)
+		}
+	}
+	if d.follow && (len(statuses) <= d.selected || statuses[d.selected] != dashboardRunning) {
+		for i, status := range statuses {
+			if status == dashboardRunning {
+				d.selected = i
+				break
+			}
+		}
+	}
+
+	// Overall progress
+	done := len(d.taskIDs) - counts[dashboardQueued] - counts[dashboardRunning]
+	lines := []string{truncateLine(fmt.Sprintf(
+		"%v %v/%v tasks · %v running · %v cached · %v failed · %v",
+		progressBar(done, len(d.taskIDs), 20),
+		done,
+		len(d.taskIDs),
+		counts[dashboardRunning],
+		counts[dashboardCached],
+		counts[dashboardFailed],
+		now.Sub(d.runState.startedAt).Truncate(100*time.Millisecond),
+	), width), ""}
+
+	// The task table shares the screen with the output of the selected task
+	tailHeight := 0
+	if d.expanded {
+		tailHeight = (height - 3) / 2
+	}
+	tableHeight := height - len(lines) - 1 - tailHeight
+	if tableHeight < 1 {
+		tableHeight = 1
+	}
+	if d.selected < d.offset {
+		d.offset = d.selected
+	} else if d.selected >= d.offset+tableHeight {
+		d.offset = d.selected - tableHeight + 1
+	}
+	for i := d.offset; i < len(d.taskIDs) && i < d.offset+tableHeight; i++ {
+		symbol, label, statusColor := formatDashboardStatus(statuses[i], durations[i])
+		cursor := " "
+		if i == d.selected {
+			cursor = "›"
+		}
+		row := truncateLine(fmt.Sprintf("%v %v %-*v  %v", cursor, symbol, nameWidth, d.taskIDs[i], label), width)
+		if i == d.selected {
+			row = _dashboardSelectColor.Sprint(row)
+		}
+		lines = append(lines, statusColor.Sprint(row))
+	}
+
+	if len(d.taskIDs) == 0 {
+		return lines
+	}
+	selectedTaskID := d.taskIDs[d.selected]
+	header := fmt.Sprintf("── %v ──", selectedTaskID)
+	if d.interactive {
+		header += " ↑/↓ select · enter show/hide output · ctrl+c stop"
+	}
+	lines = append(lines, ui.Dim(truncateLine(header, width)))
+	if recent, ok := d.recent[selectedTaskID]; ok && tailHeight > 0 {
+		for _, line := range recent.last(tailHeight) {
+			lines = append(lines, truncateLine(sanitizeLine(line), width))
+		}
+	}
+	return lines
+}
+
+// progressBar renders a bar of the given width, filled in proportion to done / total
+func progressBar(done int, total int, width int) string {
+	filled := width
+	if total > 0 {
+		filled = width * done / total
+	}
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// sanitizeLine strips control sequences from a line of task output so that it can
+// be drawn in a fixed region of the screen.
+func sanitizeLine(line string) string {
+	line = ui.StripAnsi(line)
+	// Progress bars and spinners redraw a line with carriage returns, only the last one is visible
+	if i := strings.LastIndex(line, "\r"); i >= 0 {
+		line = line[i+1:]
+	}
+	return strings.ReplaceAll(line, "\t", "    ")
+}
+
+// truncateLine shortens line to fit in the given number of columns
+func truncateLine(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	if width <= 1 {
+		return string(runes[:width])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// readInput handles keys pressed in the terminal until the dashboard is stopped. It only
+// reads when there is input waiting, so that it doesn't hold onto the terminal, or take
+// input meant for whatever runs after turbo, once the dashboard is closed.
+func (d *dashboard) readInput(file *os.File) {
+	defer close(d.inputDoneCh)
+	buf := make([]byte, 64)
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		default:
+		}
+		ready, err := d.waitForInput(file, dashboardRefreshInterval)
+		if err != nil {
+			return
+		}
+		if !ready {
+			continue
+		}
+		n, err := file.Read(buf)
+		if err != nil {
+			return
+		}
+		d.handleInput(buf[:n])
+	}
+}
+
+var (
+	_keyUp      = [][]byte{[]byte("\x1b[A"), []byte("\x1bOA")}
+	_keyDown    = [][]byte{[]byte("\x1b[B"), []byte("\x1bOB")}
+	_keyCtrlC   = byte(3)
+	_keyEnter   = byte('\r')
+	_keyNewline = byte('\n')
+)
+
+func (d *dashboard) handleInput(input []byte) {
+	for len(input) > 0 {
+		if prefix := matchKey(input, _keyUp); prefix > 0 {
+			d.move(-1)
+			input = input[prefix:]
+			continue
+		}
+		if prefix := matchKey(input, _keyDown); prefix > 0 {
+			d.move(1)
+			input = input[prefix:]
+			continue
+		}
+		switch input[0] {
+		case 'k':
+			d.move(-1)
+		case 'j':
+			d.move(1)
+		case _keyEnter, _keyNewline, ' ':
+			d.mu.Lock()
+			d.expanded = !d.expanded
+			d.mu.Unlock()
+		case _keyCtrlC:
+			// Stopping the run waits for tasks to exit, don't block reading input while that happens
+			go d.interrupt()
+		}
+		input = input[1:]
+	}
+}
+
+func matchKey(input []byte, sequences [][]byte) int {
+	for _, sequence := range sequences {
+		if bytes.HasPrefix(input, sequence) {
+			return len(sequence)
+		}
+	}
+	return 0
+}
+
+func (d *dashboard) move(delta int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.follow = false
+	d.selected += delta
+	if d.selected >= len(d.taskIDs) {
+		d.selected = len(d.taskIDs) - 1
+	}
+	if d.selected < 0 {
+		d.selected = 0
+	}
+}
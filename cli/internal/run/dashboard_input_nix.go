@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitForInput waits up to timeout for file to have input to read, and reports whether it does
+func waitForInput(file *os.File, timeout time.Duration) (bool, error) {
+	fds := []unix.PollFd{{Fd: int32(file.Fd()), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if errors.Is(err, unix.EINTR) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
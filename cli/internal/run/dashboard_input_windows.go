@@ -0,0 +1,94 @@
+//go:build windows
+// +build windows
+
+package run
+
+import (
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	_kernel32              = windows.NewLazySystemDLL("kernel32.dll")
+	_procPeekConsoleInputW = _kernel32.NewProc("PeekConsoleInputW")
+	_procReadConsoleInputW = _kernel32.NewProc("ReadConsoleInputW")
+)
+
+// _consoleKeyEvent is the event type of input records for keys being pressed or released
+const _consoleKeyEvent = 0x0001
+
+// inputRecord is an INPUT_RECORD holding a KEY_EVENT_RECORD. The other kinds of events
+// are no larger, so it is big enough to hold any record.
+type inputRecord struct {
+	eventType       uint16
+	_               uint16
+	keyDown         int32
+	repeatCount     uint16
+	virtualKeyCode  uint16
+	virtualScanCode uint16
+	char            uint16
+	controlKeyState uint32
+}
+
+// waitForInput waits up to timeout for file to have input to read, and reports whether it does.
+// A console's input handle is also signaled for key releases, focus and mouse events, which
+// reading the file skips over while it blocks for the next key press. Those events are
+// discarded here so that a read doesn't block once we report that there is input.
+func waitForInput(file *os.File, timeout time.Duration) (bool, error) {
+	handle := windows.Handle(file.Fd())
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining < 0 {
+			remaining = 0
+		}
+		event, err := windows.WaitForSingleObject(handle, uint32(remaining.Milliseconds()))
+		if err != nil {
+			return false, err
+		}
+		if event != windows.WAIT_OBJECT_0 {
+			return false, nil
+		}
+		var mode uint32
+		if windows.GetConsoleMode(handle, &mode) != nil {
+			// Not a console, so anything that signals the handle can be read
+			return true, nil
+		}
+		ready, err := discardConsoleEvents(handle)
+		if err != nil || ready {
+			return ready, err
+		}
+		if remaining == 0 {
+			return false, nil
+		}
+	}
+}
+
+// discardConsoleEvents removes the events at the front of the console's input queue that
+// don't produce any characters, and reports whether there is a key press left to read.
+// Keys such as arrows are sent as characters, as the terminal is in raw mode with virtual
+// terminal input enabled.
+func discardConsoleEvents(handle windows.Handle) (bool, error) {
+	records := make([]inputRecord, 16)
+	var n uint32
+	if r, _, err := _procPeekConsoleInputW.Call(uintptr(handle), uintptr(unsafe.Pointer(&records[0])), uintptr(len(records)), uintptr(unsafe.Pointer(&n))); r == 0 {
+		return false, err
+	}
+	skip := uint32(0)
+	for ; skip < n; skip++ {
+		record := records[skip]
+		if record.eventType == _consoleKeyEvent && record.keyDown != 0 && record.char != 0 {
+			break
+		}
+	}
+	if skip > 0 {
+		var read uint32
+		if r, _, err := _procReadConsoleInputW.Call(uintptr(handle), uintptr(unsafe.Pointer(&records[0])), uintptr(skip), uintptr(unsafe.Pointer(&read))); r == 0 {
+			return false, err
+		}
+	}
+	return skip < n, nil
+}
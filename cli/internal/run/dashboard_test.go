@@ -0,0 +1,203 @@
+package run
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/cli"
+)
+
+func Test_dashboardRender(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	startedAt := time.Now()
+	runState := NewRunState(startedAt, "")
+	taskIDs := []string{"docs#build", "lib#build", "web#build", "web#test"}
+	out := &bytes.Buffer{}
+	d := newDashboard(runState, taskIDs, &cli.BasicUi{Writer: out, ErrorWriter: out}, out, out, func() {})
+
+	// lib#build came from the cache, docs#build failed, web#build is running and web#test is queued
+	runState.Run("lib#build")(TargetCached, nil)
+	d.finish("lib#build", false)
+	docs := d.taskOutput("docs#build")
+	runState.Run("docs#build")(TargetBuildFailed, errors.New("exit 1"))
+	docs.Error("docs:build: ERROR: command finished with error")
+	d.finish("docs#build", true)
+	web := d.taskOutput("web#build")
+	runState.Run("web#build")
+	_, _ = web.Write([]byte("web:build: one\nweb:build: two\n\x1b[32mweb:build: three\x1b[0m\n"))
+
+	lines := d.render(80, 12, time.Now())
+	if !strings.Contains(lines[0], "2/4 tasks · 1 running · 1 cached · 1 failed") {
+		t.Errorf("unexpected progress line %q", lines[0])
+	}
+	expectedRows := []struct {
+		prefix string
+		status string
+	}{
+		{"  ✗ docs#build", "failed"},
+		{"  ✓ lib#build", "cached"},
+		// running tasks are selected until the user picks one
+		{"› ▶ web#build", "running"},
+		{"  · web#test", "queued"},
+	}
+	for i, expected := range expectedRows {
+		row := lines[2+i]
+		if !strings.HasPrefix(row, expected.prefix) || !strings.Contains(row, expected.status) {
+			t.Errorf("row %v: got %q, want %v ... %v", i, row, expected.prefix, expected.status)
+		}
+	}
+	if !strings.HasPrefix(lines[6], "── web#build ──") {
+		t.Errorf("expected output header for web#build, got %q", lines[6])
+	}
+	// (12 - 3) / 2 lines of output fit beneath the table
+	expectedTail := []string{"web:build: one", "web:build: two", "web:build: three"}
+	if tail := lines[7:]; strings.Join(tail, "\n") != strings.Join(expectedTail, "\n") {
+		t.Errorf("output tail: got %q, want %q", tail, expectedTail)
+	}
+
+	// Moving the selection stops following running tasks, and enter hides the output
+	d.handleInput([]byte("\x1b[A\x1b[A\r"))
+	lines = d.render(80, 12, time.Now())
+	if !strings.HasPrefix(lines[2], "› ✗ docs#build") {
+		t.Errorf("expected docs#build to be selected, got %q", lines[2])
+	}
+	if len(lines) != 7 || !strings.HasPrefix(lines[6], "── docs#build ──") {
+		t.Errorf("expected only the output header for docs#build, got %q", lines[6:])
+	}
+
+	// Closing the dashboard prints the output of failed tasks
+	d.stop()
+	if !strings.Contains(out.String(), "docs#build failed") || !strings.Contains(out.String(), "docs:build: ERROR: command finished with error") {
+		t.Errorf("expected failed task output to be printed, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "web:build") {
+		t.Errorf("expected output of successful tasks to stay hidden, got %q", out.String())
+	}
+}
+
+func Test_truncateLine(t *testing.T) {
+	testCases := []struct {
+		line     string
+		width    int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"a bit too long", 10, "a bit too…"},
+		{"▶ web#build", 4, "▶ w…"},
+	}
+	for _, tc := range testCases {
+		if got := truncateLine(tc.line, tc.width); got != tc.expected {
+			t.Errorf("truncateLine(%q, %v) = %q, want %q", tc.line, tc.width, got, tc.expected)
+		}
+	}
+}
+
+func Test_dashboardStopsReadingInput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	defer func() { _ = w.Close() }()
+	d := newDashboard(NewRunState(time.Now(), ""), []string{"lib#build", "web#build"}, cli.NewMockUi(), &bytes.Buffer{}, &bytes.Buffer{}, func() {})
+	d.interactive = true
+	go d.readInput(r)
+
+	_, _ = w.Write([]byte("j"))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d.mu.Lock()
+		selected := d.selected
+		d.mu.Unlock()
+		if selected == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected input to move the selection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// restore waits for the reader to stop, so input written afterwards is left unread
+	d.restore()
+	_, _ = w.Write([]byte("k"))
+	_ = w.Close()
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read the rest of the input: %v", err)
+	}
+	if string(rest) != "k" {
+		t.Errorf("expected input after the dashboard closed to be left unread, got %q", rest)
+	}
+	if d.selected != 1 {
+		t.Errorf("expected input after the dashboard closed to be ignored, selection is %v", d.selected)
+	}
+}
+
+func Test_dashboardRestoresWhenInputReadBlocks(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	defer func() { _ = w.Close() }()
+	d := newDashboard(NewRunState(time.Now(), ""), []string{"web#build"}, cli.NewMockUi(), &bytes.Buffer{}, &bytes.Buffer{}, func() {})
+	d.interactive = true
+	restored := false
+	d.restoreFns = append(d.restoreFns, func() { restored = true })
+	// Like a console that was signaled by a key release, report input that isn't there to read
+	d.waitForInput = func(*os.File, time.Duration) (bool, error) {
+		return true, nil
+	}
+	go d.readInput(r)
+
+	done := make(chan struct{})
+	go func() {
+		d.restore()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected restore to return while the input reader is blocked")
+	}
+	if !restored {
+		t.Error("expected the terminal to be restored")
+	}
+}
+
+func Test_dashboardKeepsRecentLinesOfSuccessfulTasks(t *testing.T) {
+	runState := NewRunState(time.Now(), "")
+	out := &bytes.Buffer{}
+	d := newDashboard(runState, []string{"web#build"}, &cli.BasicUi{Writer: out, ErrorWriter: out}, out, out, func() {})
+	web := d.taskOutput("web#build")
+	runState.Run("web#build")(TargetBuilt, nil)
+	for i := 0; i < dashboardTailLines+50; i++ {
+		_, _ = web.Write([]byte(fmt.Sprintf("line %v\n", i)))
+	}
+	_, _ = web.Write([]byte("partial"))
+	d.finish("web#build", false)
+
+	if _, ok := d.outputs["web#build"]; ok {
+		t.Error("expected the output of a successful task to be dropped")
+	}
+	tail := d.recent["web#build"].last(3)
+	expected := []string{fmt.Sprintf("line %v", dashboardTailLines+48), fmt.Sprintf("line %v", dashboardTailLines+49), "partial"}
+	if strings.Join(tail, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("recent lines: got %q, want %q", tail, expected)
+	}
+	if all := d.recent["web#build"].last(dashboardTailLines * 2); len(all) != dashboardTailLines+1 {
+		t.Errorf("expected %v recent lines to be kept, got %v", dashboardTailLines+1, len(all))
+	}
+}
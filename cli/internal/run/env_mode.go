@@ -0,0 +1,137 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/vercel/turborepo/cli/internal/env"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/taskhash"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// env mode custom flag
+const (
+	// _envModeLoose launches tasks with turbo's entire environment
+	_envModeLoose = "loose"
+	// _envModeStrict launches tasks with only the env vars they declare
+	_envModeStrict = "strict"
+)
+
+const _envModeHelp = `Set the environment tasks are launched with. Use "loose"
+to pass all of turbo's env vars to tasks. Use "strict" to
+only pass the env vars declared in turbo.json, so that
+undeclared variables can't affect task outputs.`
+
+// Variables that tasks can always read in strict mode, as they are needed to locate
+// and run programs. They don't affect task hashes.
+var _strictModePassThroughEnv = []string{
+	"PATH",
+	"HOME",
+	"USER",
+	"SHELL",
+	"TERM",
+	"COLORTERM",
+	"FORCE_COLOR",
+	"NO_COLOR",
+	"LANG",
+	"LC_*",
+	"TZ",
+	"TMPDIR",
+	"TMP",
+	"TEMP",
+	"CI",
+	// Windows, where os.Environ keeps the casing that variables were defined with
+	"APPDATA",
+	"ComSpec",
+	"LOCALAPPDATA",
+	"Path",
+	"PATHEXT",
+	"ProgramData",
+	"ProgramFiles",
+	"SystemDrive",
+	"SystemRoot",
+	"USERNAME",
+	"USERPROFILE",
+	"windir",
+}
+
+// envModeValue implements a flag that only accepts the supported env modes
+type envModeValue struct {
+	opts *runOpts
+}
+
+var _ pflag.Value = &envModeValue{}
+
+func (e *envModeValue) String() string {
+	if e.opts.strictEnv {
+		return _envModeStrict
+	}
+	return _envModeLoose
+}
+
+func (e *envModeValue) Set(value string) error {
+	switch value {
+	case _envModeLoose:
+		e.opts.strictEnv = false
+	case _envModeStrict:
+		e.opts.strictEnv = true
+	default:
+		return fmt.Errorf("invalid env mode: %v. Expected %v or %v", value, _envModeLoose, _envModeStrict)
+	}
+	return nil
+}
+
+func (e *envModeValue) Type() string {
+	return "string"
+}
+
+// taskEnv returns the env vars to launch the given task with. In strict mode, these are limited
+// to the variables that affect the task's hash, those it lists in passThroughEnv, and a few
+// that are needed to run programs at all. Variables from the task's .env files are added if it
+// sets loadDotEnv, unless they are already set.
+func (e *execContext) taskEnv(pt *nodes.PackageTask) []string {
+	var envPairs []string
+	if e.rs.Opts.runOpts.strictEnv {
+		envPairs = strictTaskEnv(pt, e.globalEnv)
+	} else {
+		envPairs = os.Environ()
+	}
+	if !pt.TaskDefinition.LoadDotEnv {
+		return envPairs
+	}
+	setVars := make(util.Set)
+	for _, pair := range envPairs {
+		setVars.Add(strings.SplitN(pair, "=", 2)[0])
+	}
+	for _, pair := range env.DotEnvPairs(e.taskHashes.DotEnv(pt.TaskID)) {
+		if !setVars.Includes(strings.SplitN(pair, "=", 2)[0]) {
+			envPairs = append(envPairs, pair)
+		}
+	}
+	return envPairs
+}
+
+// strictTaskEnv returns the env vars that the given task can read in strict mode
+func strictTaskEnv(pt *nodes.PackageTask, globalEnv []string) []string {
+	// Each set of patterns is resolved separately, so that a negation only
+	// applies to the set it appears in.
+	taskEnv := make(util.Set)
+	for _, pairs := range [][]string{
+		env.GetEnvPairs(_strictModePassThroughEnv, nil),
+		env.GetEnvPairs(_defaultEnvVars, nil),
+		env.GetEnvPairs(globalEnv, nil),
+		env.GetEnvPairs(pt.TaskDefinition.EnvVarDependencies, taskhash.FrameworkEnvPrefixes(pt.Pkg)),
+		env.GetEnvPairs(pt.TaskDefinition.PassThroughEnv, nil),
+	} {
+		for _, pair := range pairs {
+			taskEnv.Add(pair)
+		}
+	}
+	envPairs := taskEnv.UnsafeListOfStrings()
+	sort.Strings(envPairs)
+	return envPairs
+}
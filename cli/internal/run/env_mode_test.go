@@ -0,0 +1,51 @@
+package run
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+)
+
+func Test_taskEnv(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("API_URL", "https://example.com")
+	t.Setenv("API_SECRET", "hunter2")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("CI_COMMIT", "abc123")
+	t.Setenv("UNDECLARED", "leaked")
+	pt := &nodes.PackageTask{
+		TaskID: "web#build",
+		Pkg:    &fs.PackageJSON{Name: "web"},
+		TaskDefinition: &fs.TaskDefinition{
+			EnvVarDependencies: []string{"API_*", "!API_SECRET", "UNSET_VAR"},
+			PassThroughEnv:     []string{"AWS_*"},
+		},
+	}
+	ec := &execContext{
+		rs:        &runSpec{Opts: &Opts{}},
+		globalEnv: []string{"CI_*"},
+	}
+
+	if got := ec.taskEnv(pt); !reflect.DeepEqual(got, os.Environ()) {
+		t.Errorf("expected the full environment in loose mode, got %v", got)
+	}
+
+	ec.rs.Opts.runOpts.strictEnv = true
+	got := map[string]bool{}
+	for _, pair := range ec.taskEnv(pt) {
+		got[pair] = true
+	}
+	for _, pair := range []string{"PATH=/usr/bin", "API_URL=https://example.com", "AWS_REGION=us-east-1", "CI_COMMIT=abc123"} {
+		if !got[pair] {
+			t.Errorf("expected %v to be passed to the task in strict mode", pair)
+		}
+	}
+	for _, pair := range []string{"API_SECRET=hunter2", "UNDECLARED=leaked", "UNSET_VAR="} {
+		if got[pair] {
+			t.Errorf("expected %v not to be passed to the task in strict mode", pair)
+		}
+	}
+}
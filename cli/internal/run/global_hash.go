@@ -2,12 +2,12 @@ package run
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/vercel/turborepo/cli/internal/env"
 	"github.com/vercel/turborepo/cli/internal/fs"
 	"github.com/vercel/turborepo/cli/internal/globby"
 	"github.com/vercel/turborepo/cli/internal/hashing"
@@ -21,27 +21,45 @@ const _globalCacheKey = "Real G's move in silence like lasagna"
 // Variables that we always include
 var _defaultEnvVars = []string{
 	"VERCEL_ANALYTICS_ID",
+	// any variable that includes "THASH"
+	"*THASH*",
 }
 
-func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON, pipeline fs.Pipeline, externalGlobalDependencies []string, packageManager *packagemanager.PackageManager, logger hclog.Logger, env []string) (string, error) {
+// globalEnvPatterns returns the patterns of env vars that affect the hashes of all tasks. These
+// are listed in globalEnv, or prefixed with $ in globalDependencies. _defaultEnvVars aren't
+// included, since they're resolved separately so that negations can't exclude them.
+func globalEnvPatterns(globalDependencies []string, globalEnv []string) []string {
+	var patterns []string
+	for _, v := range globalDependencies {
+		if strings.HasPrefix(v, "$") {
+			patterns = append(patterns, strings.TrimPrefix(v, "$"))
+		}
+	}
+	return append(patterns, globalEnv...)
+}
+
+// globalHashableEnvPairs returns the sorted key=value pairs of the env vars that affect the
+// hashes of all tasks: _defaultEnvVars, and the variables matching envPatterns.
+func globalHashableEnvPairs(envPatterns []string) []string {
+	pairs := util.SetFromStrings(env.GetHashableEnvPairs(_defaultEnvVars, nil))
+	for _, pair := range env.GetHashableEnvPairs(envPatterns, nil) {
+		pairs.Add(pair)
+	}
+	sortedPairs := pairs.UnsafeListOfStrings()
+	sort.Strings(sortedPairs)
+	return sortedPairs
+}
+
+func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJSON, pipeline fs.Pipeline, externalGlobalDependencies []string, envPatterns []string, packageManager *packagemanager.PackageManager, logger hclog.Logger) (string, error) {
 	// Calculate the global hash
 	globalDeps := make(util.Set)
 
-	globalHashableEnvNames := []string{}
-	globalHashableEnvPairs := []string{}
 	// Calculate global file and env var dependencies
-	for _, builtinEnvVar := range _defaultEnvVars {
-		globalHashableEnvNames = append(globalHashableEnvNames, builtinEnvVar)
-		globalHashableEnvPairs = append(globalHashableEnvPairs, fmt.Sprintf("%v=%v", builtinEnvVar, os.Getenv(builtinEnvVar)))
-	}
+	globalHashableEnvPairs := globalHashableEnvPairs(envPatterns)
 	if len(externalGlobalDependencies) > 0 {
 		var globs []string
 		for _, v := range externalGlobalDependencies {
-			if strings.HasPrefix(v, "$") {
-				trimmed := strings.TrimPrefix(v, "$")
-				globalHashableEnvNames = append(globalHashableEnvNames, trimmed)
-				globalHashableEnvPairs = append(globalHashableEnvPairs, fmt.Sprintf("%v=%v", trimmed, os.Getenv(trimmed)))
-			} else {
+			if !strings.HasPrefix(v, "$") {
 				globs = append(globs, v)
 			}
 		}
@@ -63,14 +81,10 @@ func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJS
 		}
 	}
 
-	// get system env vars for hashing purposes, these include any variable that includes "TURBO"
-	// that is NOT TURBO_TOKEN or TURBO_TEAM or TURBO_BINARY_PATH.
-	names, pairs := getHashableTurboEnvVarsFromOs(env)
-	globalHashableEnvNames = append(globalHashableEnvNames, names...)
-	globalHashableEnvPairs = append(globalHashableEnvPairs, pairs...)
-	// sort them for consistent hashing
-	sort.Strings(globalHashableEnvNames)
-	sort.Strings(globalHashableEnvPairs)
+	globalHashableEnvNames := make([]string, len(globalHashableEnvPairs))
+	for i, pair := range globalHashableEnvPairs {
+		globalHashableEnvNames[i] = strings.SplitN(pair, "=", 2)[0]
+	}
 	logger.Debug("global hash env vars", "vars", globalHashableEnvNames)
 
 	if !util.IsYarn(packageManager.Name) {
@@ -109,18 +123,3 @@ func calculateGlobalHash(rootpath fs.AbsolutePath, rootPackageJSON *fs.PackageJS
 	}
 	return globalHash, nil
 }
-
-// getHashableTurboEnvVarsFromOs returns a list of environment variables names and
-// that are safe to include in the global hash
-func getHashableTurboEnvVarsFromOs(env []string) ([]string, []string) {
-	var justNames []string
-	var pairs []string
-	for _, e := range env {
-		kv := strings.SplitN(e, "=", 2)
-		if strings.Contains(kv[0], "THASH") {
-			justNames = append(justNames, kv[0])
-			pairs = append(pairs, e)
-		}
-	}
-	return justNames, pairs
-}
@@ -5,22 +5,48 @@ import (
 	"testing"
 )
 
-func Test_getHashableTurboEnvVarsFromOs(t *testing.T) {
-	env := []string{
-		"SOME_ENV_VAR=excluded",
-		"SOME_OTHER_ENV_VAR=excluded",
-		"FIRST_THASH_ENV_VAR=first",
-		"TURBO_TOKEN=never",
-		"SOME_OTHER_THASH_ENV_VAR=second",
-		"TURBO_TEAM=never",
+func Test_globalEnvPatterns(t *testing.T) {
+	globalDependencies := []string{"$GITHUB_TOKEN", "tsconfig.json", "$NODE_ENV"}
+	globalEnv := []string{"CI_*", "!CI_SECRET"}
+	got := globalEnvPatterns(globalDependencies, globalEnv)
+	want := []string{"GITHUB_TOKEN", "NODE_ENV", "CI_*", "!CI_SECRET"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("globalEnvPatterns() got = %v, want %v", got, want)
 	}
-	gotNames, gotPairs := getHashableTurboEnvVarsFromOs(env)
-	wantNames := []string{"FIRST_THASH_ENV_VAR", "SOME_OTHER_THASH_ENV_VAR"}
-	wantPairs := []string{"FIRST_THASH_ENV_VAR=first", "SOME_OTHER_THASH_ENV_VAR=second"}
-	if !reflect.DeepEqual(wantNames, gotNames) {
-		t.Errorf("getHashableTurboEnvVarsFromOs() env names got = %v, want %v", gotNames, wantNames)
+}
+
+func Test_globalEnvPatterns_THASH(t *testing.T) {
+	t.Setenv("SOME_ENV_VAR", "excluded")
+	t.Setenv("FIRST_THASH_ENV_VAR", "first")
+	t.Setenv("SOME_OTHER_THASH_ENV_VAR", "second")
+	t.Setenv("VERCEL_ANALYTICS_ID", "analytics")
+	got := globalHashableEnvPairs(globalEnvPatterns(nil, nil))
+	want := []string{"FIRST_THASH_ENV_VAR=first", "SOME_OTHER_THASH_ENV_VAR=second", "VERCEL_ANALYTICS_ID=analytics"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hashable global env pairs got = %v, want %v", got, want)
+	}
+}
+
+func Test_globalHashableEnvPairs_Negations(t *testing.T) {
+	t.Setenv("FIRST_THASH_ENV_VAR", "first")
+	t.Setenv("VERCEL_ANALYTICS_ID", "analytics")
+	t.Setenv("VERCEL_URL", "excluded")
+	t.Setenv("CI_TOKEN", "ci")
+	// Negations in globalEnv don't exclude the variables that are always included
+	testCases := [][]string{
+		{"!*"},
+		{"VERCEL_*", "!VERCEL_*", "!*THASH*"},
+	}
+	for _, globalEnv := range testCases {
+		got := globalHashableEnvPairs(globalEnvPatterns(nil, globalEnv))
+		want := []string{"FIRST_THASH_ENV_VAR=first", "VERCEL_ANALYTICS_ID=analytics"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("hashable global env pairs for %v got = %v, want %v", globalEnv, got, want)
+		}
 	}
-	if !reflect.DeepEqual(wantPairs, gotPairs) {
-		t.Errorf("getHashableTurboEnvVarsFromOs() env pairs got = %v, want %v", gotPairs, wantPairs)
+	got := globalHashableEnvPairs(globalEnvPatterns(nil, []string{"CI_*", "VERCEL_ANALYTICS_ID"}))
+	want := []string{"CI_TOKEN=ci", "FIRST_THASH_ENV_VAR=first", "VERCEL_ANALYTICS_ID=analytics"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hashable global env pairs got = %v, want %v", got, want)
 	}
 }
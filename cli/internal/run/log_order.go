@@ -0,0 +1,301 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/cli"
+	"github.com/spf13/pflag"
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/ui"
+)
+
+// log order custom flag
+const (
+	// _logOrderStream prints each line of task output as soon as it is written
+	_logOrderStream = "stream"
+	// _logOrderGrouped buffers the output of each task and prints it as one block
+	// when the task finishes
+	_logOrderGrouped = "grouped"
+)
+
+const _logOrderHelp = `Set the order in which task output is printed. Use "stream"
+to print lines as soon as they are written. Use "grouped"
+to print the output of each task as one block when it
+finishes, with failed tasks printed last. Tasks with
+caching turned off, and runs with --parallel, are
+streamed, since they may never finish.`
+
+// logOrderValue implements a flag that only accepts the supported log orders
+type logOrderValue struct {
+	opts *runOpts
+}
+
+var _ pflag.Value = &logOrderValue{}
+
+func (l *logOrderValue) String() string {
+	if l.opts.logOrder == "" {
+		return _logOrderStream
+	}
+	return l.opts.logOrder
+}
+
+func (l *logOrderValue) Set(value string) error {
+	switch value {
+	case _logOrderStream:
+		// stream is the default, so we leave the option unset
+		l.opts.logOrder = ""
+	case _logOrderGrouped:
+		l.opts.logOrder = value
+	default:
+		return fmt.Errorf("invalid log order: %v. Expected %v or %v", value, _logOrderStream, _logOrderGrouped)
+	}
+	return nil
+}
+
+func (l *logOrderValue) Type() string {
+	return "string"
+}
+
+// checkGroupedLogOrder returns an error if the output of a run can't be grouped. Tasks run
+// with --parallel are usually dev servers and watchers that never finish, so their output
+// would never be printed.
+func checkGroupedLogOrder(opts *runOpts) error {
+	if opts.parallel {
+		return errors.New("--log-order=grouped is ignored with --parallel, because tasks that run in parallel, such as dev servers, may never finish. Their output is streamed instead")
+	}
+	return nil
+}
+
+// streamsGroupedOutput reports whether the output of a task is streamed even though the
+// output of the run is grouped. Tasks with caching turned off, such as dev servers and
+// watchers, may never finish.
+func streamsGroupedOutput(taskDefinition *fs.TaskDefinition) bool {
+	return !taskDefinition.ShouldCache
+}
+
+// outputKind identifies which sink a chunk of buffered task output was written to
+type outputKind int
+
+const (
+	outputRaw outputKind = iota
+	outputRawErr
+	outputOutput
+	outputInfo
+	outputWarn
+	outputError
+)
+
+type outputChunk struct {
+	kind outputKind
+	text string
+}
+
+// taskOutputGroup buffers everything a single task prints to the terminal: the raw stdout and stderr
+// of its command, as well as messages sent through its cli.Ui. The order of writes is preserved, so that
+// replaying the group is equivalent to having streamed it.
+type taskOutputGroup struct {
+	taskID string
+	// recent also keeps the last lines of output, if set
+	recent *recentLines
+	mu     sync.Mutex
+	chunks []outputChunk
+}
+
+var _ cli.Ui = &taskOutputGroup{}
+var _ io.Writer = &taskOutputGroup{}
+
+func (g *taskOutputGroup) add(kind outputKind, text string) {
+	if g.recent != nil {
+		if kind != outputRaw && kind != outputRawErr {
+			// messages sent through the Ui are always printed on their own line
+			g.recent.add(text + "\n")
+		} else {
+			g.recent.add(text)
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	// Coalesce consecutive raw writes, the command's output usually arrives a line at a time
+	if last := len(g.chunks) - 1; (kind == outputRaw || kind == outputRawErr) && last >= 0 && g.chunks[last].kind == kind {
+		g.chunks[last].text += text
+		return
+	}
+	g.chunks = append(g.chunks, outputChunk{kind: kind, text: text})
+}
+
+// Write implements io.Writer for the raw stdout of the task's command
+func (g *taskOutputGroup) Write(p []byte) (int, error) {
+	g.add(outputRaw, string(p))
+	return len(p), nil
+}
+
+// Stderr returns a writer for the raw stderr of the task's command
+func (g *taskOutputGroup) Stderr() io.Writer {
+	return taskOutputGroupStderr{g}
+}
+
+type taskOutputGroupStderr struct {
+	g *taskOutputGroup
+}
+
+func (e taskOutputGroupStderr) Write(p []byte) (int, error) {
+	e.g.add(outputRawErr, string(p))
+	return len(p), nil
+}
+
+// Ask implements cli.Ui.Ask. Tasks do not prompt for input, and a prompt buffered until
+// the task finishes could never be answered.
+func (g *taskOutputGroup) Ask(string) (string, error) {
+	return "", errors.New("cannot prompt for input while grouping task output")
+}
+
+// AskSecret implements cli.Ui.AskSecret
+func (g *taskOutputGroup) AskSecret(query string) (string, error) {
+	return g.Ask(query)
+}
+
+// Output implements cli.Ui.Output
+func (g *taskOutputGroup) Output(message string) {
+	g.add(outputOutput, message)
+}
+
+// Info implements cli.Ui.Info
+func (g *taskOutputGroup) Info(message string) {
+	g.add(outputInfo, message)
+}
+
+// Warn implements cli.Ui.Warn
+func (g *taskOutputGroup) Warn(message string) {
+	g.add(outputWarn, message)
+}
+
+// Error implements cli.Ui.Error
+func (g *taskOutputGroup) Error(message string) {
+	g.add(outputError, message)
+}
+
+// _maxPartialLine caps how much of a line without a newline yet is kept by recentLines.
+// Progress bars may redraw a line for a long time, and only the end of it is shown.
+const _maxPartialLine = 4096
+
+// recentLines keeps the last lines of a task's output in a fixed size ring
+type recentLines struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int
+	partial string
+}
+
+func newRecentLines(size int) *recentLines {
+	return &recentLines{lines: make([]string, 0, size)}
+}
+
+func (r *recentLines) add(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := strings.Split(r.partial+text, "\n")
+	for _, line := range lines[:len(lines)-1] {
+		if len(r.lines) < cap(r.lines) {
+			r.lines = append(r.lines, line)
+		} else {
+			r.lines[r.next] = line
+			r.next = (r.next + 1) % len(r.lines)
+		}
+	}
+	r.partial = lines[len(lines)-1]
+	if len(r.partial) > _maxPartialLine {
+		r.partial = r.partial[len(r.partial)-_maxPartialLine:]
+	}
+}
+
+// last returns up to the last n lines, including a line that hasn't been finished yet
+func (r *recentLines) last(n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := make([]string, 0, len(r.lines)+1)
+	lines = append(lines, r.lines[r.next:]...)
+	lines = append(lines, r.lines[:r.next]...)
+	if r.partial != "" {
+		lines = append(lines, r.partial)
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// taskOutputGroups prints the output of each task as one contiguous block, rather than
+// interleaving lines from concurrently running tasks. Successful tasks are printed as soon
+// as they finish, while failed tasks are held back so that they can be printed last.
+type taskOutputGroups struct {
+	ui     cli.Ui
+	stdout io.Writer
+	stderr io.Writer
+	mu     sync.Mutex
+	failed []*taskOutputGroup
+}
+
+func newTaskOutputGroups(terminal cli.Ui, stdout io.Writer, stderr io.Writer) *taskOutputGroups {
+	return &taskOutputGroups{
+		ui:     terminal,
+		stdout: stdout,
+		stderr: stderr,
+	}
+}
+
+// newGroup returns an empty group to buffer the output of the given task
+func (o *taskOutputGroups) newGroup(taskID string) *taskOutputGroup {
+	return &taskOutputGroup{taskID: taskID}
+}
+
+// finish prints the group if the task succeeded, or holds onto it until printFailures
+// is called if the task failed.
+func (o *taskOutputGroups) finish(g *taskOutputGroup, failed bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if failed {
+		o.failed = append(o.failed, g)
+		return
+	}
+	o.print(g)
+}
+
+// printFailures prints the groups of all failed tasks, each behind a highlighted header
+func (o *taskOutputGroups) printFailures() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, g := range o.failed {
+		o.ui.Error(fmt.Sprintf("%s %s", ui.ERROR_PREFIX, color.New(color.Bold, color.FgRed).Sprintf("%v failed", g.taskID)))
+		o.print(g)
+	}
+	o.failed = nil
+}
+
+func (o *taskOutputGroups) print(g *taskOutputGroup) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, chunk := range g.chunks {
+		switch chunk.kind {
+		case outputRaw:
+			// As when streaming, there's nothing useful to do if the terminal
+			// won't take the task's output.
+			_, _ = io.WriteString(o.stdout, chunk.text)
+		case outputRawErr:
+			_, _ = io.WriteString(o.stderr, chunk.text)
+		case outputOutput:
+			o.ui.Output(chunk.text)
+		case outputInfo:
+			o.ui.Info(chunk.text)
+		case outputWarn:
+			o.ui.Warn(chunk.text)
+		case outputError:
+			o.ui.Error(chunk.text)
+		}
+	}
+	g.chunks = nil
+}
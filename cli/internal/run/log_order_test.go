@@ -0,0 +1,110 @@
+package run
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+	"github.com/vercel/turborepo/cli/internal/fs"
+)
+
+func Test_taskOutputGroups(t *testing.T) {
+	out := &bytes.Buffer{}
+	terminal := &cli.BasicUi{Writer: out, ErrorWriter: out}
+	groups := newTaskOutputGroups(terminal, out, out)
+
+	lib := groups.newGroup("lib#build")
+	web := groups.newGroup("web#build")
+	docs := groups.newGroup("docs#build")
+
+	// Interleave writes from all three tasks, as happens when they run concurrently
+	lib.Output("lib: cache miss, executing")
+	web.Output("web: cache miss, executing")
+	_, _ = lib.Write([]byte("lib: compiling\n"))
+	_, _ = web.Write([]byte("web: compiling\n"))
+	docs.Output("docs: cache hit, replaying output")
+	_, _ = lib.Write([]byte("lib: done\n"))
+	web.Error("web: ERROR: command finished with error")
+	_, _ = docs.Write([]byte("docs: done\n"))
+
+	groups.finish(web, true)
+	if out.Len() != 0 {
+		t.Errorf("expected failed task output to be held back, got %q", out.String())
+	}
+	groups.finish(lib, false)
+	groups.finish(docs, false)
+	groups.printFailures()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := []string{
+		"lib: cache miss, executing",
+		"lib: compiling",
+		"lib: done",
+		"docs: cache hit, replaying output",
+		"docs: done",
+		"", // failure header
+		"web: cache miss, executing",
+		"web: compiling",
+		"web: ERROR: command finished with error",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("got %v lines of output, want %v:\n%v", len(lines), len(expected), out.String())
+	}
+	for i, line := range lines {
+		if expected[i] == "" {
+			if !strings.Contains(line, "web#build failed") {
+				t.Errorf("line %v: expected failure header for web#build, got %q", i, line)
+			}
+		} else if line != expected[i] {
+			t.Errorf("line %v: got %q, want %q", i, line, expected[i])
+		}
+	}
+
+	// Failures are only printed once
+	out.Reset()
+	groups.printFailures()
+	if out.Len() != 0 {
+		t.Errorf("expected no further output, got %q", out.String())
+	}
+}
+
+func Test_logOrderValue(t *testing.T) {
+	opts := &runOpts{}
+	value := &logOrderValue{opts: opts}
+	if value.String() != _logOrderStream {
+		t.Errorf("default log order: got %v, want %v", value.String(), _logOrderStream)
+	}
+	if err := value.Set("grouped"); err != nil {
+		t.Fatalf("failed to set log order: %v", err)
+	}
+	if opts.logOrder != _logOrderGrouped {
+		t.Errorf("got log order %q, want %q", opts.logOrder, _logOrderGrouped)
+	}
+	if err := value.Set("stream"); err != nil {
+		t.Fatalf("failed to set log order: %v", err)
+	}
+	if opts.logOrder != "" {
+		t.Errorf("expected streaming to leave the log order unset, got %q", opts.logOrder)
+	}
+	if err := value.Set("interleaved"); err == nil {
+		t.Error("expected an error for an unknown log order")
+	}
+}
+
+func Test_groupedLogOrderStreamsUnfinishedTasks(t *testing.T) {
+	if err := checkGroupedLogOrder(&runOpts{logOrder: _logOrderGrouped}); err != nil {
+		t.Errorf("expected output to be grouped, got %v", err)
+	}
+	// Tasks run in parallel may never finish, so their output would never be printed
+	if err := checkGroupedLogOrder(&runOpts{logOrder: _logOrderGrouped, parallel: true}); err == nil {
+		t.Error("expected grouped output to be refused with --parallel")
+	}
+
+	if streamsGroupedOutput(&fs.TaskDefinition{ShouldCache: true}) {
+		t.Error("expected the output of a cached task to be grouped")
+	}
+	if !streamsGroupedOutput(&fs.TaskDefinition{ShouldCache: false}) {
+		t.Error("expected the output of an uncached task, such as a dev server, to be streamed")
+	}
+}
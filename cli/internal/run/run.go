@@ -4,9 +4,9 @@ import (
 	gocontext "context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -61,7 +61,9 @@ type completeGraph struct {
 	Pipeline         fs.Pipeline
 	PackageInfos     map[interface{}]*fs.PackageJSON
 	GlobalHash       string
-	RootNode         string
+	// GlobalEnvPatterns are the patterns of env vars included in GlobalHash
+	GlobalEnvPatterns []string
+	RootNode          string
 }
 
 // runSpec contains the run-specific configuration elements that come from a particular
@@ -155,13 +157,18 @@ func configureRun(config *config.Config, output cli.Ui, opts *Opts, signalWatche
 		opts.cacheOpts.SkipFilesystem = true
 	}
 
+	if os.Getenv("TURBO_REMOTE_CACHE_READ_ONLY") == "true" {
+		opts.cacheOpts.RemoteReadOnly = true
+	}
+
 	processes := process.NewManager(config.Logger.Named("processes"))
 	signalWatcher.AddOnClose(processes.Close)
 	return &run{
-		opts:      opts,
-		config:    config,
-		ui:        output,
-		processes: processes,
+		opts:          opts,
+		config:        config,
+		ui:            output,
+		processes:     processes,
+		signalWatcher: signalWatcher,
 	}
 }
 
@@ -194,10 +201,11 @@ func (c *RunCommand) Run(args []string) int {
 }
 
 type run struct {
-	opts      *Opts
-	config    *config.Config
-	ui        cli.Ui
-	processes *process.Manager
+	opts          *Opts
+	config        *config.Config
+	ui            cli.Ui
+	processes     *process.Manager
+	signalWatcher *signals.Watcher
 }
 
 func (r *run) run(ctx gocontext.Context, targets []string) error {
@@ -213,10 +221,17 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 	}
 	// TODO: these values come from a config file, hopefully viper can help us merge these
 	r.opts.cacheOpts.RemoteCacheOpts = turboJSON.RemoteCacheOptions
+	r.opts.cacheOpts.Tiers = turboJSON.CacheTiers
 	pkgDepGraph, err := context.New(context.WithGraph(r.config.Cwd, rootPackageJSON, r.opts.cacheOpts.Dir))
 	if err != nil {
 		return err
 	}
+	if err := turboJSON.ApplyPresets(r.config.Cwd, pkgDepGraph.PackageInfos); err != nil {
+		return err
+	}
+	if err := turboJSON.MergePackageConfigs(r.config.Cwd, pkgDepGraph.PackageInfos); err != nil {
+		return err
+	}
 	// This technically could be one flag, but we plan on removing
 	// the daemon opt-in flag at some point once it stabilizes
 	if r.opts.runOpts.daemonOptIn && !r.opts.runOpts.noDaemon {
@@ -248,7 +263,7 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 			return errors.Wrap(err, "failed to create SCM")
 		}
 	}
-	filteredPkgs, isAllPackages, err := scope.ResolvePackages(&r.opts.scopeOpts, r.config.Cwd.ToStringDuringMigration(), scmInstance, pkgDepGraph, r.ui, r.config.Logger)
+	filteredPkgs, isAllPackages, err := scope.ResolvePackages(&r.opts.scopeOpts, r.config.Cwd.ToStringDuringMigration(), scmInstance, pkgDepGraph, pipeline, targets, r.ui, r.config.Logger)
 	if err != nil {
 		return errors.Wrap(err, "failed to resolve packages to run")
 	}
@@ -263,14 +278,15 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 			}
 		}
 	}
+	globalEnv := globalEnvPatterns(turboJSON.GlobalDependencies, turboJSON.GlobalEnv)
 	globalHash, err := calculateGlobalHash(
 		r.config.Cwd,
 		rootPackageJSON,
 		pipeline,
 		turboJSON.GlobalDependencies,
+		globalEnv,
 		pkgDepGraph.PackageManager,
 		r.config.Logger,
-		os.Environ(),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to calculate global hash: %v", err)
@@ -280,11 +296,12 @@ func (r *run) run(ctx gocontext.Context, targets []string) error {
 
 	// TODO: consolidate some of these arguments
 	g := &completeGraph{
-		TopologicalGraph: pkgDepGraph.TopologicalGraph,
-		Pipeline:         pipeline,
-		PackageInfos:     pkgDepGraph.PackageInfos,
-		GlobalHash:       globalHash,
-		RootNode:         pkgDepGraph.RootNode,
+		TopologicalGraph:  pkgDepGraph.TopologicalGraph,
+		Pipeline:          pipeline,
+		PackageInfos:      pkgDepGraph.PackageInfos,
+		GlobalHash:        globalHash,
+		GlobalEnvPatterns: globalEnv,
+		RootNode:          pkgDepGraph.RootNode,
 	}
 	rs := &runSpec{
 		Targets:      targets,
@@ -326,7 +343,13 @@ func (r *run) runOperation(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		}
 	}
 
-	if rs.Opts.runOpts.graphFile != "" || rs.Opts.runOpts.graphDot {
+	if rs.Opts.runOpts.exportBundle != "" {
+		tasksRun, err := r.executeDryRun(ctx, engine, g, hashTracker, rs, nil)
+		if err != nil {
+			return err
+		}
+		return r.exportBundle(tasksRun, rs)
+	} else if rs.Opts.runOpts.graphFile != "" || rs.Opts.runOpts.graphDot {
 		visualizer := graphvisualizer.New(r.config, r.ui, engine.TaskGraph)
 
 		if rs.Opts.runOpts.graphDot {
@@ -338,7 +361,13 @@ func (r *run) runOperation(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 			}
 		}
 	} else if rs.Opts.runOpts.dryRun {
-		tasksRun, err := r.executeDryRun(ctx, engine, g, hashTracker, rs)
+		turboCache, analyticsClient, err := r.initCache(ctx, rs)
+		if err != nil {
+			return err
+		}
+		defer analyticsClient.CloseWithTimeout(50 * time.Millisecond)
+		defer turboCache.Shutdown()
+		tasksRun, err := r.executeDryRun(ctx, engine, g, hashTracker, rs, turboCache)
 		if err != nil {
 			return err
 		}
@@ -376,6 +405,8 @@ func (r *run) runOperation(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Task\t=\t%s\t${RESET}", task.Task))
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Package\t=\t%s\t${RESET}", task.Package))
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Hash\t=\t%s\t${RESET}", task.Hash))
+				fmt.Fprintln(w, util.Sprintf("  ${GREY}Cache Status\t=\t%s\t${RESET}", task.CacheStatus))
+				fmt.Fprintln(w, util.Sprintf("  ${GREY}Duration\t=\t%s\t${RESET}", formatDuration(task.Duration)))
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Directory\t=\t%s\t${RESET}", task.Dir))
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Command\t=\t%s\t${RESET}", task.Command))
 				fmt.Fprintln(w, util.Sprintf("  ${GREY}Outputs\t=\t%s\t${RESET}", strings.Join(task.Outputs, ", ")))
@@ -467,6 +498,14 @@ type runOpts struct {
 	graphFile   string
 	noDaemon    bool
 	daemonOptIn bool
+	// Either "grouped", or unset to stream task output
+	logOrder string
+	// Show an interactive dashboard instead of streaming output, if stdout is a terminal
+	tui bool
+	// Launch tasks with only the env vars they declare, rather than turbo's whole environment
+	strictEnv bool
+	// The bundle to pack the tasks' local cache entries into, instead of running them
+	exportBundle fs.AbsolutePath
 }
 
 var (
@@ -498,6 +537,24 @@ func addRunOpts(opts *runOpts, flags *pflag.FlagSet, aliases map[string]string)
 	flags.StringVar(&opts.profile, "profile", "", _profileHelp)
 	flags.BoolVar(&opts.continueOnError, "continue", false, _continueHelp)
 	flags.BoolVar(&opts.only, "only", false, _onlyHelp)
+	flags.AddFlag(&pflag.Flag{
+		Name:     "env-mode",
+		Usage:    _envModeHelp,
+		DefValue: _envModeLoose,
+		Value:    &envModeValue{opts: opts},
+	})
+	flags.AddFlag(&pflag.Flag{
+		Name:     "ui",
+		Usage:    _uiHelp,
+		DefValue: _uiStream,
+		Value:    &uiValue{opts: opts},
+	})
+	flags.AddFlag(&pflag.Flag{
+		Name:     "log-order",
+		Usage:    _logOrderHelp,
+		DefValue: _logOrderStream,
+		Value:    &logOrderValue{opts: opts},
+	})
 	flags.BoolVar(&opts.noDaemon, "no-daemon", false, "Run without using turbo's daemon process")
 	flags.BoolVar(&opts.daemonOptIn, "experimental-use-daemon", false, "Use the experimental turbo daemon")
 	// Daemon-related flags hidden for now, we can unhide when daemon is ready.
@@ -682,7 +739,9 @@ func (r *run) logWarning(prefix string, err error) {
 	r.ui.Error(fmt.Sprintf("%s%s%s", ui.WARNING_PREFIX, prefix, color.YellowString(" %v", err)))
 }
 
-func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec, engine *core.Scheduler, packageManager *packagemanager.PackageManager, hashes *taskhash.Tracker, startAt time.Time) error {
+// initCache sets up the caches for this run, along with the analytics client that records
+// their events. The caller must close the analytics client and shut down the cache.
+func (r *run) initCache(ctx gocontext.Context, rs *runSpec) (cache.Cache, analytics.Client, error) {
 	apiClient := r.config.NewClient()
 	var analyticsSink analytics.Sink
 	if apiClient.IsLoggedIn() {
@@ -692,7 +751,6 @@ func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		analyticsSink = analytics.NullSink
 	}
 	analyticsClient := analytics.NewClient(ctx, analyticsSink, r.config.Logger.Named("analytics"))
-	defer analyticsClient.CloseWithTimeout(50 * time.Millisecond)
 	// Theoretically this is overkill, but bias towards not spamming the console
 	once := &sync.Once{}
 	turboCache, err := cache.New(rs.Opts.cacheOpts, r.config, apiClient, analyticsClient, func(_cache cache.Cache, err error) {
@@ -707,9 +765,22 @@ func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		if errors.Is(err, cache.ErrNoCachesEnabled) {
 			r.logWarning("No caches are enabled. You can try \"turbo login\", \"turbo link\", or ensuring you are not passing --remote-only to enable caching", nil)
 		} else {
-			return errors.Wrap(err, "failed to set up caching")
+			analyticsClient.CloseWithTimeout(50 * time.Millisecond)
+			return nil, nil, errors.Wrap(err, "failed to set up caching")
 		}
 	}
+	return turboCache, analyticsClient, nil
+}
+
+func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec, engine *core.Scheduler, packageManager *packagemanager.PackageManager, hashes *taskhash.Tracker, startAt time.Time) error {
+	turboCache, analyticsClient, err := r.initCache(ctx, rs)
+	if err != nil {
+		return err
+	}
+	defer analyticsClient.CloseWithTimeout(50 * time.Millisecond)
+	if rs.Opts.cacheOpts.UsesRemote() {
+		r.ui.Output(ui.Dim("• Remote computation caching enabled"))
+	}
 	defer func() {
 		_ = spinner.WaitFor(ctx, turboCache.Shutdown, r.ui, "...writing to cache...", 1500*time.Millisecond)
 	}()
@@ -727,6 +798,20 @@ func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		processes:      r.processes,
 		taskHashes:     hashes,
 		repoRoot:       r.config.Cwd,
+		globalEnv:      g.GlobalEnvPatterns,
+	}
+	if rs.Opts.runOpts.tui {
+		ec.dashboard = r.startDashboard(engine, runState)
+	}
+	if ec.dashboard != nil {
+		// Anything else printed while the dashboard is shown would be drawn over
+		ec.ui = ec.dashboard.messages
+	} else if rs.Opts.runOpts.logOrder == _logOrderGrouped {
+		if err := checkGroupedLogOrder(&rs.Opts.runOpts); err != nil {
+			r.logWarning("", err)
+		} else {
+			ec.outputGroups = newTaskOutputGroups(ec.ui, os.Stdout, os.Stderr)
+		}
 	}
 
 	// run the thing
@@ -737,6 +822,12 @@ func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 		Parallel:    rs.Opts.runOpts.parallel,
 		Concurrency: rs.Opts.runOpts.concurrency,
 	})
+	if ec.dashboard != nil {
+		ec.dashboard.stop()
+	}
+	if ec.outputGroups != nil {
+		ec.outputGroups.printFailures()
+	}
 
 	// Track if we saw any child with a non-zero exit code
 	exitCode := 0
@@ -764,11 +855,45 @@ func (r *run) executeTasks(ctx gocontext.Context, g *completeGraph, rs *runSpec,
 	return nil
 }
 
+// startDashboard shows the interactive dashboard for the tasks in the engine's task graph.
+// It returns nil if stdout isn't a terminal, in which case task output is streamed as usual.
+func (r *run) startDashboard(engine *core.Scheduler, runState *RunState) *dashboard {
+	if !ui.IsTTY {
+		r.config.Logger.Debug("stdout is not a terminal, streaming task output instead of showing the dashboard")
+		return nil
+	}
+	var taskIDs []string
+	for _, v := range engine.TaskGraph.Vertices() {
+		if taskID := dag.VertexName(v); taskID != core.ROOT_NODE_NAME {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	sort.Strings(taskIDs)
+	interrupt := r.processes.Close
+	if r.signalWatcher != nil {
+		interrupt = r.signalWatcher.Close
+	}
+	dash := newDashboard(runState, taskIDs, r.ui, os.Stdout, os.Stderr, interrupt)
+	if err := dash.start(); err != nil {
+		dash.restore()
+		r.logWarning("Failed to show the dashboard, streaming task output instead", err)
+		return nil
+	}
+	if r.signalWatcher != nil {
+		// Put the terminal back the way we found it if the run is interrupted
+		r.signalWatcher.AddOnClose(dash.restore)
+	}
+	return dash
+}
+
 type hashedTask struct {
-	TaskID       string   `json:"taskId"`
-	Task         string   `json:"task"`
-	Package      string   `json:"package"`
-	Hash         string   `json:"hash"`
+	TaskID      string `json:"taskId"`
+	Task        string `json:"task"`
+	Package     string `json:"package"`
+	Hash        string `json:"hash"`
+	CacheStatus string `json:"cacheStatus"`
+	// Duration is how long the task took when its artifact was cached, in milliseconds
+	Duration     int      `json:"duration"`
 	Command      string   `json:"command"`
 	Outputs      []string `json:"outputs"`
 	LogFile      string   `json:"logFile"`
@@ -777,7 +902,16 @@ type hashedTask struct {
 	Dependents   []string `json:"dependents"`
 }
 
-func (r *run) executeDryRun(ctx gocontext.Context, engine *core.Scheduler, g *completeGraph, taskHashes *taskhash.Tracker, rs *runSpec) ([]hashedTask, error) {
+// Cache statuses of a dry run's tasks, in the order the caches are checked
+const (
+	_cacheStatusLocal  = "local"
+	_cacheStatusRemote = "remote"
+	_cacheStatusMiss   = "miss"
+)
+
+// executeDryRun hashes the tasks that would be run. If turboCache is set, each task's cache
+// status is predicted by checking the caches, without restoring anything.
+func (r *run) executeDryRun(ctx gocontext.Context, engine *core.Scheduler, g *completeGraph, taskHashes *taskhash.Tracker, rs *runSpec, turboCache cache.Cache) ([]hashedTask, error) {
 	taskIDs := []hashedTask{}
 	errs := engine.Execute(g.getPackageTaskVisitor(ctx, func(ctx gocontext.Context, pt *nodes.PackageTask) error {
 		passThroughArgs := rs.ArgsForTask(pt.Task)
@@ -818,11 +952,22 @@ func (r *run) executeDryRun(ctx gocontext.Context, engine *core.Scheduler, g *co
 		}
 		sort.Strings(stringDescendents)
 
+		cacheStatus := ""
+		duration := 0
+		if turboCache != nil {
+			cacheStatus, duration, err = predictCacheStatus(turboCache, hash, pt.TaskDefinition, rs.Opts.runcacheOpts)
+			if err != nil {
+				return err
+			}
+		}
+
 		taskIDs = append(taskIDs, hashedTask{
 			TaskID:       pt.TaskID,
 			Task:         pt.Task,
 			Package:      pt.PackageName,
 			Hash:         hash,
+			CacheStatus:  cacheStatus,
+			Duration:     duration,
 			Command:      command,
 			Dir:          pt.Pkg.Dir.ToString(),
 			Outputs:      pt.TaskDefinition.Outputs,
@@ -844,6 +989,27 @@ func (r *run) executeDryRun(ctx gocontext.Context, engine *core.Scheduler, g *co
 	return taskIDs, nil
 }
 
+// predictCacheStatus returns whether a task with the given hash would be restored from the
+// cache, and how long it took to run when it was cached. Tasks that don't read from the cache,
+// because they have caching turned off or the run is forced, are always a miss.
+func predictCacheStatus(turboCache cache.Cache, hash string, taskDefinition *fs.TaskDefinition, runcacheOpts runcache.Opts) (string, int, error) {
+	if !taskDefinition.ShouldCache || runcacheOpts.SkipReads {
+		return _cacheStatusMiss, 0, nil
+	}
+	itemStatus, err := turboCache.Exists(hash)
+	if err != nil {
+		return "", 0, err
+	}
+	switch {
+	case itemStatus.Local:
+		return _cacheStatusLocal, itemStatus.Duration, nil
+	case itemStatus.Remote:
+		return _cacheStatusRemote, itemStatus.Duration, nil
+	default:
+		return _cacheStatusMiss, 0, nil
+	}
+}
+
 var _isTurbo = regexp.MustCompile(fmt.Sprintf("(?:^|%v|\\s)turbo(?:$|\\s)", regexp.QuoteMeta(string(filepath.Separator))))
 
 func commandLooksLikeTurbo(command string) bool {
@@ -870,6 +1036,12 @@ type execContext struct {
 	processes      *process.Manager
 	taskHashes     *taskhash.Tracker
 	repoRoot       fs.AbsolutePath
+	// outputGroups is set when each task's output should be printed as one block
+	outputGroups *taskOutputGroups
+	// dashboard is set when the interactive dashboard is shown instead of task output
+	dashboard *dashboard
+	// globalEnv are the patterns of env vars that affect every task
+	globalEnv []string
 }
 
 func (e *execContext) logError(log hclog.Logger, prefix string, err error) {
@@ -883,6 +1055,23 @@ func (e *execContext) logError(log hclog.Logger, prefix string, err error) {
 }
 
 func (e *execContext) exec(ctx gocontext.Context, pt *nodes.PackageTask, deps dag.Set) error {
+	if e.dashboard != nil {
+		output := e.dashboard.taskOutput(pt.TaskID)
+		err := e.execTask(ctx, pt, deps, output, output, output.Stderr())
+		e.dashboard.finish(pt.TaskID, err != nil)
+		return err
+	}
+	if e.outputGroups == nil || streamsGroupedOutput(pt.TaskDefinition) {
+		return e.execTask(ctx, pt, deps, e.ui, os.Stdout, os.Stderr)
+	}
+	group := e.outputGroups.newGroup(pt.TaskID)
+	err := e.execTask(ctx, pt, deps, group, group, group.Stderr())
+	e.outputGroups.finish(group, err != nil)
+	return err
+}
+
+// execTask runs a single task, sending its terminal output to the given ui and writers
+func (e *execContext) execTask(ctx gocontext.Context, pt *nodes.PackageTask, deps dag.Set, taskUi cli.Ui, stdout io.Writer, stderr io.Writer) error {
 	cmdTime := time.Now()
 
 	targetLogger := e.logger.Named(pt.OutputPrefix())
@@ -895,7 +1084,7 @@ func (e *execContext) exec(ctx gocontext.Context, pt *nodes.PackageTask, deps da
 	colorPrefixer := e.colorCache.PrefixColor(pt.PackageName)
 	prettyTaskPrefix := colorPrefixer("%s: ", pt.OutputPrefix())
 	targetUi := &cli.PrefixedUi{
-		Ui:           e.ui,
+		Ui:           taskUi,
 		OutputPrefix: prettyTaskPrefix,
 		InfoPrefix:   prettyTaskPrefix,
 		ErrorPrefix:  prettyTaskPrefix,
@@ -921,33 +1110,23 @@ func (e *execContext) exec(ctx gocontext.Context, pt *nodes.PackageTask, deps da
 	}
 	// Cache ---------------------------------------------
 	taskCache := e.runCache.TaskCache(pt, hash)
-	hit, err := taskCache.RestoreOutputs(ctx, targetUi, targetLogger)
+	hit, err := taskCache.RestoreOutputs(ctx, targetUi, stdout, stderr, targetLogger)
 	if err != nil {
 		targetUi.Error(fmt.Sprintf("error fetching from cache: %s", err))
 	} else if hit {
 		tracer(TargetCached, nil)
 		return nil
 	}
-	// Setup command execution
-	argsactual := append([]string{"run"}, pt.Task)
-	if len(passThroughArgs) > 0 {
-		// This will be either '--' or a typed nil
-		argsactual = append(argsactual, e.packageManager.ArgSeparator...)
-		argsactual = append(argsactual, passThroughArgs...)
+	if err := taskCache.CleanOutputs(targetLogger); err != nil {
+		targetUi.Warn(fmt.Sprintf("failed to clean outputs: %s", err))
 	}
-
-	cmd := exec.Command(e.packageManager.Command, argsactual...)
-	// TODO: repoRoot probably should be AbsoluteSystemPath, but it's Join method
-	// takes a RelativeSystemPath. Resolve during migration from AbsolutePath to
-	// AbsoluteSystemPath
-	cmd.Dir = e.repoRoot.Join(pt.Pkg.Dir.ToStringDuringMigration()).ToString()
-	envs := fmt.Sprintf("TURBO_HASH=%v", hash)
-	cmd.Env = append(os.Environ(), envs)
+	// Setup command execution
+	cmd := e.taskCommand(pt, passThroughArgs, hash)
 
 	// Setup stdout/stderr
 	// If we are not caching anything, then we don't need to write logs to disk
 	// be careful about this conditional given the default of cache = true
-	writer, err := taskCache.OutputWriter()
+	writer, err := taskCache.OutputWriter(stdout, stderr)
 	if err != nil {
 		tracer(TargetBuildFailed, err)
 		e.logError(targetLogger, prettyTaskPrefix, err)
@@ -955,11 +1134,10 @@ func (e *execContext) exec(ctx gocontext.Context, pt *nodes.PackageTask, deps da
 			os.Exit(1)
 		}
 	}
-	logger := log.New(writer, "", 0)
 	// Setup a streamer that we'll pipe cmd.Stdout to
-	logStreamerOut := logstreamer.NewLogstreamer(logger, prettyTaskPrefix, false)
+	logStreamerOut := logstreamer.NewLogstreamer(log.New(writer.Stdout, "", 0), prettyTaskPrefix, false)
 	// Setup a streamer that we'll pipe cmd.Stderr to.
-	logStreamerErr := logstreamer.NewLogstreamer(logger, prettyTaskPrefix, false)
+	logStreamerErr := logstreamer.NewLogstreamer(log.New(writer.Stderr, "", 0), prettyTaskPrefix, false)
 	cmd.Stderr = logStreamerErr
 	cmd.Stdout = logStreamerOut
 	// Flush/Reset any error we recorded
@@ -134,6 +134,17 @@ func (r *RunState) add(result *RunResult, previous string, active bool) {
 	}
 }
 
+// TaskState returns a copy of the state of the given task, and whether the task has started
+func (r *RunState) TaskState(label string) (BuildTargetState, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.state[label]
+	if !ok {
+		return BuildTargetState{}, false
+	}
+	return *s, true
+}
+
 // Close finishes a trace of a turbo run. The tracing file will be written if applicable,
 // and run stats are written to the terminal
 func (r *RunState) Close(terminal cli.Ui, filename string) error {
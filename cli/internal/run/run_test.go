@@ -446,3 +446,42 @@ func TestUsageText(t *testing.T) {
 	usage := cmd.Help()
 	assert.NotEmpty(t, usage, "expected usage text")
 }
+
+// existsCache is a cache.Cache that only answers Exists
+type existsCache struct {
+	cache.Cache
+	status cache.ItemStatus
+}
+
+func (c *existsCache) Exists(hash string) (cache.ItemStatus, error) {
+	return c.status, nil
+}
+
+func Test_predictCacheStatus(t *testing.T) {
+	cached := &fs.TaskDefinition{ShouldCache: true}
+	uncached := &fs.TaskDefinition{ShouldCache: false}
+	testCases := []struct {
+		name             string
+		status           cache.ItemStatus
+		taskDefinition   *fs.TaskDefinition
+		runcacheOpts     runcache.Opts
+		expectedStatus   string
+		expectedDuration int
+	}{
+		{"local hit", cache.ItemStatus{Local: true, Remote: true, Duration: 120}, cached, runcache.Opts{}, _cacheStatusLocal, 120},
+		{"remote hit", cache.ItemStatus{Remote: true, Duration: 80}, cached, runcache.Opts{}, _cacheStatusRemote, 80},
+		{"miss", cache.ItemStatus{}, cached, runcache.Opts{}, _cacheStatusMiss, 0},
+		// These tasks are executed even though the cache has an artifact for them
+		{"cache: false", cache.ItemStatus{Local: true, Duration: 120}, uncached, runcache.Opts{}, _cacheStatusMiss, 0},
+		{"--force", cache.ItemStatus{Remote: true, Duration: 80}, cached, runcache.Opts{SkipReads: true}, _cacheStatusMiss, 0},
+	}
+	for _, tc := range testCases {
+		turboCache := &existsCache{status: tc.status}
+		status, duration, err := predictCacheStatus(turboCache, "abc123", tc.taskDefinition, tc.runcacheOpts)
+		if err != nil {
+			t.Fatalf("%v: predictCacheStatus() error: %v", tc.name, err)
+		}
+		assert.Equal(t, tc.expectedStatus, status, tc.name)
+		assert.Equal(t, tc.expectedDuration, duration, tc.name)
+	}
+}
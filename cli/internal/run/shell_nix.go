@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package run
+
+import "os/exec"
+
+// shellCommand returns a process that runs command with the system shell
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("sh", "-c", command)
+}
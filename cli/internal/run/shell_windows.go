@@ -0,0 +1,27 @@
+//go:build windows
+// +build windows
+
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// shellCommand returns a process that runs command with the system shell
+func shellCommand(command string) *exec.Cmd {
+	shell := os.Getenv("ComSpec")
+	if shell == "" {
+		shell = "cmd.exe"
+	}
+	cmd := exec.Command(shell)
+	// cmd.exe doesn't parse its command line the way Go quotes arguments, so pass the
+	// command as written. /s makes cmd.exe remove only the outer quotes.
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine: fmt.Sprintf(`%v /d /s /c "%v"`, shell, command),
+	}
+	return cmd
+}
+
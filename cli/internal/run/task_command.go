@@ -0,0 +1,80 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// taskCommand returns the process that runs the given task. Tasks are run as package.json
+// scripts by the package manager, unless their definition has a command or uses the shell
+// runner. In that case, the command is run directly with the system shell, with the
+// node_modules/.bin directories of the package and the repo root added to PATH.
+func (e *execContext) taskCommand(pt *nodes.PackageTask, passThroughArgs []string, hash string) *exec.Cmd {
+	pkgDir := e.repoRoot.Join(pt.Pkg.Dir.ToStringDuringMigration())
+	env := append(e.taskEnv(pt), fmt.Sprintf("TURBO_HASH=%v", hash))
+
+	var cmd *exec.Cmd
+	if pt.RunsDirectly() {
+		command, _ := pt.Command()
+		for _, arg := range passThroughArgs {
+			command += " " + util.QuoteShellArg(arg)
+		}
+		cmd = shellCommand(command)
+		binDirs := []string{pkgDir.Join("node_modules", ".bin").ToString()}
+		if rootBinDir := e.repoRoot.Join("node_modules", ".bin").ToString(); rootBinDir != binDirs[0] {
+			binDirs = append(binDirs, rootBinDir)
+		}
+		env = prependPath(env, binDirs)
+	} else {
+		args := append([]string{"run"}, pt.Task)
+		if len(passThroughArgs) > 0 {
+			// This will be either '--' or a typed nil
+			args = append(args, e.packageManager.ArgSeparator...)
+			args = append(args, passThroughArgs...)
+		}
+		cmd = exec.Command(e.packageManager.Command, args...)
+	}
+	// TODO: repoRoot probably should be AbsoluteSystemPath, but it's Join method
+	// takes a RelativeSystemPath. Resolve during migration from AbsolutePath to
+	// AbsoluteSystemPath
+	cmd.Dir = pkgDir.ToString()
+	cmd.Env = env
+	return cmd
+}
+
+// prependPath adds dirs to the start of the PATH in the given env var pairs
+func prependPath(envPairs []string, dirs []string) []string {
+	prefix := strings.Join(dirs, string(os.PathListSeparator))
+	result := make([]string, 0, len(envPairs)+1)
+	found := false
+	for _, pair := range envPairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 && isPathVar(kv[0]) {
+			found = true
+			if kv[1] != "" {
+				pair = fmt.Sprintf("%v=%v%c%v", kv[0], prefix, os.PathListSeparator, kv[1])
+			} else {
+				pair = fmt.Sprintf("%v=%v", kv[0], prefix)
+			}
+		}
+		result = append(result, pair)
+	}
+	if !found {
+		result = append(result, fmt.Sprintf("PATH=%v", prefix))
+	}
+	return result
+}
+
+// isPathVar returns true if name is the PATH env var, which isn't case sensitive on Windows
+func isPathVar(name string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(name, "PATH")
+	}
+	return name == "PATH"
+}
@@ -0,0 +1,150 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/nodes"
+	"github.com/vercel/turborepo/cli/internal/packagemanager"
+	"github.com/vercel/turborepo/cli/internal/turbopath"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+func Test_taskCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("PATH", "/usr/bin")
+	repoRoot := fs.AbsolutePath("/repo")
+	e := &execContext{
+		repoRoot:       repoRoot,
+		packageManager: &packagemanager.PackageManager{Command: "npm", ArgSeparator: []string{"--"}},
+		rs:             &runSpec{Opts: &Opts{}},
+	}
+	pkg := &fs.PackageJSON{
+		Name:    "web",
+		Dir:     turbopath.AnchoredSystemPath("apps/web"),
+		Scripts: map[string]string{"build": "next build"},
+	}
+	testCases := []struct {
+		name           string
+		taskDefinition fs.TaskDefinition
+		args           []string
+		wantArgs       []string
+		wantPath       string
+	}{
+		{
+			name:           "script run by the package manager",
+			taskDefinition: fs.TaskDefinition{},
+			args:           []string{"--verbose"},
+			wantArgs:       []string{"npm", "run", "build", "--", "--verbose"},
+			wantPath:       "/usr/bin",
+		},
+		{
+			name:           "script run by the shell",
+			taskDefinition: fs.TaskDefinition{Runner: fs.RunnerShell},
+			args:           []string{"--profile", "it's"},
+			wantArgs:       []string{"sh", "-c", `next build '--profile' 'it'\''s'`},
+			wantPath:       "/repo/apps/web/node_modules/.bin:/repo/node_modules/.bin:/usr/bin",
+		},
+		{
+			name:           "command with placeholders",
+			taskDefinition: fs.TaskDefinition{Command: "tsc -p {dir}/tsconfig.json # {package}:{task}"},
+			wantArgs:       []string{"sh", "-c", "tsc -p 'apps/web'/tsconfig.json # 'web':'build'"},
+			wantPath:       "/repo/apps/web/node_modules/.bin:/repo/node_modules/.bin:/usr/bin",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pt := &nodes.PackageTask{
+				TaskID:         "web#build",
+				Task:           "build",
+				PackageName:    "web",
+				Pkg:            pkg,
+				TaskDefinition: &tc.taskDefinition,
+			}
+			cmd := e.taskCommand(pt, tc.args, "abc123")
+			if !reflect.DeepEqual(cmd.Args, tc.wantArgs) {
+				t.Errorf("args got %q, want %q", cmd.Args, tc.wantArgs)
+			}
+			if cmd.Dir != "/repo/apps/web" {
+				t.Errorf("dir got %v, want /repo/apps/web", cmd.Dir)
+			}
+			env := strings.Join(cmd.Env, "\n")
+			if !strings.Contains(env, "TURBO_HASH=abc123") {
+				t.Errorf("env is missing TURBO_HASH:\n%v", env)
+			}
+			if !strings.Contains(env, "PATH="+tc.wantPath+"\n") && !strings.HasSuffix(env, "PATH="+tc.wantPath) {
+				t.Errorf("env is missing PATH=%v:\n%v", tc.wantPath, env)
+			}
+		})
+	}
+}
+
+func Test_taskCommandRunsTaskWithoutScript(t *testing.T) {
+	pt := &nodes.PackageTask{
+		Task:           "deploy",
+		PackageName:    "//",
+		Pkg:            &fs.PackageJSON{},
+		TaskDefinition: &fs.TaskDefinition{Command: "./scripts/deploy.sh {dir}"},
+	}
+	command, ok := pt.Command()
+	if want := "./scripts/deploy.sh " + util.QuoteShellArg("."); !ok || command != want {
+		t.Errorf("Command() got %q, %v, want %q, true", command, ok, want)
+	}
+}
+
+func Test_taskCommandQuotesPlaceholders(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	repoRoot := t.TempDir()
+	dir := filepath.Join(repoRoot, "apps", "my web; echo oops")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create package dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.js"), []byte("index"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	e := &execContext{
+		repoRoot: fs.AbsolutePathFromUpstream(repoRoot),
+		rs:       &runSpec{Opts: &Opts{}},
+	}
+	pt := &nodes.PackageTask{
+		TaskID:      "my web#build",
+		Task:        "build",
+		PackageName: "my web",
+		Pkg: &fs.PackageJSON{
+			Name: "my web",
+			Dir:  turbopath.AnchoredSystemPath(filepath.Join("apps", "my web; echo oops")),
+		},
+		TaskDefinition: &fs.TaskDefinition{Command: "cat ../../{dir}/index.js && echo {package}"},
+	}
+	cmd := e.taskCommand(pt, nil, "abc123")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("command failed: %v\n%s", err, out)
+	}
+	if string(out) != "indexmy web\n" {
+		t.Errorf("output got %q, want %q", out, "indexmy web\n")
+	}
+}
+
+func Test_prependPath(t *testing.T) {
+	sep := string(os.PathListSeparator)
+	got := prependPath([]string{"HOME=/home/me", "PATH=/usr/bin"}, []string{"/a", "/b"})
+	want := []string{"HOME=/home/me", "PATH=/a" + sep + "/b" + sep + "/usr/bin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prependPath() got %v, want %v", got, want)
+	}
+	got = prependPath([]string{"HOME=/home/me"}, []string{"/a"})
+	want = []string{"HOME=/home/me", "PATH=/a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prependPath() got %v, want %v", got, want)
+	}
+}
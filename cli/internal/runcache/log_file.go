@@ -0,0 +1,130 @@
+package runcache
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Each line of a task's log file is tagged with the stream it was written to, so that
+// replaying a cached task can send it back to the same place. The tags are plain text to
+// keep log files readable. Lines without a tag were written by older versions of turbo,
+// which only recorded stdout.
+const (
+	_stdoutTag = "[stdout] "
+	_stderrTag = "[stderr] "
+)
+
+// parseLogLine returns the contents of a line from a log file and whether it
+// was written to stderr.
+func parseLogLine(line string) (string, bool) {
+	if strings.HasPrefix(line, _stderrTag) {
+		return line[len(_stderrTag):], true
+	}
+	return strings.TrimPrefix(line, _stdoutTag), false
+}
+
+// TaskOutputWriter handles the output of the command associated with a task.
+// Stdout and Stderr are shown on the terminal and recorded in the task's log file,
+// depending on the task's output mode and whether caching is enabled.
+type TaskOutputWriter struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	file   *logFileWriter
+}
+
+// Close flushes the log file, if there is one
+func (w *TaskOutputWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
+
+// logFileWriter writes tagged lines from both of a task's output streams to its log file.
+// Only whole lines are written, so that a partial line on one stream can't run into a
+// line from the other.
+type logFileWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	bufio   *bufio.Writer
+	streams []*taggedLineWriter
+}
+
+func newLogFileWriter(file *os.File) *logFileWriter {
+	return &logFileWriter{
+		file:  file,
+		bufio: bufio.NewWriter(file),
+	}
+}
+
+// stream returns a writer that tags each line written to it with the given tag
+func (lfw *logFileWriter) stream(tag string) io.Writer {
+	lfw.mu.Lock()
+	defer lfw.mu.Unlock()
+	stream := &taggedLineWriter{file: lfw, tag: tag}
+	lfw.streams = append(lfw.streams, stream)
+	return stream
+}
+
+// Close writes out the partial line that each stream ended with, and closes the file
+func (lfw *logFileWriter) Close() error {
+	lfw.mu.Lock()
+	defer lfw.mu.Unlock()
+	for _, stream := range lfw.streams {
+		if len(stream.partial) > 0 {
+			if err := stream.writeLine(append(stream.partial, '\n')); err != nil {
+				return err
+			}
+			stream.partial = nil
+		}
+	}
+	if err := lfw.bufio.Flush(); err != nil {
+		return err
+	}
+	return lfw.file.Close()
+}
+
+type taggedLineWriter struct {
+	file *logFileWriter
+	tag  string
+	// partial is the text written since the last newline, which is held until the line
+	// is complete
+	partial []byte
+}
+
+func (t *taggedLineWriter) Write(p []byte) (int, error) {
+	t.file.mu.Lock()
+	defer t.file.mu.Unlock()
+	remaining := p
+	for {
+		i := bytes.IndexByte(remaining, '\n')
+		if i < 0 {
+			break
+		}
+		line := remaining[:i+1]
+		if len(t.partial) > 0 {
+			line = append(t.partial, line...)
+			t.partial = nil
+		}
+		if err := t.writeLine(line); err != nil {
+			return 0, err
+		}
+		remaining = remaining[i+1:]
+	}
+	t.partial = append(t.partial, remaining...)
+	return len(p), nil
+}
+
+// writeLine writes a complete line to the file with this stream's tag. The file's lock
+// must be held.
+func (t *taggedLineWriter) writeLine(line []byte) error {
+	if _, err := t.file.bufio.WriteString(t.tag); err != nil {
+		return err
+	}
+	_, err := t.file.bufio.Write(line)
+	return err
+}
@@ -0,0 +1,119 @@
+package runcache
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
+	"github.com/vercel/turborepo/cli/internal/fs"
+)
+
+func TestLogFileRoundTrip(t *testing.T) {
+	logFileName := fs.AbsolutePath(filepath.Join(t.TempDir(), "turbo-build.log"))
+	file, err := logFileName.Create()
+	if err != nil {
+		t.Fatalf("failed to create log file: %v", err)
+	}
+	logFile := newLogFileWriter(file)
+	stdout := logFile.stream(_stdoutTag)
+	stderr := logFile.stream(_stderrTag)
+
+	fmt.Fprintln(stdout, "web:build: compiling")
+	fmt.Fprint(stderr, "web:build: warning: ")
+	fmt.Fprintln(stderr, "something looks off")
+	fmt.Fprint(stdout, "web:build: first\nweb:build: second\n")
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("failed to close log file: %v", err)
+	}
+
+	contents, err := os.ReadFile(logFileName.ToString())
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	expectedContents := "[stdout] web:build: compiling\n" +
+		"[stderr] web:build: warning: something looks off\n" +
+		"[stdout] web:build: first\n" +
+		"[stdout] web:build: second\n"
+	if string(contents) != expectedContents {
+		t.Errorf("log file contents: got %q, want %q", contents, expectedContents)
+	}
+
+	replayedOut := &bytes.Buffer{}
+	replayedErr := &bytes.Buffer{}
+	defaultLogReplayer(hclog.NewNullLogger(), cli.NewMockUi(), replayedOut, replayedErr, logFileName)
+	expectedOut := "web:build: compiling\nweb:build: first\nweb:build: second\n"
+	if replayedOut.String() != expectedOut {
+		t.Errorf("replayed stdout: got %q, want %q", replayedOut.String(), expectedOut)
+	}
+	expectedErr := "web:build: warning: something looks off\n"
+	if replayedErr.String() != expectedErr {
+		t.Errorf("replayed stderr: got %q, want %q", replayedErr.String(), expectedErr)
+	}
+}
+
+func TestLogFileInterleavedPartialLines(t *testing.T) {
+	logFileName := fs.AbsolutePath(filepath.Join(t.TempDir(), "turbo-build.log"))
+	file, err := logFileName.Create()
+	if err != nil {
+		t.Fatalf("failed to create log file: %v", err)
+	}
+	logFile := newLogFileWriter(file)
+	stdout := logFile.stream(_stdoutTag)
+	stderr := logFile.stream(_stderrTag)
+
+	fmt.Fprint(stdout, "abc")
+	fmt.Fprint(stderr, "err\n")
+	fmt.Fprint(stderr, "partial ")
+	fmt.Fprint(stdout, "def\nghi")
+	fmt.Fprint(stderr, "warning\nunterminated")
+	if err := logFile.Close(); err != nil {
+		t.Fatalf("failed to close log file: %v", err)
+	}
+
+	contents, err := os.ReadFile(logFileName.ToString())
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	expectedContents := "[stderr] err\n" +
+		"[stdout] abcdef\n" +
+		"[stderr] partial warning\n" +
+		"[stdout] ghi\n" +
+		"[stderr] unterminated\n"
+	if string(contents) != expectedContents {
+		t.Errorf("log file contents: got %q, want %q", contents, expectedContents)
+	}
+
+	replayedOut := &bytes.Buffer{}
+	replayedErr := &bytes.Buffer{}
+	defaultLogReplayer(hclog.NewNullLogger(), cli.NewMockUi(), replayedOut, replayedErr, logFileName)
+	if expectedOut := "abcdef\nghi\n"; replayedOut.String() != expectedOut {
+		t.Errorf("replayed stdout: got %q, want %q", replayedOut.String(), expectedOut)
+	}
+	if expectedErr := "err\npartial warning\nunterminated\n"; replayedErr.String() != expectedErr {
+		t.Errorf("replayed stderr: got %q, want %q", replayedErr.String(), expectedErr)
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected string
+		isStderr bool
+	}{
+		{"[stdout] web:build: ok", "web:build: ok", false},
+		{"[stderr] web:build: failed", "web:build: failed", true},
+		// Log files written by older versions of turbo don't have tags
+		{"web:build: untagged", "web:build: untagged", false},
+		{"", "", false},
+	}
+	for _, tc := range testCases {
+		line, isStderr := parseLogLine(tc.line)
+		if line != tc.expected || isStderr != tc.isStderr {
+			t.Errorf("parseLogLine(%q) got (%q, %v), want (%q, %v)", tc.line, line, isStderr, tc.expected, tc.isStderr)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -22,8 +21,10 @@ import (
 	"github.com/vercel/turborepo/cli/internal/util"
 )
 
-// LogReplayer is a function that is responsible for replaying the contents of a given log file
-type LogReplayer = func(logger hclog.Logger, output cli.Ui, logFile fs.AbsolutePath)
+// LogReplayer is a function that is responsible for replaying the contents of a given log file.
+// Lines are written back to the stdout or stderr writer they were originally written to, and
+// problems reading the log file are reported to output.
+type LogReplayer = func(logger hclog.Logger, output cli.Ui, stdout io.Writer, stderr io.Writer, logFile fs.AbsolutePath)
 
 // Opts holds the configurable options for a RunCache instance
 type Opts struct {
@@ -144,12 +145,16 @@ type TaskCache struct {
 	pt                *nodes.PackageTask
 	taskOutputMode    util.TaskOutputMode
 	cachingDisabled   bool
-	LogFileName       fs.AbsolutePath
+	// cleanBeforeRestore and cleanBeforeRun are true if the task's existing outputs are removed
+	// before they're restored from the cache, and before the task runs
+	cleanBeforeRestore bool
+	cleanBeforeRun     bool
+	LogFileName        fs.AbsolutePath
 }
 
 // RestoreOutputs attempts to restore output for the corresponding task from the cache. Returns true
 // if successful.
-func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi, logger hclog.Logger) (bool, error) {
+func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi, stdout io.Writer, stderr io.Writer, logger hclog.Logger) (bool, error) {
 	if tc.cachingDisabled || tc.rc.readsDisabled {
 		if tc.taskOutputMode != util.NoTaskOutput {
 			terminal.Output(fmt.Sprintf("cache bypass, force executing %s", ui.Dim(tc.hash)))
@@ -164,9 +169,12 @@ func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi
 	}
 	hasChangedOutputs := len(changedOutputGlobs) > 0
 	if hasChangedOutputs {
-		// Note that we currently don't use the output globs when restoring, but we could in the
-		// future to avoid doing unnecessary file I/O
-		hit, _, _, err := tc.rc.cache.Fetch(tc.rc.repoRoot.ToString(), tc.hash, changedOutputGlobs)
+		// The cache removes the files matching the globs we pass before it restores a hit
+		var cleanedOutputGlobs []string
+		if tc.cleanBeforeRestore {
+			cleanedOutputGlobs = changedOutputGlobs
+		}
+		hit, _, _, err := tc.rc.cache.Fetch(tc.rc.repoRoot.ToString(), tc.hash, cleanedOutputGlobs)
 		if err != nil {
 			return false, err
 		} else if !hit {
@@ -195,7 +203,7 @@ func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi
 		if tc.LogFileName.FileExists() {
 			// The task label is baked into the log file, so we need to grab the underlying Ui
 			// instance in order to not duplicate it
-			tc.rc.logReplayer(logger, terminal.Ui, tc.LogFileName)
+			tc.rc.logReplayer(logger, terminal.Ui, stdout, stderr, tc.LogFileName)
 		}
 	default:
 		// NoLogs, do not output anything
@@ -203,31 +211,20 @@ func (tc TaskCache) RestoreOutputs(ctx context.Context, terminal *cli.PrefixedUi
 	return true, nil
 }
 
-// nopWriteCloser is modeled after io.NopCloser, which is for Readers
-type nopWriteCloser struct {
-	io.Writer
-}
-
-func (nopWriteCloser) Close() error { return nil }
-
-type fileWriterCloser struct {
-	io.Writer
-	file  *os.File
-	bufio *bufio.Writer
-}
-
-func (fwc *fileWriterCloser) Close() error {
-	if err := fwc.bufio.Flush(); err != nil {
-		return err
+// CleanOutputs removes the task's existing outputs before it runs, if it is configured to
+func (tc TaskCache) CleanOutputs(logger hclog.Logger) error {
+	if !tc.cleanBeforeRun {
+		return nil
 	}
-	return fwc.file.Close()
+	logger.Debug("cleaning outputs", "outputs", tc.repoRelativeGlobs)
+	return cache.CleanOutputs(tc.rc.repoRoot, tc.repoRelativeGlobs)
 }
 
 // OutputWriter creates a sink suitable for handling the output of the command associated
-// with this task.
-func (tc TaskCache) OutputWriter() (io.WriteCloser, error) {
+// with this task. Output that should be shown is written through to the given terminal writers.
+func (tc TaskCache) OutputWriter(stdout io.Writer, stderr io.Writer) (*TaskOutputWriter, error) {
 	if tc.cachingDisabled || tc.rc.writesDisabled {
-		return nopWriteCloser{os.Stdout}, nil
+		return &TaskOutputWriter{Stdout: stdout, Stderr: stderr}, nil
 	}
 	// Setup log file
 	if err := tc.LogFileName.EnsureDir(); err != nil {
@@ -239,24 +236,26 @@ func (tc TaskCache) OutputWriter() (io.WriteCloser, error) {
 	}
 	colorPrefixer := tc.rc.colorCache.PrefixColor(tc.pt.PackageName)
 	prettyTaskPrefix := colorPrefixer(tc.pt.OutputPrefix())
-	bufWriter := bufio.NewWriter(output)
-	if _, err := bufWriter.WriteString(fmt.Sprintf("%s: cache hit, replaying output %s\n", prettyTaskPrefix, ui.Dim(tc.hash))); err != nil {
+	logFile := newLogFileWriter(output)
+	fileStdout := logFile.stream(_stdoutTag)
+	if _, err := fmt.Fprintf(fileStdout, "%s: cache hit, replaying output %s\n", prettyTaskPrefix, ui.Dim(tc.hash)); err != nil {
 		// We've already errored, we don't care if there's a further error closing the file we just
 		// failed to write to.
 		_ = output.Close()
 		return nil, err
 	}
-	fwc := &fileWriterCloser{
-		file:  output,
-		bufio: bufWriter,
+	tow := &TaskOutputWriter{
+		file: logFile,
 	}
 	if tc.taskOutputMode == util.NoTaskOutput || tc.taskOutputMode == util.HashTaskOutput {
-		// only write to log file, not to stdout
-		fwc.Writer = bufWriter
+		// only write to log file, not to the terminal
+		tow.Stdout = fileStdout
+		tow.Stderr = logFile.stream(_stderrTag)
 	} else {
-		fwc.Writer = io.MultiWriter(os.Stdout, bufWriter)
+		tow.Stdout = io.MultiWriter(stdout, fileStdout)
+		tow.Stderr = io.MultiWriter(stderr, logFile.stream(_stderrTag))
 	}
-	return fwc, nil
+	return tow, nil
 }
 
 var _emptyIgnore []string
@@ -315,18 +314,20 @@ func (rc *RunCache) TaskCache(pt *nodes.PackageTask, hash string) TaskCache {
 	}
 
 	return TaskCache{
-		rc:                rc,
-		repoRelativeGlobs: repoRelativeGlobs,
-		hash:              hash,
-		pt:                pt,
-		taskOutputMode:    taskOutputMode,
-		cachingDisabled:   !pt.TaskDefinition.ShouldCache,
-		LogFileName:       logFileName,
+		rc:                 rc,
+		repoRelativeGlobs:  repoRelativeGlobs,
+		hash:               hash,
+		pt:                 pt,
+		taskOutputMode:     taskOutputMode,
+		cachingDisabled:    !pt.TaskDefinition.ShouldCache,
+		cleanBeforeRestore: pt.TaskDefinition.CleansOutputsBeforeRestore(),
+		cleanBeforeRun:     pt.TaskDefinition.CleansOutputsBeforeRun(),
+		LogFileName:        logFileName,
 	}
 }
 
-// defaultLogReplayer will try to replay logs back to the given Ui instance
-func defaultLogReplayer(logger hclog.Logger, output cli.Ui, logFileName fs.AbsolutePath) {
+// defaultLogReplayer will try to replay logs back to the given writers
+func defaultLogReplayer(logger hclog.Logger, output cli.Ui, stdout io.Writer, stderr io.Writer, logFileName fs.AbsolutePath) {
 	logger.Debug("start replaying logs")
 	f, err := logFileName.Open()
 	if err != nil {
@@ -336,7 +337,12 @@ func defaultLogReplayer(logger hclog.Logger, output cli.Ui, logFileName fs.Absol
 	defer func() { _ = f.Close() }()
 	scan := bufio.NewScanner(f)
 	for scan.Scan() {
-		output.Output(string(scan.Bytes())) //Writing to Stdout
+		line, isStderr := parseLogLine(scan.Text())
+		if isStderr {
+			fmt.Fprintln(stderr, line)
+		} else {
+			fmt.Fprintln(stdout, line)
+		}
 	}
 	logger.Debug("finish replaying logs")
 }
@@ -0,0 +1,205 @@
+package scm
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Range describes the span of history that is compared when finding changed files.
+type Range struct {
+	// FromRef is the lower bound as it was requested, for instance "origin/HEAD"
+	FromRef string
+	// ResolvedFromRef is FromRef after resolving a remote's default branch, for instance "origin/main"
+	ResolvedFromRef string
+	// MergeBase is the commit that ToRef is compared against
+	MergeBase string
+	// ToRef is the upper bound of the comparison
+	ToRef string
+	// Deepened is true if history had to be fetched to find the merge base
+	Deepened bool
+}
+
+// String describes the range in a form suitable for showing to users
+func (r *Range) String() string {
+	from := r.ResolvedFromRef
+	if r.FromRef != r.ResolvedFromRef {
+		from = fmt.Sprintf("%v (%v)", r.ResolvedFromRef, r.FromRef)
+	}
+	description := fmt.Sprintf("%v...%v", from, r.ToRef)
+	if r.MergeBase != "" && r.MergeBase != r.ResolvedFromRef {
+		description += fmt.Sprintf(", merge base %v", shortSha(r.MergeBase))
+	}
+	if r.Deepened {
+		description += " (fetched additional history)"
+	}
+	return description
+}
+
+func shortSha(sha string) string {
+	if len(sha) > 10 {
+		return sha[:10]
+	}
+	return sha
+}
+
+// _initialDeepenBy is the number of commits to fetch the first time that we
+// deepen a shallow clone. Each subsequent attempt doubles the amount.
+const _initialDeepenBy = 50
+
+var _remoteHeadRegex = regexp.MustCompile(`^([^/]+)/HEAD$`)
+
+// ResolveRange finds the commit that changes between fromCommit and toCommit
+// should be calculated from, which is the merge base of the two. A fromCommit of
+// the form <remote>/HEAD is resolved to the default branch of that remote.
+// If deepen is true and the repository is a shallow clone, history is fetched
+// from the remote until the merge base can be found.
+func (g *git) ResolveRange(fromCommit string, toCommit string, deepen bool) (*Range, error) {
+	r := &Range{
+		FromRef:         fromCommit,
+		ResolvedFromRef: fromCommit,
+		ToRef:           toCommit,
+	}
+	remote := ""
+	if match := _remoteHeadRegex.FindStringSubmatch(fromCommit); match != nil && g.isRemote(match[1]) {
+		remote = match[1]
+		resolved, err := g.resolveRemoteHead(remote)
+		if err != nil {
+			return nil, err
+		}
+		r.ResolvedFromRef = resolved
+	} else if parts := strings.SplitN(fromCommit, "/", 2); len(parts) == 2 && g.isRemote(parts[0]) {
+		remote = parts[0]
+	}
+
+	if deepen && remote != "" {
+		if exists, err := commitExists(r.ResolvedFromRef); err != nil {
+			return nil, err
+		} else if !exists {
+			if err := g.fetchRemoteBranch(r.ResolvedFromRef, remote); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	mergeBase, err := g.mergeBase(r.ResolvedFromRef, toCommit)
+	deepenBy := _initialDeepenBy
+	for err != nil && deepen {
+		isShallow, shallowErr := g.isShallow()
+		if shallowErr != nil || !isShallow {
+			break
+		}
+		if fetchErr := g.deepen(remote, deepenBy); fetchErr != nil {
+			return nil, fetchErr
+		}
+		r.Deepened = true
+		deepenBy *= 2
+		mergeBase, err = g.mergeBase(r.ResolvedFromRef, toCommit)
+	}
+	if err != nil {
+		if exists, existsErr := commitExists(r.ResolvedFromRef); existsErr == nil && !exists {
+			return nil, fmt.Errorf("commit %v does not exist", r.ResolvedFromRef)
+		}
+		if isShallow, shallowErr := g.isShallow(); shallowErr == nil && isShallow && !deepen {
+			return nil, fmt.Errorf("cannot find a merge base for %v and %v in this shallow clone. Fetch more history, or pass --deepen-shallow-clone to let turbo do it", r.ResolvedFromRef, toCommit)
+		}
+		return nil, errors.Wrapf(err, "finding merge base of %v and %v", r.ResolvedFromRef, toCommit)
+	}
+	r.MergeBase = mergeBase
+	return r, nil
+}
+
+func (g *git) command(args ...string) *exec.Cmd {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.repoRoot
+	return cmd
+}
+
+func (g *git) output(args ...string) (string, error) {
+	out, err := g.command(args...).Output()
+	if err != nil {
+		exitErr := &exec.ExitError{}
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %v: %v", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", errors.Wrapf(err, "git %v", strings.Join(args, " "))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (g *git) isRemote(name string) bool {
+	out, err := g.output("remote")
+	if err != nil {
+		return false
+	}
+	for _, remote := range strings.Split(out, "\n") {
+		if remote == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRemoteHead finds the default branch of a remote. It prefers the
+// locally recorded refs/remotes/<remote>/HEAD, which most CI checkouts do not
+// set, and falls back to asking the remote.
+func (g *git) resolveRemoteHead(remote string) (string, error) {
+	remotePrefix := "refs/remotes/"
+	if ref, err := g.output("symbolic-ref", "--quiet", remotePrefix+remote+"/HEAD"); err == nil && ref != "" {
+		return strings.TrimPrefix(ref, remotePrefix), nil
+	}
+	out, err := g.output("ls-remote", "--symref", remote, "HEAD")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to detect the default branch of %v", remote)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		// ref: refs/heads/main	HEAD
+		if strings.HasPrefix(line, "ref: ") && strings.HasSuffix(line, "\tHEAD") {
+			branch := strings.TrimSuffix(strings.TrimPrefix(line, "ref: refs/heads/"), "\tHEAD")
+			return remote + "/" + branch, nil
+		}
+	}
+	return "", fmt.Errorf("failed to detect the default branch of %v", remote)
+}
+
+// fetchRemoteBranch fetches a remote-tracking branch that is missing locally,
+// as is the case for single-branch CI checkouts.
+func (g *git) fetchRemoteBranch(ref string, remote string) error {
+	branch := strings.TrimPrefix(ref, remote+"/")
+	args := []string{"fetch", "--no-tags"}
+	if isShallow, err := g.isShallow(); err == nil && isShallow {
+		args = append(args, "--depth="+strconv.Itoa(_initialDeepenBy))
+	}
+	args = append(args, remote, fmt.Sprintf("+refs/heads/%v:refs/remotes/%v", branch, ref))
+	if _, err := g.output(args...); err != nil {
+		return errors.Wrapf(err, "failed to fetch %v", ref)
+	}
+	return nil
+}
+
+func (g *git) deepen(remote string, by int) error {
+	args := []string{"fetch", "--no-tags", "--deepen=" + strconv.Itoa(by)}
+	if remote != "" {
+		args = append(args, remote)
+	}
+	if _, err := g.output(args...); err != nil {
+		return errors.Wrap(err, "failed to deepen shallow clone")
+	}
+	return nil
+}
+
+func (g *git) isShallow() (bool, error) {
+	out, err := g.output("rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return false, err
+	}
+	return out == "true", nil
+}
+
+func (g *git) mergeBase(fromCommit string, toCommit string) (string, error) {
+	return g.output("merge-base", fromCommit, toCommit)
+}
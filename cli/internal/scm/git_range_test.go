@@ -0,0 +1,102 @@
+package scm
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=turbo", "-c", "user.email=turbo@example.com", "-c", "protocol.file.allow=always"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func commitFile(t *testing.T, dir string, name string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+		t.Fatalf("failed to write %v: %v", name, err)
+	}
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "--quiet", "-m", name)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
+
+// setupRemote creates an upstream repository with a default branch of "trunk"
+// and a feature branch that diverges from it after forkPoint commits.
+func setupRemote(t *testing.T) (string, string) {
+	upstream := t.TempDir()
+	runGit(t, upstream, "init", "--quiet")
+	runGit(t, upstream, "checkout", "--quiet", "-b", "trunk")
+	var forkPoint string
+	for i := 0; i < 5; i++ {
+		forkPoint = commitFile(t, upstream, "base-"+string(rune('a'+i)))
+	}
+	runGit(t, upstream, "checkout", "--quiet", "-b", "feature")
+	for i := 0; i < 5; i++ {
+		commitFile(t, upstream, "feature-"+string(rune('a'+i)))
+	}
+	runGit(t, upstream, "checkout", "--quiet", "trunk")
+	commitFile(t, upstream, "trunk-only")
+	return upstream, forkPoint
+}
+
+func TestResolveRange(t *testing.T) {
+	upstream, forkPoint := setupRemote(t)
+
+	clone := t.TempDir()
+	runGit(t, clone, "clone", "--quiet", "--branch", "feature", "file://"+upstream, ".")
+	// Simulate a CI checkout where origin/HEAD is not recorded locally
+	runGit(t, clone, "remote", "set-head", "origin", "--delete")
+	g := &git{repoRoot: clone}
+
+	r, err := g.ResolveRange("origin/HEAD", "HEAD", false)
+	if err != nil {
+		t.Fatalf("ResolveRange: %v", err)
+	}
+	if r.ResolvedFromRef != "origin/trunk" {
+		t.Errorf("ResolvedFromRef got %v, want origin/trunk", r.ResolvedFromRef)
+	}
+	if r.MergeBase != forkPoint {
+		t.Errorf("MergeBase got %v, want %v", r.MergeBase, forkPoint)
+	}
+	if r.Deepened {
+		t.Error("expected a full clone to not need deepening")
+	}
+	if want := "origin/trunk (origin/HEAD)...HEAD, merge base " + forkPoint[:10]; r.String() != want {
+		t.Errorf("String() got %v, want %v", r.String(), want)
+	}
+}
+
+func TestResolveRangeShallowClone(t *testing.T) {
+	upstream, forkPoint := setupRemote(t)
+
+	clone := t.TempDir()
+	runGit(t, clone, "clone", "--quiet", "--depth=1", "--single-branch", "--branch", "feature", "file://"+upstream, ".")
+	g := &git{repoRoot: clone}
+
+	if _, err := g.ResolveRange("origin/HEAD", "HEAD", false); err == nil {
+		t.Error("expected an error finding the merge base without deepening")
+	}
+
+	r, err := g.ResolveRange("origin/HEAD", "HEAD", true)
+	if err != nil {
+		t.Fatalf("ResolveRange: %v", err)
+	}
+	if r.ResolvedFromRef != "origin/trunk" {
+		t.Errorf("ResolvedFromRef got %v, want origin/trunk", r.ResolvedFromRef)
+	}
+	if r.MergeBase != forkPoint {
+		t.Errorf("MergeBase got %v, want %v", r.MergeBase, forkPoint)
+	}
+	if !r.Deepened {
+		t.Error("expected the shallow clone to be deepened")
+	}
+}
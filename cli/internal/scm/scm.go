@@ -20,6 +20,9 @@ var ErrFallback = errors.New("cannot find a .git folder. Falling back to manual
 type SCM interface {
 	// ChangedFiles returns a list of modified files since the given commit, optionally including untracked files.*/
 	ChangedFiles(fromCommit string, toCommit string, includeUntracked bool, relativeTo string) ([]string, error)
+	// ResolveRange finds the commits that changes between fromCommit and toCommit are calculated from,
+	// optionally deepening a shallow clone until they can be found.
+	ResolveRange(fromCommit string, toCommit string, deepen bool) (*Range, error)
 }
 
 // newGitSCM returns a new SCM instance for this repo root.
@@ -8,3 +8,12 @@ type stub struct{}
 func (s *stub) ChangedFiles(fromCommit string, toCommit string, includeUntracked bool, relativeTo string) ([]string, error) {
 	return nil, nil
 }
+
+func (s *stub) ResolveRange(fromCommit string, toCommit string, deepen bool) (*Range, error) {
+	return &Range{
+		FromRef:         fromCommit,
+		ResolvedFromRef: fromCommit,
+		MergeBase:       fromCommit,
+		ToRef:           toCommit,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/util"
+)
+
+// Attributes of a package that can be selected on, in the form <attribute>:<value>
+const (
+	// private:true selects packages that are marked private in package.json, private:false those that are not
+	attributePrivate = "private"
+	// has-script:<pattern> selects packages with a script matching the pattern
+	attributeHasScript = "has-script"
+	// depends-on:<pattern> selects packages that declare an external dependency matching the
+	// pattern. Dependencies on workspace packages are selected with ... instead.
+	attributeDependsOn = "depends-on"
+)
+
+var attributeSelectorRegex = regexp.MustCompile(`^([a-z][a-z-]*):([^{}[\]]+)$`)
+
+// parseAttribute validates an attribute predicate and returns a matcher for package.json files.
+// workspaces are the names of the packages in the workspace, which depends-on doesn't match.
+func parseAttribute(name string, value string, workspaces util.Set) (func(pkg *fs.PackageJSON) bool, error) {
+	switch name {
+	case attributePrivate:
+		if value != "true" && value != "false" {
+			return nil, fmt.Errorf("invalid value for %v: %v. Expected true or false", name, value)
+		}
+		isPrivate := value == "true"
+		return func(pkg *fs.PackageJSON) bool {
+			return pkg.Private == isPrivate
+		}, nil
+	case attributeHasScript:
+		matcher, err := matcherFromPattern(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(pkg *fs.PackageJSON) bool {
+			for script := range pkg.Scripts {
+				if matcher(script) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case attributeDependsOn:
+		matcher, err := matcherFromPattern(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(pkg *fs.PackageJSON) bool {
+			for _, deps := range []map[string]string{pkg.Dependencies, pkg.DevDependencies, pkg.OptionalDependencies, pkg.PeerDependencies} {
+				for dep := range deps {
+					if matcher(dep) && !workspaces.Includes(dep) {
+						return true
+					}
+				}
+			}
+			return false
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown package attribute %v. Expected one of %v, %v or %v", name, attributePrivate, attributeHasScript, attributeDependsOn)
+	}
+}
+
+// filterNodesWithAttribute returns the set of workspace packages whose package.json matches
+// the selector's attribute predicate. The root package is never matched, as running a task
+// there is only done on request.
+func (r *Resolver) filterNodesWithAttribute(selector *TargetSelector) (util.Set, error) {
+	workspaces := make(util.Set)
+	for name := range r.PackageInfos {
+		workspaces.Add(name)
+	}
+	matches, err := parseAttribute(selector.attributeName, selector.attributeValue, workspaces)
+	if err != nil {
+		return nil, err
+	}
+	entryPackages := make(util.Set)
+	for name, pkg := range r.PackageInfos {
+		if name != util.RootPkgName && matches(pkg) {
+			entryPackages.Add(name)
+		}
+	}
+	return entryPackages, nil
+}
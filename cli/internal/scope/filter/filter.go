@@ -28,6 +28,20 @@ type Resolver struct {
 	Cwd string
 	// HasGlobalChange      bool
 	PackagesChangedInRange PackagesChangedInRange
+	// TaskInputsChangedInRange provides the set of packages where a file that is an input
+	// to one of the tasks being run has changed. It is used for selectors ending in {inputs}.
+	TaskInputsChangedInRange PackagesChangedInRange
+}
+
+// packagesChangedInRange returns the packages that a selector with a git range considers changed
+func (r *Resolver) packagesChangedInRange(selector *TargetSelector) (util.Set, error) {
+	if selector.matchInputs {
+		if r.TaskInputsChangedInRange == nil {
+			return nil, fmt.Errorf("%v: task inputs are not available to match against", selector.raw)
+		}
+		return r.TaskInputsChangedInRange(selector.fromRef, selector.getToRef())
+	}
+	return r.PackagesChangedInRange(selector.fromRef, selector.getToRef())
 }
 
 // GetPackagesFromPatterns compiles filter patterns and applies them, returning
@@ -110,59 +124,101 @@ func (r *Resolver) filterGraph(selectors []*TargetSelector) (*SelectedPackages,
 
 func (r *Resolver) filterGraphWithSelectors(selectors []*TargetSelector) (*SelectedPackages, error) {
 	unmatchedSelectors := []*TargetSelector{}
+	allPkgs := make(util.Set)
+	for _, selector := range selectors {
+		selected, matched, err := r.filterGraphWithIntersection(selector)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			unmatchedSelectors = append(unmatchedSelectors, selector)
+		}
+		for pkg := range selected {
+			allPkgs.Add(pkg)
+		}
+	}
+	return &SelectedPackages{
+		pkgs:          allPkgs,
+		unusedFilters: unmatchedSelectors,
+	}, nil
+}
+
+// filterGraphWithIntersection returns the packages selected by a selector, narrowed down by
+// any selectors that are intersected with it. It also reports whether the selector matched
+// anything, which for an intersection means that the final result is not empty.
+func (r *Resolver) filterGraphWithIntersection(selector *TargetSelector) (util.Set, bool, error) {
+	selected, matched, err := r.expandSelector(selector)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(selector.intersect) == 0 {
+		return selected, matched, nil
+	}
+	for _, other := range selector.intersect {
+		otherPkgs, _, err := r.expandSelector(other)
+		if err != nil {
+			return nil, false, err
+		}
+		if other.exclude {
+			selected = selected.Difference(otherPkgs)
+		} else {
+			selected = selected.Intersection(otherPkgs)
+		}
+	}
+	return selected, selected.Len() > 0, nil
+}
 
+// expandSelector returns the packages matched by a single selector, along with their
+// dependencies and dependents if requested. It also reports whether any package matched
+// the selector before dependencies and dependents were considered.
+func (r *Resolver) expandSelector(selector *TargetSelector) (util.Set, bool, error) {
 	cherryPickedPackages := make(dag.Set)
 	walkedDependencies := make(dag.Set)
 	walkedDependents := make(dag.Set)
 	walkedDependentsDependencies := make(dag.Set)
 
-	for _, selector := range selectors {
-		// TODO(gsoltis): this should be a list?
-		entryPackages, err := r.filterGraphWithSelector(selector)
-		if err != nil {
-			return nil, err
-		}
-		if entryPackages.Len() == 0 {
-			unmatchedSelectors = append(unmatchedSelectors, selector)
+	// TODO(gsoltis): this should be a list?
+	entryPackages, err := r.filterGraphWithSelector(selector)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, pkg := range entryPackages {
+		if selector.includeDependencies {
+			dependencies, err := r.Graph.Ancestors(pkg)
+			if err != nil {
+				return nil, false, errors.Wrapf(err, "failed to get dependencies of package %v", pkg)
+			}
+			for dep := range dependencies {
+				walkedDependencies.Add(dep)
+			}
+			if !selector.excludeSelf {
+				walkedDependencies.Add(pkg)
+			}
 		}
-		for _, pkg := range entryPackages {
-			if selector.includeDependencies {
-				dependencies, err := r.Graph.Ancestors(pkg)
-				if err != nil {
-					return nil, errors.Wrapf(err, "failed to get dependencies of package %v", pkg)
-				}
-				for dep := range dependencies {
-					walkedDependencies.Add(dep)
-				}
-				if !selector.excludeSelf {
-					walkedDependencies.Add(pkg)
-				}
+		if selector.includeDependents {
+			dependents, err := r.Graph.Descendents(pkg)
+			if err != nil {
+				return nil, false, errors.Wrapf(err, "failed to get dependents of package %v", pkg)
 			}
-			if selector.includeDependents {
-				dependents, err := r.Graph.Descendents(pkg)
-				if err != nil {
-					return nil, errors.Wrapf(err, "failed to get dependents of package %v", pkg)
-				}
-				for dep := range dependents {
-					walkedDependents.Add(dep)
-					if selector.includeDependencies {
-						dependentDeps, err := r.Graph.Ancestors(dep)
-						if err != nil {
-							return nil, errors.Wrapf(err, "failed to get dependencies of dependent %v", dep)
-						}
-						for dependentDep := range dependentDeps {
-							walkedDependentsDependencies.Add(dependentDep)
-						}
+			for dep := range dependents {
+				walkedDependents.Add(dep)
+				if selector.includeDependencies {
+					dependentDeps, err := r.Graph.Ancestors(dep)
+					if err != nil {
+						return nil, false, errors.Wrapf(err, "failed to get dependencies of dependent %v", dep)
+					}
+					for dependentDep := range dependentDeps {
+						walkedDependentsDependencies.Add(dependentDep)
 					}
-				}
-				if !selector.excludeSelf {
-					walkedDependents.Add(pkg)
 				}
 			}
-			if !selector.includeDependencies && !selector.includeDependents {
-				cherryPickedPackages.Add(pkg)
+			if !selector.excludeSelf {
+				walkedDependents.Add(pkg)
 			}
 		}
+		if !selector.includeDependencies && !selector.includeDependents {
+			cherryPickedPackages.Add(pkg)
+		}
 	}
 	allPkgs := make(util.Set)
 	for pkg := range cherryPickedPackages {
@@ -177,10 +233,7 @@ func (r *Resolver) filterGraphWithSelectors(selectors []*TargetSelector) (*Selec
 	for pkg := range walkedDependentsDependencies {
 		allPkgs.Add(pkg)
 	}
-	return &SelectedPackages{
-		pkgs:          allPkgs,
-		unusedFilters: unmatchedSelectors,
-	}, nil
+	return allPkgs, entryPackages.Len() > 0, nil
 }
 
 func (r *Resolver) filterGraphWithSelector(selector *TargetSelector) (util.Set, error) {
@@ -192,12 +245,15 @@ func (r *Resolver) filterGraphWithSelector(selector *TargetSelector) (util.Set,
 
 // filterNodesWithSelector returns the set of nodes that match a given selector
 func (r *Resolver) filterNodesWithSelector(selector *TargetSelector) (util.Set, error) {
+	if selector.attributeName != "" {
+		return r.filterNodesWithAttribute(selector)
+	}
 	entryPackages := make(util.Set)
 	selectorWasUsed := false
 	if selector.fromRef != "" {
 		// get changed packaged
 		selectorWasUsed = true
-		changedPkgs, err := r.PackagesChangedInRange(selector.fromRef, selector.getToRef())
+		changedPkgs, err := r.packagesChangedInRange(selector)
 		if err != nil {
 			return nil, err
 		}
@@ -268,7 +324,7 @@ func (r *Resolver) filterNodesWithSelector(selector *TargetSelector) (util.Set,
 // match a selector
 func (r *Resolver) filterSubtreesWithSelector(selector *TargetSelector) (util.Set, error) {
 	// foreach package that matches parentDir && namePattern, check if any dependency is in changed packages
-	changedPkgs, err := r.PackagesChangedInRange(selector.fromRef, selector.getToRef())
+	changedPkgs, err := r.packagesChangedInRange(selector)
 	if err != nil {
 		return nil, err
 	}
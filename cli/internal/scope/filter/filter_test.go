@@ -426,6 +426,14 @@ func Test_SCM(t *testing.T) {
 			}
 			panic(fmt.Sprintf("unsupported commit range %v...%v", fromRef, toRef))
 		},
+		TaskInputsChangedInRange: func(fromRef string, toRef string) (util.Set, error) {
+			if fromRef == "HEAD~1" && toRef == "HEAD" {
+				inputsChanged := make(util.Set)
+				inputsChanged.Add("package-2")
+				return inputsChanged, nil
+			}
+			panic(fmt.Sprintf("unsupported commit range %v...%v", fromRef, toRef))
+		},
 	}
 
 	testCases := []struct {
@@ -516,6 +524,16 @@ func Test_SCM(t *testing.T) {
 			},
 			[]string{"package-3"},
 		},
+		{
+			"changed task inputs",
+			[]*TargetSelector{
+				{
+					fromRef:     "HEAD~1",
+					matchInputs: true,
+				},
+			},
+			[]string{"package-2"},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -528,3 +546,115 @@ func Test_SCM(t *testing.T) {
 		})
 	}
 }
+
+func Test_filterIntersectionsAndAttributes(t *testing.T) {
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	// web -> ui -> utils
+	// docs -> ui
+	// api -> utils
+	graph := &dag.AcyclicGraph{}
+	packageJSONs := map[interface{}]*fs.PackageJSON{
+		util.RootPkgName: {
+			Name:    "root",
+			Private: true,
+			Scripts: map[string]string{"test": "turbo run test"},
+		},
+		"web": {
+			Name:         "web",
+			Dir:          turbopath.AnchoredSystemPath(filepath.Join("apps", "web")),
+			Private:      true,
+			Scripts:      map[string]string{"build": "next build", "test": "jest"},
+			Dependencies: map[string]string{"react": "^18.0.0", "ui": "*"},
+		},
+		"docs": {
+			Name:         "docs",
+			Dir:          turbopath.AnchoredSystemPath(filepath.Join("apps", "docs")),
+			Private:      true,
+			Scripts:      map[string]string{"build": "next build"},
+			Dependencies: map[string]string{"react": "^17.0.0", "ui": "*"},
+		},
+		"api": {
+			Name:            "api",
+			Dir:             turbopath.AnchoredSystemPath(filepath.Join("apps", "api")),
+			Scripts:         map[string]string{"build": "tsc", "test:unit": "jest"},
+			DevDependencies: map[string]string{"@types/node": "^18.0.0"},
+		},
+		"ui": {
+			Name:             "ui",
+			Dir:              turbopath.AnchoredSystemPath(filepath.Join("packages", "ui")),
+			Scripts:          map[string]string{"test": "jest"},
+			PeerDependencies: map[string]string{"react": "*"},
+		},
+		"utils": {
+			Name: "utils",
+			Dir:  turbopath.AnchoredSystemPath(filepath.Join("packages", "utils")),
+		},
+	}
+	for name := range packageJSONs {
+		if name != util.RootPkgName {
+			graph.Add(name)
+		}
+	}
+	graph.Connect(dag.BasicEdge("web", "ui"))
+	graph.Connect(dag.BasicEdge("docs", "ui"))
+	graph.Connect(dag.BasicEdge("ui", "utils"))
+	graph.Connect(dag.BasicEdge("api", "utils"))
+
+	r := &Resolver{
+		Graph:        graph,
+		PackageInfos: packageJSONs,
+		Cwd:          root,
+		PackagesChangedInRange: func(fromRef string, toRef string) (util.Set, error) {
+			changed := make(util.Set)
+			if fromRef == "main" {
+				changed.Add("ui")
+				changed.Add("api")
+			}
+			return changed, nil
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{"private packages", []string{"private:true"}, []string{"web", "docs"}},
+		{"public packages", []string{"private:false"}, []string{"api", "ui", "utils"}},
+		{"packages with a script", []string{"has-script:test"}, []string{"web", "ui"}},
+		{"packages with a script matching a pattern", []string{"has-script:test*"}, []string{"web", "api", "ui"}},
+		{"packages depending on a package", []string{"depends-on:react"}, []string{"web", "docs", "ui"}},
+		{"packages depending on a package matching a pattern", []string{"depends-on:@types/*"}, []string{"api"}},
+		// Dependencies on workspace packages are selected with ... instead
+		{"packages depending on a workspace package", []string{"depends-on:ui"}, []string{}},
+		{"packages with any external dependency", []string{"depends-on:*"}, []string{"web", "docs", "api", "ui"}},
+		{"dependents of packages with an attribute", []string{"...has-script:test"}, []string{"web", "docs", "ui"}},
+		{"excluding packages with an attribute", []string{"!private:true"}, []string{"api", "ui", "utils"}},
+		{"changed packages in a directory", []string{"[main]&{./apps/*}"}, []string{"api"}},
+		{"dependents of changed packages in a directory", []string{"...[main]&{./apps/*}"}, []string{"web", "docs", "api"}},
+		{"changed packages with an attribute", []string{"[main]&has-script:test"}, []string{"ui"}},
+		{"multiple intersections", []string{"{./apps/*}&private:true&depends-on:react"}, []string{"web", "docs"}},
+		{"intersection with an exclusion", []string{"{./apps/*}&!has-script:test"}, []string{"docs", "api"}},
+		{"union of intersections", []string{"{./apps/*}&has-script:test", "{./packages/*}&private:false"}, []string{"web", "ui", "utils"}},
+		{"excluding an intersection", []string{"!{./apps/*}&private:true"}, []string{"api", "ui", "utils"}},
+		{"empty intersection", []string{"web&docs"}, []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pkgs, err := r.GetPackagesFromPatterns(tc.patterns)
+			if err != nil {
+				t.Fatalf("%v failed to filter packages: %v", tc.name, err)
+			}
+			setMatches(t, tc.name, pkgs, tc.expected)
+		})
+	}
+
+	for _, invalid := range []string{"private:maybe", "colour:blue", "&web", "web&", "!web&&docs"} {
+		if _, err := r.GetPackagesFromPatterns([]string{invalid}); err == nil {
+			t.Errorf("expected an error for %v", invalid)
+		}
+	}
+}
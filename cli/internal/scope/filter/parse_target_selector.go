@@ -2,6 +2,7 @@ package filter
 
 import (
 	"errors"
+	"fmt"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -18,11 +19,19 @@ type TargetSelector struct {
 	namePattern         string
 	fromRef             string
 	toRefOverride       string
-	raw                 string
+	// matchInputs restricts changes in fromRef to those that match the inputs of the tasks being run
+	matchInputs bool
+	// attributeName and attributeValue select packages by a property of their package.json
+	attributeName  string
+	attributeValue string
+	// intersect are further selectors that packages must also match, or must not match if they
+	// are exclusions, to be selected
+	intersect []*TargetSelector
+	raw       string
 }
 
 func (ts *TargetSelector) IsValid() bool {
-	return ts.fromRef != "" || ts.parentDir != "" || ts.namePattern != ""
+	return ts.fromRef != "" || ts.parentDir != "" || ts.namePattern != "" || ts.attributeName != ""
 }
 
 // getToRef returns the git ref to use for upper bound of the comparison when finding changed
@@ -36,7 +45,14 @@ func (ts *TargetSelector) getToRef() string {
 
 var errCantMatchDependencies = errors.New("cannot use match dependencies without specifying either a directory or package")
 
-var targetSelectorRegex = regexp.MustCompile(`^([^.](?:[^{}[\]]*[^{}[\].])?)?(\{[^}]+\})?((?:\.{3})?\[[^\]]+\])?$`)
+var errCantMatchInputs = errors.New("cannot match task inputs without specifying a git range")
+
+// _intersectionDelimiter joins selectors that packages must match all of
+const _intersectionDelimiter = "&"
+
+const _inputsModifier = "{inputs}"
+
+var targetSelectorRegex = regexp.MustCompile(`^([^.](?:[^{}[\]]*[^{}[\].])?)?(\{[^}]+\})?((?:\.{3})?\[[^\]]+\])?(\{inputs\})?$`)
 
 // ParseTargetSelector is a function that returns pnpm compatible --filter command line flags
 func ParseTargetSelector(rawSelector string, prefix string) (TargetSelector, error) {
@@ -47,6 +63,9 @@ func ParseTargetSelector(rawSelector string, prefix string) (TargetSelector, err
 		selector = selector[1:]
 		exclude = true
 	}
+	if parts := strings.Split(selector, _intersectionDelimiter); len(parts) > 1 {
+		return parseIntersection(rawSelector, parts, exclude, prefix)
+	}
 	excludeSelf := false
 	includeDependencies := strings.HasSuffix(selector, "...")
 	if includeDependencies {
@@ -65,6 +84,21 @@ func ParseTargetSelector(rawSelector string, prefix string) (TargetSelector, err
 		}
 	}
 
+	if attribute := attributeSelectorRegex.FindStringSubmatch(selector); attribute != nil {
+		if _, err := parseAttribute(attribute[1], attribute[2], nil); err != nil {
+			return TargetSelector{}, err
+		}
+		return TargetSelector{
+			exclude:             exclude,
+			excludeSelf:         excludeSelf,
+			includeDependencies: includeDependencies,
+			includeDependents:   includeDependents,
+			attributeName:       attribute[1],
+			attributeValue:      attribute[2],
+			raw:                 rawSelector,
+		}, nil
+	}
+
 	matches := targetSelectorRegex.FindAllStringSubmatch(selector, -1)
 
 	if len(matches) == 0 {
@@ -92,6 +126,7 @@ func ParseTargetSelector(rawSelector string, prefix string) (TargetSelector, err
 	parentDir := ""
 	namePattern := ""
 	preAddDepdencies := false
+	matchInputs := false
 	if len(matches) > 0 && len(matches[0]) > 0 {
 		if len(matches[0][1]) > 0 {
 			namePattern = matches[0][1]
@@ -117,6 +152,12 @@ func ParseTargetSelector(rawSelector string, prefix string) (TargetSelector, err
 				toRefOverride = refs[1]
 			}
 		}
+		if matches[0][4] == _inputsModifier {
+			if fromRef == "" {
+				return TargetSelector{}, errCantMatchInputs
+			}
+			matchInputs = true
+		}
 	}
 
 	return TargetSelector{
@@ -129,10 +170,36 @@ func ParseTargetSelector(rawSelector string, prefix string) (TargetSelector, err
 		includeDependents:   includeDependents,
 		namePattern:         namePattern,
 		parentDir:           parentDir,
+		matchInputs:         matchInputs,
 		raw:                 rawSelector,
 	}, nil
 }
 
+// parseIntersection parses selectors joined by "&". The first selector is the base and the
+// remainder are intersected with it, or subtracted from it if they start with "!". A leading
+// "!" applies to the intersection as a whole.
+func parseIntersection(rawSelector string, parts []string, exclude bool, prefix string) (TargetSelector, error) {
+	selectors := make([]*TargetSelector, len(parts))
+	for i, part := range parts {
+		if part == "" {
+			return TargetSelector{}, fmt.Errorf("invalid selector %v: cannot intersect with an empty selector", rawSelector)
+		}
+		if i == 0 && strings.HasPrefix(part, "!") {
+			return TargetSelector{}, fmt.Errorf("invalid selector %v: the first selector of an intersection cannot be an exclusion", rawSelector)
+		}
+		selector, err := ParseTargetSelector(part, prefix)
+		if err != nil {
+			return TargetSelector{}, err
+		}
+		selectors[i] = &selector
+	}
+	base := *selectors[0]
+	base.exclude = exclude
+	base.intersect = selectors[1:]
+	base.raw = rawSelector
+	return base, nil
+}
+
 // isSelectorByLocation returns true if the selector is by filesystem location
 func isSelectorByLocation(rawSelector string) bool {
 	if rawSelector[0:1] != "." {
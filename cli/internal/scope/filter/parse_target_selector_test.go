@@ -312,6 +312,93 @@ func TestParseTargetSelector(t *testing.T) {
 			TargetSelector{},
 			true,
 		},
+		{
+			"[master]{inputs}",
+			args{"[master]{inputs}", "."},
+			TargetSelector{
+				fromRef:     "master",
+				matchInputs: true,
+			},
+			false,
+		},
+		{
+			"...{foo}[master...HEAD]{inputs}",
+			args{"...{foo}[master...HEAD]{inputs}", "."},
+			TargetSelector{
+				fromRef:           "master",
+				toRefOverride:     "HEAD",
+				parentDir:         "foo",
+				includeDependents: true,
+				matchInputs:       true,
+			},
+			false,
+		},
+		{
+			"{inputs}",
+			args{"{inputs}", "."},
+			TargetSelector{
+				parentDir: "inputs",
+			},
+			false,
+		},
+		{
+			"{foo}{inputs}",
+			args{"{foo}{inputs}", "."},
+			TargetSelector{},
+			true,
+		},
+		{
+			"private:true",
+			args{"private:true", "."},
+			TargetSelector{
+				attributeName:  "private",
+				attributeValue: "true",
+			},
+			false,
+		},
+		{
+			"...has-script:test",
+			args{"...has-script:test", "."},
+			TargetSelector{
+				attributeName:     "has-script",
+				attributeValue:    "test",
+				includeDependents: true,
+			},
+			false,
+		},
+		{
+			"unknown:attribute",
+			args{"unknown:attribute", "."},
+			TargetSelector{},
+			true,
+		},
+		{
+			"![master]&{./foo}&!depends-on:react",
+			args{"![master]&{./foo}&!depends-on:react", "."},
+			TargetSelector{
+				fromRef: "master",
+				exclude: true,
+				intersect: []*TargetSelector{
+					{
+						parentDir: "foo",
+						raw:       "{./foo}",
+					},
+					{
+						attributeName:  "depends-on",
+						attributeValue: "react",
+						exclude:        true,
+						raw:            "!depends-on:react",
+					},
+				},
+			},
+			false,
+		},
+		{
+			"foo&",
+			args{"foo&", "."},
+			TargetSelector{},
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -14,6 +14,7 @@ import (
 	"github.com/vercel/turborepo/cli/internal/fs"
 	"github.com/vercel/turborepo/cli/internal/scm"
 	scope_filter "github.com/vercel/turborepo/cli/internal/scope/filter"
+	"github.com/vercel/turborepo/cli/internal/ui"
 	"github.com/vercel/turborepo/cli/internal/util"
 	"github.com/vercel/turborepo/cli/internal/util/filter"
 )
@@ -33,7 +34,8 @@ type LegacyFilter struct {
 
 var _sinceHelp = `Limit/Set scope to changed packages since a
 mergebase. This uses the git diff ${target_branch}...
-mechanism to identify which packages have changed.`
+mechanism to identify which packages have changed.
+Use <remote>/HEAD to compare with the remote's default branch.`
 
 func addLegacyFlags(opts *LegacyFilter, flags *pflag.FlagSet) {
 	flags.BoolVar(&opts.IncludeDependencies, "include-dependencies", false, "Include the dependencies of tasks in execution.")
@@ -51,6 +53,11 @@ type Opts struct {
 	GlobalDepPatterns []string
 	// Patterns are the filter patterns supplied to --filter on the commandline
 	FilterPatterns []string
+	// DeepenShallowClone is whether to fetch history as needed to find the merge base of a git range
+	DeepenShallowClone bool
+	// reportedRanges holds the git ranges that have already been printed, since the same range
+	// is resolved for each filter and for each kind of change that refers to it
+	reportedRanges util.Set
 }
 
 var (
@@ -59,9 +66,13 @@ entry points. The syntax mirrors pnpm's syntax, and
 additional documentation and examples can be found in
 turbo's documentation https://turborepo.org/docs/reference/command-line-reference#--filter
 --filter can be specified multiple times. Packages that
-match any filter will be included.`
+match any filter will be included. Join selectors with &
+to only include packages that match all of them.`
 	_ignoreHelp    = `Files to ignore when calculating changed files (i.e. --since). Supports globs.`
 	_globalDepHelp = `Specify glob of global filesystem dependencies to be hashed. Useful for .env and files in the root directory.`
+	_deepenHelp    = `When comparing against a git ref (i.e. --since or [ref] filters)
+in a shallow clone, fetch history from the remote until
+the merge base is found.`
 )
 
 // AddFlags adds the flags relevant to this package to the given FlagSet
@@ -69,6 +80,7 @@ func AddFlags(opts *Opts, flags *pflag.FlagSet) {
 	flags.StringArrayVar(&opts.FilterPatterns, "filter", nil, _filterHelp)
 	flags.StringArrayVar(&opts.IgnorePatterns, "ignore", nil, _ignoreHelp)
 	flags.StringArrayVar(&opts.GlobalDepPatterns, "global-deps", nil, _globalDepHelp)
+	flags.BoolVar(&opts.DeepenShallowClone, "deepen-shallow-clone", false, _deepenHelp)
 	addLegacyFlags(&opts.LegacyFilter, flags)
 }
 
@@ -111,12 +123,13 @@ func (l *LegacyFilter) asFilterPatterns() []string {
 // ResolvePackages translates specified flags to a set of entry point packages for
 // the selected tasks. Returns the selected packages and whether or not the selected
 // packages represents a default "all packages".
-func ResolvePackages(opts *Opts, cwd string, scm scm.SCM, ctx *context.Context, tui cli.Ui, logger hclog.Logger) (util.Set, bool, error) {
+func ResolvePackages(opts *Opts, cwd string, scm scm.SCM, ctx *context.Context, pipeline fs.Pipeline, tasks []string, tui cli.Ui, logger hclog.Logger) (util.Set, bool, error) {
 	filterResolver := &scope_filter.Resolver{
-		Graph:                  &ctx.TopologicalGraph,
-		PackageInfos:           ctx.PackageInfos,
-		Cwd:                    cwd,
-		PackagesChangedInRange: opts.getPackageChangeFunc(scm, cwd, ctx.PackageInfos),
+		Graph:                    &ctx.TopologicalGraph,
+		PackageInfos:             ctx.PackageInfos,
+		Cwd:                      cwd,
+		PackagesChangedInRange:   opts.getPackageChangeFunc(scm, cwd, ctx.PackageInfos, tui, nil),
+		TaskInputsChangedInRange: opts.getPackageChangeFunc(scm, cwd, ctx.PackageInfos, tui, taskInputsFilter(pipeline, tasks)),
 	}
 	filterPatterns := opts.FilterPatterns
 	legacyFilterPatterns := opts.LegacyFilter.asFilterPatterns()
@@ -137,7 +150,46 @@ func ResolvePackages(opts *Opts, cwd string, scm scm.SCM, ctx *context.Context,
 	return filteredPkgs, isAllPackages, nil
 }
 
-func (o *Opts) getPackageChangeFunc(scm scm.SCM, cwd string, packageInfos map[interface{}]*fs.PackageJSON) scope_filter.PackagesChangedInRange {
+// changedFileFilter reports whether a change to a file, given as a unix path relative to
+// the package that contains it, counts as a change to that package.
+type changedFileFilter = func(pkgName string, pkgRelativeFile string) (bool, error)
+
+// taskInputsFilter counts a change to a file as a change to its package if it is an input
+// to any of the given tasks in that package.
+func taskInputsFilter(pipeline fs.Pipeline, tasks []string) changedFileFilter {
+	return func(pkgName string, pkgRelativeFile string) (bool, error) {
+		for _, task := range tasks {
+			taskDefinition, ok := pipeline.GetTaskDefinition(util.GetTaskId(pkgName, task))
+			if !ok {
+				continue
+			}
+			if isAffected, err := taskDefinition.IsAffectedBy(pkgRelativeFile); err != nil {
+				return false, err
+			} else if isAffected {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// reportRange prints the git range that changes are found in, unless it was already printed
+func (o *Opts) reportRange(tui cli.Ui, gitRange *scm.Range) {
+	description := fmt.Sprintf("%v", gitRange)
+	if o.reportedRanges == nil {
+		o.reportedRanges = make(util.Set)
+	}
+	if o.reportedRanges.Includes(description) {
+		return
+	}
+	o.reportedRanges.Add(description)
+	// Report on stderr so that machine-readable output, such as --dry=json, is unaffected
+	tui.Warn(ui.Dim(fmt.Sprintf("• Comparing %v", description)))
+}
+
+// getPackageChangeFunc returns a function to find the packages which changed in a git range.
+// If fileFilter is nil, a change to any file in a package counts as a change to the package.
+func (o *Opts) getPackageChangeFunc(scm scm.SCM, cwd string, packageInfos map[interface{}]*fs.PackageJSON, tui cli.Ui, fileFilter changedFileFilter) scope_filter.PackagesChangedInRange {
 	return func(fromRef string, toRef string) (util.Set, error) {
 		// We could filter changed files at the git level, since it's possible
 		// that the changes we're interested in are scoped, but we need to handle
@@ -145,7 +197,12 @@ func (o *Opts) getPackageChangeFunc(scm scm.SCM, cwd string, packageInfos map[in
 		// scope changed files more deeply if we know there are no global dependencies.
 		var changedFiles []string
 		if fromRef != "" {
-			scmChangedFiles, err := scm.ChangedFiles(fromRef, toRef, true, cwd)
+			gitRange, err := scm.ResolveRange(fromRef, toRef, o.DeepenShallowClone)
+			if err != nil {
+				return nil, err
+			}
+			o.reportRange(tui, gitRange)
+			scmChangedFiles, err := scm.ChangedFiles(gitRange.MergeBase, toRef, true, cwd)
 			if err != nil {
 				return nil, err
 			}
@@ -164,7 +221,29 @@ func (o *Opts) getPackageChangeFunc(scm scm.SCM, cwd string, packageInfos map[in
 		if err != nil {
 			return nil, err
 		}
-		changedPkgs := getChangedPackages(filteredChangedFiles, packageInfos)
+		if fileFilter == nil {
+			return getChangedPackages(filteredChangedFiles, packageInfos), nil
+		}
+		changedPkgs := make(util.Set)
+		for _, changedFile := range filteredChangedFiles {
+			pkgName := PackageForFile(changedFile, packageInfos)
+			if changedPkgs.Includes(pkgName) {
+				continue
+			}
+			pkgDir := ""
+			if pkg, ok := packageInfos[pkgName]; ok {
+				pkgDir = pkg.Dir.ToStringDuringMigration()
+			}
+			pkgRelativeFile, err := filepath.Rel(pkgDir, changedFile)
+			if err != nil {
+				return nil, err
+			}
+			if isChanged, err := fileFilter(pkgName, filepath.ToSlash(pkgRelativeFile)); err != nil {
+				return nil, err
+			} else if isChanged {
+				changedPkgs.Add(pkgName)
+			}
+		}
 		return changedPkgs, nil
 	}
 }
@@ -233,18 +312,18 @@ func fileInPackage(changedFile string, packagePath string) bool {
 func getChangedPackages(changedFiles []string, packageInfos map[interface{}]*fs.PackageJSON) util.Set {
 	changedPackages := make(util.Set)
 	for _, changedFile := range changedFiles {
-		found := false
-		for pkgName, pkgInfo := range packageInfos {
-			if pkgName != util.RootPkgName && fileInPackage(changedFile, pkgInfo.Dir.ToStringDuringMigration()) {
-				changedPackages.Add(pkgName)
-				found = true
-				break
-			}
-		}
-		if !found {
-			// Consider the root package to have changed
-			changedPackages.Add(util.RootPkgName)
-		}
+		changedPackages.Add(PackageForFile(changedFile, packageInfos))
 	}
 	return changedPackages
 }
+
+// PackageForFile returns the name of the package that a repo-relative file belongs to.
+// Files outside of every workspace package belong to the root package.
+func PackageForFile(file string, packageInfos map[interface{}]*fs.PackageJSON) string {
+	for pkgName, pkgInfo := range packageInfos {
+		if pkgName != util.RootPkgName && fileInPackage(file, pkgInfo.Dir.ToStringDuringMigration()) {
+			return pkgName.(string)
+		}
+	}
+	return util.RootPkgName
+}
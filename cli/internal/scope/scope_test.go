@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/mitchellh/cli"
 	"github.com/pyr-sh/dag"
 	"github.com/vercel/turborepo/cli/internal/context"
 	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/scm"
 	"github.com/vercel/turborepo/cli/internal/turbopath"
 	"github.com/vercel/turborepo/cli/internal/ui"
 	"github.com/vercel/turborepo/cli/internal/util"
@@ -23,6 +26,15 @@ func (m *mockSCM) ChangedFiles(_fromCommit string, _toCommit string, _includeUnt
 	return m.changed, nil
 }
 
+func (m *mockSCM) ResolveRange(fromCommit string, toCommit string, _deepen bool) (*scm.Range, error) {
+	return &scm.Range{
+		FromRef:         fromCommit,
+		ResolvedFromRef: fromCommit,
+		MergeBase:       fromCommit,
+		ToRef:           toCommit,
+	}, nil
+}
+
 func TestResolvePackages(t *testing.T) {
 	tui := ui.Default()
 	logger := hclog.Default()
@@ -81,6 +93,12 @@ func TestResolvePackages(t *testing.T) {
 			Dir: turbopath.AnchoredSystemPath(filepath.FromSlash("libs/libD")),
 		},
 	}
+	pipeline := fs.Pipeline{
+		"build": {
+			Inputs: []string{"src/**"},
+		},
+		"libD#build": {},
+	}
 	packageNames := []string{}
 	for name := range packagesInfos {
 		packageNames = append(packageNames, name.(string))
@@ -93,6 +111,7 @@ func TestResolvePackages(t *testing.T) {
 		expectAllPackages   bool
 		scope               []string
 		since               string
+		filter              []string
 		ignore              string
 		globalDeps          []string
 		includeDependencies bool
@@ -214,6 +233,24 @@ func TestResolvePackages(t *testing.T) {
 			expected: []string{"app2", "app2-a"},
 			since:    "dummy",
 		},
+		{
+			name:     "changes outside of task inputs are skipped when filtering by inputs",
+			changed:  []string{"libs/libB/README.md", "app/app0/src/index.ts"},
+			filter:   []string{"[dummy]{inputs}"},
+			expected: []string{"app0"},
+		},
+		{
+			name:     "changes to task inputs include dependents",
+			changed:  []string{"libs/libB/src/index.ts", "libs/libC/README.md"},
+			filter:   []string{"...[dummy]{inputs}"},
+			expected: []string{"app0", "app1", "app2", "libA", "libB"},
+		},
+		{
+			name:     "package-specific task inputs are respected",
+			changed:  []string{"libs/libC/README.md", "libs/libD/README.md"},
+			filter:   []string{"[dummy]{inputs}"},
+			expected: []string{"libD"},
+		},
 	}
 	for i, tc := range testCases {
 		t.Run(fmt.Sprintf("test #%v %v", i, tc.name), func(t *testing.T) {
@@ -232,13 +269,14 @@ func TestResolvePackages(t *testing.T) {
 					IncludeDependencies: tc.includeDependencies,
 					SkipDependents:      !tc.includeDependents,
 				},
+				FilterPatterns:    tc.filter,
 				IgnorePatterns:    []string{tc.ignore},
 				GlobalDepPatterns: tc.globalDeps,
 			}, filepath.FromSlash("/dummy/repo/root"), scm, &context.Context{
 				PackageInfos:     packagesInfos,
 				PackageNames:     packageNames,
 				TopologicalGraph: graph,
-			}, tui, logger)
+			}, pipeline, []string{"build"}, tui, logger)
 			if err != nil {
 				t.Errorf("expected no error, got %v", err)
 			}
@@ -255,3 +293,30 @@ func TestResolvePackages(t *testing.T) {
 		})
 	}
 }
+
+func TestPackageChangeFuncReportsRangeOnce(t *testing.T) {
+	tui := cli.NewMockUi()
+	opts := &Opts{}
+	scm := &mockSCM{changed: []string{"app/index.js"}}
+	packageInfos := map[interface{}]*fs.PackageJSON{
+		"app": {Name: "app", Dir: turbopath.AnchoredSystemPath("app")},
+	}
+	anyFile := func(pkgName string, pkgRelativeFile string) (bool, error) { return true, nil }
+	packagesChanged := opts.getPackageChangeFunc(scm, "", packageInfos, tui, nil)
+	taskInputsChanged := opts.getPackageChangeFunc(scm, "", packageInfos, tui, anyFile)
+	for _, changed := range []func(string, string) (util.Set, error){packagesChanged, taskInputsChanged, packagesChanged} {
+		if _, err := changed("main", "HEAD"); err != nil {
+			t.Fatalf("failed to find changed packages: %v", err)
+		}
+	}
+	if _, err := packagesChanged("release", "HEAD"); err != nil {
+		t.Fatalf("failed to find changed packages: %v", err)
+	}
+	output := tui.ErrorWriter.String()
+	if count := strings.Count(output, "Comparing"); count != 2 {
+		t.Errorf("expected each range to be reported once, got %v reports:\n%v", count, output)
+	}
+	if !strings.Contains(output, "release") {
+		t.Errorf("expected a different range to be reported, got:\n%v", output)
+	}
+}
@@ -5,15 +5,18 @@ package taskhash
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 
+	"github.com/pkg/errors"
 	"github.com/pyr-sh/dag"
-	gitignore "github.com/sabhiram/go-gitignore"
-	"github.com/vercel/turborepo/cli/internal/doublestar"
 	"github.com/vercel/turborepo/cli/internal/env"
 	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/gitignore"
+	"github.com/vercel/turborepo/cli/internal/globby"
 	"github.com/vercel/turborepo/cli/internal/hashing"
 	"github.com/vercel/turborepo/cli/internal/inference"
 	"github.com/vercel/turborepo/cli/internal/nodes"
@@ -34,7 +37,8 @@ type Tracker struct {
 	packageInfos        map[interface{}]*fs.PackageJSON
 	mu                  sync.RWMutex
 	packageInputsHashes packageFileHashes
-	packageTaskHashes   map[string]string // taskID -> hash
+	packageTaskHashes   map[string]string            // taskID -> hash
+	packageTaskDotEnv   map[string]map[string]string // taskID -> variables from .env files
 }
 
 // NewTracker creates a tracker for package-inputs combinations and package-task combinations.
@@ -45,6 +49,7 @@ func NewTracker(rootNode string, globalHash string, pipeline fs.Pipeline, packag
 		pipeline:          pipeline,
 		packageInfos:      packageInfos,
 		packageTaskHashes: make(map[string]string),
+		packageTaskDotEnv: make(map[string]map[string]string),
 	}
 }
 
@@ -69,14 +74,6 @@ func (pfs packageFileSpec) ToKey() packageFileHashKey {
 	return packageFileHashKey(fmt.Sprintf("%v#%v", pfs.pkg, strings.Join(pfs.inputs, "!")))
 }
 
-func safeCompileIgnoreFile(filepath string) (*gitignore.GitIgnore, error) {
-	if fs.FileExists(filepath) {
-		return gitignore.CompileIgnoreFile(filepath)
-	}
-	// no op
-	return gitignore.CompileIgnoreLines([]string{}...), nil
-}
-
 func (pfs *packageFileSpec) hash(pkg *fs.PackageJSON, repoRoot fs.AbsolutePath) (string, error) {
 	hashObject, pkgDepsErr := hashing.GetPackageDeps(repoRoot, &hashing.PackageDepsOptions{
 		PackagePath:   pkg.Dir,
@@ -96,59 +93,88 @@ func (pfs *packageFileSpec) hash(pkg *fs.PackageJSON, repoRoot fs.AbsolutePath)
 	return hashOfFiles, nil
 }
 
+// manuallyHashPackage hashes the files in a package without relying on git. It
+// is used when git is unavailable, and aims to produce the same result as
+// hashing.GetPackageDeps for the same tree. The exception is files which are
+// tracked by git despite matching an ignore rule, which can't be detected here.
 func manuallyHashPackage(pkg *fs.PackageJSON, inputs []string, rootPath fs.AbsolutePath) (map[turbopath.AnchoredUnixPath]string, error) {
 	hashObject := make(map[turbopath.AnchoredUnixPath]string)
-	// Instead of implementing all gitignore properly, we hack it. We only respect .gitignore in the root and in
-	// the directory of a package.
-	ignore, err := safeCompileIgnoreFile(rootPath.Join(".gitignore").ToString())
-	if err != nil {
-		return nil, err
+	convertedRootPath := turbopath.AbsoluteSystemPathFromUpstream(rootPath.ToString())
+	pathPrefix := pkg.Dir.RestoreAnchor(convertedRootPath)
+
+	// Mirror hashing.GetPackageDeps: explicit inputs are resolved by glob without
+	// regard for ignore files, everything else follows the rules git would use.
+	if len(inputs) > 0 {
+		files, err := globby.GlobFiles(pathPrefix.ToString(), inputs, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve input globs %v", inputs)
+		}
+		for _, file := range files {
+			convertedName := turbopath.AbsoluteSystemPathFromUpstream(file)
+			if err := hashFile(hashObject, convertedName, pathPrefix, 0); err != nil {
+				return nil, err
+			}
+		}
+		return hashObject, nil
 	}
 
-	ignorePkg, err := safeCompileIgnoreFile(rootPath.Join(pkg.Dir.ToStringDuringMigration(), ".gitignore").ToString())
+	ignore, err := gitignore.NewMatcher(convertedRootPath)
 	if err != nil {
 		return nil, err
 	}
-
-	includePattern := ""
-	if len(inputs) > 0 {
-		includePattern = "{" + strings.Join(inputs, ",") + "}"
-	}
-
-	pathPrefix := rootPath.Join(pkg.Dir.ToStringDuringMigration()).ToString()
-	convertedPathPrefix := turbopath.AbsoluteSystemPathFromUpstream(pathPrefix)
-	fs.Walk(pathPrefix, func(name string, isDir bool) error {
+	err = fs.WalkMode(pathPrefix.ToString(), func(name string, isDir bool, mode os.FileMode) error {
 		convertedName := turbopath.AbsoluteSystemPathFromUpstream(name)
-		rootMatch := ignore.MatchesPath(convertedName.ToString())
-		otherMatch := ignorePkg.MatchesPath(convertedName.ToString())
-		if !rootMatch && !otherMatch {
-			if !isDir {
-				if includePattern != "" {
-					val, err := doublestar.PathMatch(includePattern, convertedName.ToString())
-					if err != nil {
-						return err
-					}
-					if !val {
-						return nil
-					}
-				}
-				hash, err := fs.GitLikeHashFile(convertedName.ToString())
-				if err != nil {
-					return fmt.Errorf("could not hash file %v. \n%w", convertedName.ToString(), err)
-				}
-
-				relativePath, err := convertedName.RelativeTo(convertedPathPrefix)
-				if err != nil {
-					return fmt.Errorf("File path cannot be made relative: %w", err)
-				}
-				hashObject[relativePath.ToUnixPath()] = hash
+		if convertedName == pathPrefix {
+			return nil
+		}
+		repoRelativePath, err := convertedName.RelativeTo(convertedRootPath)
+		if err != nil {
+			return fmt.Errorf("File path cannot be made relative: %w", err)
+		}
+		// git records symlinks, including those pointing at directories, as files.
+		isLink := mode&os.ModeSymlink != 0
+		ignored, err := ignore.IsIgnored(repoRelativePath.ToUnixPath(), isDir && !isLink)
+		if err != nil {
+			return err
+		}
+		if ignored {
+			if isDir && !isLink {
+				return filepath.SkipDir
 			}
+			return nil
 		}
-		return nil
+		if isDir && !isLink {
+			return nil
+		}
+		return hashFile(hashObject, convertedName, pathPrefix, mode)
 	})
+	if err != nil {
+		return nil, err
+	}
 	return hashObject, nil
 }
 
+// hashFile adds the git-like hash of a file, keyed by its path relative to the package, to hashObject.
+func hashFile(hashObject map[turbopath.AnchoredUnixPath]string, name turbopath.AbsoluteSystemPath, pathPrefix turbopath.AbsoluteSystemPath, mode os.FileMode) error {
+	var hash string
+	var err error
+	if mode&os.ModeSymlink != 0 {
+		hash, err = fs.GitLikeHashSymlink(name.ToString())
+	} else {
+		hash, err = fs.GitLikeHashFile(name.ToString())
+	}
+	if err != nil {
+		return fmt.Errorf("could not hash file %v. \n%w", name.ToString(), err)
+	}
+
+	relativePath, err := name.RelativeTo(pathPrefix)
+	if err != nil {
+		return fmt.Errorf("File path cannot be made relative: %w", err)
+	}
+	hashObject[relativePath.ToUnixPath()] = hash
+	return nil
+}
+
 // packageFileHashes is a map from a package and optional input globs to the hash of
 // the matched files in the package.
 type packageFileHashes map[packageFileHashKey]string
@@ -177,6 +203,17 @@ func (th *Tracker) CalculateFileHashes(allTasks []dag.Vertex, workerCount int, r
 			pkg:    pkgName,
 			inputs: taskDefinition.Inputs,
 		})
+		if len(taskDefinition.DotEnv) > 0 {
+			pkg, ok := th.packageInfos[pkgName]
+			if !ok {
+				return fmt.Errorf("cannot find package %v", pkgName)
+			}
+			dotEnv, err := readDotEnv(pkg, taskDefinition.DotEnv, repoRoot)
+			if err != nil {
+				return fmt.Errorf("failed to read .env files for %v: %w", taskID, err)
+			}
+			th.packageTaskDotEnv[taskID] = dotEnv
+		}
 	}
 
 	hashes := make(map[packageFileHashKey]string)
@@ -212,6 +249,27 @@ func (th *Tracker) CalculateFileHashes(allTasks []dag.Vertex, workerCount int, r
 	return nil
 }
 
+// readDotEnv reads the given .env files from the repo root, and then from the package directory, so
+// that variables defined for a package override those defined for the whole repo.
+func readDotEnv(pkg *fs.PackageJSON, dotEnvFiles []string, repoRoot fs.AbsolutePath) (map[string]string, error) {
+	paths := make([]string, 0, 2*len(dotEnvFiles))
+	for _, file := range dotEnvFiles {
+		paths = append(paths, repoRoot.Join(file).ToString())
+	}
+	if pkgDir := pkg.Dir.ToString(); pkgDir != "" && pkgDir != "." {
+		for _, file := range dotEnvFiles {
+			paths = append(paths, repoRoot.Join(pkgDir, file).ToString())
+		}
+	}
+	return env.ReadDotEnvFiles(paths)
+}
+
+// DotEnv returns the variables read from the .env files of the given task. File hashes must be
+// calculated first.
+func (th *Tracker) DotEnv(taskID string) map[string]string {
+	return th.packageTaskDotEnv[taskID]
+}
+
 type taskHashInputs struct {
 	hashOfFiles          string
 	externalDepsHash     string
@@ -219,6 +277,7 @@ type taskHashInputs struct {
 	outputs              []string
 	passThruArgs         []string
 	hashableEnvPairs     []string
+	dotEnvPairs          []string
 	globalHash           string
 	taskDependencyHashes []string
 }
@@ -251,6 +310,17 @@ func (th *Tracker) calculateDependencyHashes(dependencySet dag.Set) ([]string, e
 	return dependenciesHashList, nil
 }
 
+// FrameworkEnvPrefixes returns the prefixes of env vars that are automatically included in the
+// hashes of tasks in the given package, based on the framework it uses.
+func FrameworkEnvPrefixes(pkg *fs.PackageJSON) []string {
+	var envPrefixes []string
+	framework := inference.InferFramework(pkg)
+	if framework != nil && framework.EnvPrefix != "" {
+		envPrefixes = append(envPrefixes, framework.EnvPrefix)
+	}
+	return envPrefixes
+}
+
 // CalculateTaskHash calculates the hash for package-task combination. It is threadsafe, provided
 // that it has previously been called on its task-graph dependencies. File hashes must be calculated
 // first.
@@ -261,13 +331,7 @@ func (th *Tracker) CalculateTaskHash(pt *nodes.PackageTask, dependencySet dag.Se
 		return "", fmt.Errorf("cannot find package-file hash for %v", pkgFileHashKey)
 	}
 
-	var envPrefixes []string
-	framework := inference.InferFramework(pt.Pkg)
-	if framework != nil && framework.EnvPrefix != "" {
-		envPrefixes = append(envPrefixes, framework.EnvPrefix)
-	}
-
-	hashableEnvPairs := env.GetHashableEnvPairs(pt.TaskDefinition.EnvVarDependencies, envPrefixes)
+	hashableEnvPairs := env.GetHashableEnvPairs(pt.TaskDefinition.EnvVarDependencies, FrameworkEnvPrefixes(pt.Pkg))
 	outputs := pt.HashableOutputs()
 	taskDependencyHashes, err := th.calculateDependencyHashes(dependencySet)
 	if err != nil {
@@ -280,6 +344,7 @@ func (th *Tracker) CalculateTaskHash(pt *nodes.PackageTask, dependencySet dag.Se
 		outputs:              outputs,
 		passThruArgs:         args,
 		hashableEnvPairs:     hashableEnvPairs,
+		dotEnvPairs:          env.DotEnvPairs(th.packageTaskDotEnv[pt.TaskID]),
 		globalHash:           th.globalHash,
 		taskDependencyHashes: taskDependencyHashes,
 	})
@@ -2,11 +2,15 @@ package taskhash
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/vercel/turborepo/cli/internal/fs"
+	"github.com/vercel/turborepo/cli/internal/hashing"
 	"github.com/vercel/turborepo/cli/internal/turbopath"
 )
 
@@ -112,6 +116,9 @@ func Test_manuallyHashPackage(t *testing.T) {
 		t.Errorf("found extra hashes in %v", hashes)
 	}
 
+	// Explicit inputs are resolved by glob, the same as when using git, so
+	// ignored files that match are included.
+	ignoredFileHash := "67aed78ea231bdee3de45b6d47d8f32a0a792f6d"
 	count = 0
 	justFileHashes, err := manuallyHashPackage(pkg, []string{filepath.FromSlash("**/*file")}, fs.AbsolutePath(repoRoot.ToString()))
 	if err != nil {
@@ -120,13 +127,15 @@ func Test_manuallyHashPackage(t *testing.T) {
 	for path, spec := range files {
 		if strings.HasPrefix(path.ToString(), prefix.ToString()) {
 			shouldInclude := strings.HasSuffix(path.ToString(), "file")
+			wantHash := spec.hash
+			if wantHash == "" {
+				wantHash = ignoredFileHash
+			}
 			got, ok := justFileHashes[turbopath.AnchoredUnixPath(path[prefixLen:])]
 			if !ok && shouldInclude {
-				if spec.hash != "" {
-					t.Errorf("did not find hash for %v, but wanted one", path)
-				}
-			} else if shouldInclude && got != spec.hash {
-				t.Errorf("hash of %v, got %v want %v", path, got, spec.hash)
+				t.Errorf("did not find hash for %v, but wanted one", path)
+			} else if shouldInclude && got != wantHash {
+				t.Errorf("hash of %v, got %v want %v", path, got, wantHash)
 			} else if shouldInclude {
 				count++
 			}
@@ -136,3 +145,121 @@ func Test_manuallyHashPackage(t *testing.T) {
 		t.Errorf("found extra hashes in %v", hashes)
 	}
 }
+
+func Test_manuallyHashPackageMatchesGit(t *testing.T) {
+	root := t.TempDir()
+	repoRoot := turbopath.AbsoluteSystemPathFromUpstream(root)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=turbo", "-c", "user.email=turbo@example.com"}, args...)...)
+		cmd.Dir = root
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	writeFiles := func(files map[string]string) {
+		t.Helper()
+		for path, contents := range files {
+			filename := turbopath.AnchoredUnixPathFromUpstream(path).ToSystemPath().RestoreAnchor(repoRoot)
+			if err := fs.EnsureDir(filename.ToString()); err != nil {
+				t.Fatalf("failed to ensure directories for %v: %v", filename, err)
+			}
+			if err := os.WriteFile(filename.ToString(), []byte(contents), 0644); err != nil {
+				t.Fatalf("failed to write %v: %v", filename, err)
+			}
+		}
+	}
+
+	git("init", "--quiet")
+	// Don't let the global configuration of whoever runs the test affect git's view of the tree.
+	git("config", "core.excludesFile", filepath.Join(root, "no-global-excludes"))
+	writeFiles(map[string]string{
+		".git/info/exclude":                  "*.local\n",
+		".gitignore":                         "*.log\n!keep.log\nbuild/\n",
+		"libA/.gitignore":                    "/generated\ncache/\n!*.txt\n",
+		"libA/src/.gitignore":                "*.tmp\n!important.tmp\n",
+		"libA/src/deep/.gitignore":           "[ab].js\n",
+		"libA/src/index.js":                  "index",
+		"libA/src/deep/a.js":                 "a",
+		"libA/src/deep/c.js":                 "c",
+		"libA/keep.log":                      "keep",
+		"libA/README.md":                     "readme",
+		"libA/src/committed-then-deleted.js": "deleted",
+	})
+	git("add", ".")
+	git("commit", "--quiet", "-m", "initial")
+
+	// Leave a mix of modified, deleted, untracked and ignored files in the working tree.
+	writeFiles(map[string]string{
+		"libA/src/index.js":         "modified",
+		"libA/src/untracked.js":     "untracked",
+		"libA/debug.log":            "ignored",
+		"libA/settings.local":       "ignored",
+		"libA/generated":            "ignored",
+		"libA/src/generated":        "not ignored",
+		"libA/cache/file.txt":       "ignored",
+		"libA/build/output.js":      "ignored",
+		"libA/src/scratch.tmp":      "ignored",
+		"libA/src/important.tmp":    "not ignored",
+		"libA/src/deep/b.js":        "ignored",
+		"libA/src/deep/nested/a.js": "ignored",
+		"libA/src/deep/nested/d.js": "not ignored",
+	})
+	if err := os.Remove(repoRoot.Join("libA", "src", "committed-then-deleted.js").ToString()); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("index.js", repoRoot.Join("libA", "src", "link.js").ToString()); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+		git("add", "libA/src/link.js")
+		git("commit", "--quiet", "-m", "add link")
+	}
+
+	pkg := &fs.PackageJSON{
+		Dir: turbopath.AnchoredSystemPath("libA"),
+	}
+	for _, inputs := range [][]string{nil, {"src/**/*.js"}} {
+		fromGit, err := hashing.GetPackageDeps(fs.AbsolutePath(root), &hashing.PackageDepsOptions{
+			PackagePath:   pkg.Dir,
+			InputPatterns: inputs,
+		})
+		if err != nil {
+			t.Fatalf("failed to hash package with git: %v", err)
+		}
+		manual, err := manuallyHashPackage(pkg, inputs, fs.AbsolutePath(root))
+		if err != nil {
+			t.Fatalf("failed to hash package manually: %v", err)
+		}
+		if !reflect.DeepEqual(fromGit, manual) {
+			t.Errorf("inputs %v: hashes differ\ngit:    %v\nmanual: %v", inputs, fromGit, manual)
+		}
+	}
+}
+
+func Test_readDotEnv(t *testing.T) {
+	repoRoot := fs.AbsolutePath(t.TempDir())
+	pkgDir := repoRoot.Join("packages", "web")
+	if err := pkgDir.MkdirAll(); err != nil {
+		t.Fatalf("failed to create package dir: %v", err)
+	}
+	files := map[fs.AbsolutePath]string{
+		repoRoot.Join(".env"):     "SHARED=root\nROOT_ONLY=root\n",
+		pkgDir.Join(".env"):       "SHARED=package\n",
+		pkgDir.Join(".env.local"): "LOCAL=package-local\n",
+	}
+	for path, contents := range files {
+		if err := path.WriteFile([]byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write %v: %v", path, err)
+		}
+	}
+	pkg := &fs.PackageJSON{Dir: turbopath.AnchoredSystemPath(filepath.Join("packages", "web"))}
+	got, err := readDotEnv(pkg, []string{".env", ".env.local"}, repoRoot)
+	if err != nil {
+		t.Fatalf("readDotEnv() error: %v", err)
+	}
+	want := map[string]string{"SHARED": "package", "ROOT_ONLY": "root", "LOCAL": "package-local"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readDotEnv() got %v, want %v", got, want)
+	}
+}
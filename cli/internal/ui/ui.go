@@ -63,6 +63,11 @@ func Rainbow(text string) string {
 	return strings.Join(rainbowStr, "")
 }
 
+// StripAnsi removes ansi escape codes, such as colors, from the given string
+func StripAnsi(str string) string {
+	return ansiRegex.ReplaceAllString(str, "")
+}
+
 type stripAnsiWriter struct {
 	wrappedWriter io.Writer
 }
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package util
+
+import "strings"
+
+// QuoteShellArg quotes arg so that the system shell passes it to a command unchanged
+func QuoteShellArg(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
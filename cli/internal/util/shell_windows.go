@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package util
+
+import "strings"
+
+// QuoteShellArg quotes arg so that the system shell passes it to a command unchanged
+func QuoteShellArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"&|<>^%") {
+		return arg
+	}
+	return `"` + strings.ReplaceAll(arg, `"`, `""`) + `"`
+}
@@ -4,6 +4,22 @@ export interface Schema {
   /** @default https://turborepo.org/schema.json */
   $schema?: string;
 
+  /**
+   * In the root turbo.json, the presets it builds on. Each entry is a path starting with
+   * ./ or ../, or the name of a workspace package or a package installed in node_modules,
+   * optionally followed by a path within the package. Presets are applied in order, and the
+   * turbo.json is applied last. Keys of each task replace the presets' values, except env,
+   * passThroughEnv, dotEnv and $ entries in dependsOn, which add to them.
+   *
+   * In a workspace package's turbo.json, the configuration it extends. Package
+   * configurations can only extend the root turbo.json, written as ["//"], and can only
+   * set extends and pipeline. Each task in the package's pipeline starts from the root's
+   * definition for that package and task.
+   *
+   * @default []
+   */
+  extends?: string[];
+
   /**
    * A list of globs and environment variables for implicit global hash dependencies.
    * Environment variables should be prefixed with $ (e.g. $GITHUB_TOKEN).
@@ -22,6 +38,16 @@ export interface Schema {
    */
   globalDependencies?: string[];
 
+  /**
+   * A list of environment variables whose values affect the hashes of all tasks.
+   *
+   * A * matches any sequence of characters, and an entry prefixed with ! excludes the
+   * variables it matches (e.g. ["CI_*", "!CI_JOB_ID"]).
+   *
+   * @default []
+   */
+  globalEnv?: string[];
+
   /**
    * An object representing the task dependency graph of your project. turbo interprets
    * these conventions to properly schedule, execute, and cache the outputs of tasks in
@@ -43,6 +69,16 @@ export interface Schema {
    * @default {}
    */
   remoteCache?: RemoteCache;
+
+  /**
+   * The caches that task outputs are restored from and stored in, highest priority first.
+   * Outputs are restored from the first readable tier that has them, and then stored in the
+   * writable tiers before it. By default, the local cache in --cache-dir is used, followed by
+   * the remote cache.
+   *
+   * @default []
+   */
+  cacheTiers?: CacheTier[];
 }
 
 export interface Pipeline {
@@ -65,6 +101,41 @@ export interface Pipeline {
    */
   dependsOn?: string[];
 
+  /**
+   * A list of environment variables whose values affect the hash of this task.
+   *
+   * A * matches any sequence of characters, and an entry prefixed with ! excludes the
+   * variables it matches (e.g. ["API_*", "!API_SECRET"]).
+   *
+   * @default []
+   */
+  env?: string[];
+
+  /**
+   * A list of environment variables that are passed to this task when running with
+   * --env-mode=strict, but that don't affect its hash. Entries follow the same rules as env.
+   *
+   * @default []
+   */
+  passThroughEnv?: string[];
+
+  /**
+   * A list of .env files whose variables affect the hash of this task. Each file is read
+   * from the root of the monorepo, and then from the package's directory, with later files
+   * overriding earlier ones. Files that don't exist are skipped.
+   *
+   * @default []
+   */
+  dotEnv?: string[];
+
+  /**
+   * Whether to pass the variables from the task's dotEnv files to the task when it runs.
+   * Variables that are already set in the environment take precedence.
+   *
+   * @default false
+   */
+  loadDotEnv?: boolean;
+
   /**
    * The set of glob patterns of a task's cacheable filesystem outputs.
    *
@@ -108,6 +179,57 @@ export interface Pipeline {
    * @default full
    */
   outputMode?: string;
+
+  /**
+   * A command to run for this task instead of the package's package.json script. The
+   * command is run with the system shell in the package's directory, without the package
+   * manager, and with node_modules/.bin in PATH. It's hashed, cached and logged the same
+   * way as a script, and tasks with a command don't need a script. {package}, {dir} and
+   * {task} are replaced with the package name, the package directory relative to the repo
+   * root, and the task name.
+   */
+  command?: string;
+
+  /**
+   * How the task's package.json script is run. Use "package-manager" to run it with
+   * `<package manager> run <task>`. Use "shell" to run the script directly with the system
+   * shell, skipping the package manager's startup time.
+   *
+   * @default package-manager
+   */
+  runner?: "package-manager" | "shell";
+
+  /**
+   * When to remove the files that match outputs. Use "never" to write restored and rebuilt
+   * outputs over the existing files. Use "restore" to remove the existing outputs before
+   * restoring the task from the cache, so that only the cached files remain. Use "always" to
+   * also remove them before running the task.
+   *
+   * @default never
+   */
+  cleanOutputs?: "never" | "restore" | "always";
+}
+
+export interface CacheTier {
+  /**
+   * "local" for a cache in a directory, or "remote" for the remote cache. There can only be
+   * one "remote" tier.
+   */
+  type: "local" | "remote";
+
+  /**
+   * The directory of a "local" tier, relative to the repo root or absolute. Defaults to the
+   * --cache-dir.
+   */
+  dir?: string;
+
+  /**
+   * Whether outputs are restored from the tier ("read-only"), stored in it ("write-only"), or
+   * both.
+   *
+   * @default "read-write"
+   */
+  policy?: "read-write" | "read-only" | "write-only";
 }
 
 export interface RemoteCache {
@@ -120,4 +242,12 @@ export interface RemoteCache {
    * @default false
    */
   signature?: boolean;
+
+  /**
+   * A base64 encoded Ed25519 public key. When set, Turborepo only accepts downloaded artifacts
+   * that are signed with the matching private key, and only uploads artifacts when the private
+   * key is given in the `TURBO_REMOTE_CACHE_SIGNATURE_PRIVATE_KEY` environment variable. Unlike
+   * `signature`, machines that can verify artifacts can't sign them.
+   */
+  publicKey?: string;
 }